@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"strings"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+const azureResourceTypeResourceGroup = "Microsoft.Resources/subscriptions/resourceGroups"
+
+// applyParentAffinity narrows the candidates of each plan resource to those under its parent, once the parent has been
+// mapped to a single Azure resource. Narrowing one resource can settle the parent of another, so it repeats until nothing changes.
+func (importer *MappingClient) applyParentAffinity(planResources []*types.PlanResource) {
+	planResourcesByAddress := map[string]*types.PlanResource{}
+	for _, planResource := range planResources {
+		planResourcesByAddress[planResource.Address] = planResource
+	}
+
+	for pass := 1; ; pass++ {
+		changed := false
+		for _, planResource := range planResources {
+			if len(planResource.MappedResources) < 2 {
+				continue
+			}
+
+			parentID := getMappedParentID(planResource, planResourcesByAddress, planResources)
+			if parentID == "" {
+				continue
+			}
+
+			parentPrefix := azure.NormalizeResourceID(parentID) + "/"
+			mappedResources := []*types.GraphResource{}
+			for _, mappedResource := range planResource.MappedResources {
+				if strings.HasPrefix(azure.NormalizeResourceID(mappedResource.ID), parentPrefix) {
+					mappedResources = append(mappedResources, mappedResource)
+				}
+			}
+
+			if len(mappedResources) > 0 && len(mappedResources) < len(planResource.MappedResources) {
+				importer.Logger.Debugf("Narrowed candidates from %d to %d under parent %s for Address: %s", len(planResource.MappedResources), len(mappedResources), parentID, planResource.Address)
+				planResource.MappedResources = mappedResources
+				changed = true
+			}
+		}
+
+		if !changed {
+			importer.Logger.Debugf("Parent affinity settled after %d passes", pass)
+			return
+		}
+	}
+}
+
+// getMappedParentID returns the Azure resource ID of the parent of a plan resource, if the parent is known.
+func getMappedParentID(planResource *types.PlanResource, planResourcesByAddress map[string]*types.PlanResource, planResources []*types.PlanResource) string {
+	if parentResource, ok := planResourcesByAddress[planResource.ParentAddress]; ok && len(parentResource.MappedResources) == 1 {
+		return parentResource.MappedResources[0].ID
+	}
+
+	if parentID, ok := planResource.Properties["parent_id"].(string); ok && strings.HasPrefix(parentID, "/") {
+		return parentID
+	}
+
+	if resourceGroupName, ok := planResource.Properties["resource_group_name"].(string); ok && resourceGroupName != "" {
+		parentID := ""
+		for _, resourceGroup := range planResources {
+			if !strings.EqualFold(resourceGroup.AzureResourceType, azureResourceTypeResourceGroup) || !strings.EqualFold(resourceGroup.ResourceName, resourceGroupName) {
+				continue
+			}
+			if len(resourceGroup.MappedResources) != 1 || (parentID != "" && !strings.EqualFold(parentID, resourceGroup.MappedResources[0].ID)) {
+				return ""
+			}
+			parentID = resourceGroup.MappedResources[0].ID
+		}
+		return parentID
+	}
+
+	return ""
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyParentAffinity_PropagatesThroughGenerations(t *testing.T) {
+	rg2 := &types.GraphResource{ID: "/subscriptions/sub2/resourceGroups/rg-hub"}
+	vnet1 := &types.GraphResource{ID: "/subscriptions/sub1/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub"}
+	vnet2 := &types.GraphResource{ID: "/subscriptions/sub2/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub"}
+	subnet1 := &types.GraphResource{ID: vnet1.ID + "/subnets/default"}
+	subnet2 := &types.GraphResource{ID: vnet2.ID + "/subnets/default"}
+
+	planResources := []*types.PlanResource{
+		{Address: "azapi_resource.subnet", ParentAddress: "azapi_resource.vnet", MappedResources: []*types.GraphResource{subnet1, subnet2}},
+		{Address: "azapi_resource.vnet", ParentAddress: "azapi_resource.rg", MappedResources: []*types.GraphResource{vnet1, vnet2}},
+		{Address: "azapi_resource.rg", Properties: map[string]any{}, MappedResources: []*types.GraphResource{rg2}},
+	}
+
+	client := &MappingClient{Logger: logrus.New()}
+	client.applyParentAffinity(planResources)
+
+	assert.Equal(t, []*types.GraphResource{vnet2}, planResources[1].MappedResources)
+	assert.Equal(t, []*types.GraphResource{subnet2}, planResources[0].MappedResources)
+}
+
+func Test_applyParentAffinity_ResourceGroupName(t *testing.T) {
+	vnet1 := &types.GraphResource{ID: "/subscriptions/sub1/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub"}
+	vnet2 := &types.GraphResource{ID: "/subscriptions/sub2/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub"}
+
+	planResources := []*types.PlanResource{
+		{Address: "azurerm_virtual_network.hub", Properties: map[string]any{"resource_group_name": "rg-hub"}, MappedResources: []*types.GraphResource{vnet1, vnet2}},
+		{Address: "azurerm_resource_group.hub", ResourceName: "rg-hub", AzureResourceType: azureResourceTypeResourceGroup, MappedResources: []*types.GraphResource{{ID: "/subscriptions/sub1/resourceGroups/rg-hub"}}},
+	}
+
+	client := &MappingClient{Logger: logrus.New()}
+	client.applyParentAffinity(planResources)
+
+	assert.Equal(t, []*types.GraphResource{vnet1}, planResources[0].MappedResources)
+}
+
+func Test_applyParentAffinity_KeepsCandidatesWhenParentIsAmbiguous(t *testing.T) {
+	vnet1 := &types.GraphResource{ID: "/subscriptions/sub1/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub"}
+	vnet2 := &types.GraphResource{ID: "/subscriptions/sub2/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub"}
+
+	planResources := []*types.PlanResource{
+		{Address: "azapi_resource.vnet", ParentAddress: "azapi_resource.rg", MappedResources: []*types.GraphResource{vnet1, vnet2}},
+		{Address: "azapi_resource.rg", MappedResources: []*types.GraphResource{{ID: "/subscriptions/sub1/resourceGroups/rg-hub"}, {ID: "/subscriptions/sub2/resourceGroups/rg-hub"}}},
+	}
+
+	client := &MappingClient{Logger: logrus.New()}
+	client.applyParentAffinity(planResources)
+
+	assert.Len(t, planResources[0].MappedResources, 2)
+}
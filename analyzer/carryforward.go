@@ -0,0 +1,119 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// staleIssueKeyPrefix keys stale resolutions in the exported issues, as they can share an ID with a current issue.
+const staleIssueKeyPrefix = "stale:"
+
+// carryForwardResolutions returns every current issue for the CSV export, filled in with the resolutions from the
+// supplied CSV file and the resolution rules, so only new issues are left to resolve. Resolutions from the CSV file
+// that no longer apply are added as stale rows, so reviewers can see what changed.
+func (mappingClient *MappingClient) carryForwardResolutions(graphResources []*types.GraphResource, planResources []*types.PlanResource, resolvedIssues *map[string]types.Issue, ruleResolvedIssues map[string]types.Issue) map[string]types.Issue {
+	_, currentIssues, _ := mappingClient.mapResourcesFromGraphToPlan(graphResources, planResources, nil)
+
+	// The CSV file may have short IDs from earlier versions, Replace actions are translated to the current IDs
+	currentIssueIDs := map[string]string{}
+	for issueID, issue := range currentIssues {
+		if resolvedIssue, exists := getResolvedIssue(resolvedIssues, issue); exists && resolvedIssue.IssueID != "" {
+			currentIssueIDs[resolvedIssue.IssueID] = issueID
+		}
+	}
+
+	exportIssues := map[string]types.Issue{}
+	carried := 0
+	for issueID, issue := range currentIssues {
+		if ruleResolvedIssue, exists := ruleResolvedIssues[issueID]; exists {
+			exportIssues[issueID] = ruleResolvedIssue
+			continue
+		}
+
+		exportIssues[issueID] = issue
+		resolvedIssue, exists := getResolvedIssue(resolvedIssues, issue)
+		if !exists {
+			continue
+		}
+
+		// Reviewer notes stay with the issue, even when its resolution is stale
+		issue.ExtraColumns = resolvedIssue.ExtraColumns
+		exportIssues[issueID] = issue
+
+		// Multiple resource ID issues with every candidate ignored have no resolution to carry
+		if resolvedIssue.IssueID == "" {
+			continue
+		}
+
+		carriedIssue, staleReason := carryForwardResolution(issue, resolvedIssue, currentIssueIDs)
+		if staleReason != "" {
+			mappingClient.Logger.Warnf("Resolution for Issue ID: %s, Address: %s no longer applies, %s", issueID, issue.ResourceAddress, staleReason)
+			resolvedIssue.StaleReason = staleReason
+			resolvedIssue.ExtraColumns = nil
+			exportIssues[staleIssueKeyPrefix+issueID] = resolvedIssue
+			continue
+		}
+		exportIssues[issueID] = carriedIssue
+		carried++
+	}
+
+	stale := 0
+	if resolvedIssues != nil {
+		for resolvedIssueID, resolvedIssue := range *resolvedIssues {
+			// Multiple resource ID issues with every candidate ignored have no resolution to carry
+			if resolvedIssue.IssueID == "" {
+				continue
+			}
+			if _, exists := currentIssueIDs[resolvedIssueID]; exists {
+				continue
+			}
+			resolvedIssue.StaleReason = "the issue no longer exists"
+			exportIssues[staleIssueKeyPrefix+resolvedIssueID] = resolvedIssue
+			stale++
+		}
+	}
+
+	mappingClient.Logger.Infof("Carried forward %d resolutions from the CSV file, %d resolutions are stale as their issue no longer exists", carried, stale)
+	return exportIssues
+}
+
+// carryForwardResolution copies a resolution from the CSV file to the current issue, returning why it is stale when
+// it no longer applies.
+func carryForwardResolution(issue types.Issue, resolvedIssue types.Issue, currentIssueIDs map[string]string) (types.Issue, string) {
+	// Resolutions file entries keyed by issue ID may not say which type of issue they resolve
+	if resolvedIssue.IssueType != "" && resolvedIssue.IssueType != issue.IssueType {
+		return issue, fmt.Sprintf("the issue type changed from %s to %s", resolvedIssue.IssueType, issue.IssueType)
+	}
+
+	resolution := resolvedIssue.Resolution
+	switch resolution.ActionType {
+	case types.ActionTypeUse:
+		candidateID := ""
+		if len(resolvedIssue.MappedResourceIDs) > 0 {
+			for _, mappedResourceID := range issue.MappedResourceIDs {
+				if azure.NormalizeResourceID(mappedResourceID) == azure.NormalizeResourceID(resolvedIssue.MappedResourceIDs[0]) {
+					candidateID = mappedResourceID
+					break
+				}
+			}
+		}
+		if candidateID == "" {
+			return issue, "the resource ID chosen with Use is no longer a candidate"
+		}
+		issue.MappedResourceIDs = []string{candidateID}
+	case types.ActionTypeReplace:
+		if resolution.ActionID != "" {
+			actionID, exists := currentIssueIDs[resolution.ActionID]
+			if !exists {
+				return issue, fmt.Sprintf("the issue %s paired with Replace no longer exists", resolution.ActionID)
+			}
+			resolution.ActionID = actionID
+		}
+	}
+
+	issue.Resolution = resolution
+	issue.ResolvedBy = resolvedIssue.ResolvedBy
+	return issue, ""
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMappingClient_carryForwardResolutions(t *testing.T) {
+	graphResources := []*types.GraphResource{
+		{ID: "/rg/id-a", Name: "multi", Type: "type1", Location: "eastus"},
+		{ID: "/rg/id-b", Name: "multi", Type: "type1", Location: "eastus"},
+		{ID: "/rg/id-c", Name: "other", Type: "type1", Location: "eastus"},
+		{ID: "/rg/id-d", Name: "other", Type: "type1", Location: "eastus"},
+		{ID: "/rg/unused", Name: "unused", Type: "type1", Location: "eastus"},
+		{ID: "/rg/replacement", Name: "replacement", Type: "type1", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{
+		{Address: "type1.ignored", ResourceName: "ignored", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: "type1.used", ResourceName: "multi", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: "type1.changed", ResourceName: "other", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: "type1.replaced", ResourceName: "renamed", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: "type1.new", ResourceName: "new", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+
+	// The Replace pair uses short IDs written by earlier versions
+	replacedIssueID := types.GetIssueID("type1.replaced")[:7]
+	replacementIssueID := types.GetIssueID("/rg/replacement")[:7]
+	resolvedIssues := map[string]types.Issue{
+		types.GetIssueID("type1.ignored"): {IssueID: types.GetIssueID("type1.ignored"), IssueType: types.IssueTypeNoResourceID, ResourceAddress: "type1.ignored", Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}},
+		types.GetIssueID("type1.used"):    {IssueID: types.GetIssueID("type1.used"), IssueType: types.IssueTypeMultipleResourceIDs, ResourceAddress: "type1.used", MappedResourceIDs: []string{"/rg/id-b"}, Resolution: types.IssueResolution{ActionType: types.ActionTypeUse}},
+		types.GetIssueID("type1.changed"): {IssueID: types.GetIssueID("type1.changed"), IssueType: types.IssueTypeMultipleResourceIDs, ResourceAddress: "type1.changed", MappedResourceIDs: []string{"/rg/id-gone"}, Resolution: types.IssueResolution{ActionType: types.ActionTypeUse}, ExtraColumns: map[string]string{"Owner": "network"}},
+		types.GetIssueID("/rg/unused"):    {IssueID: types.GetIssueID("/rg/unused"), IssueType: types.IssueTypeUnusedResourceID, ResourceAddress: "/rg/unused", Resolution: types.IssueResolution{ActionType: types.ActionTypeDestroy}},
+		replacedIssueID:                   {IssueID: replacedIssueID, IssueType: types.IssueTypeNoResourceID, ResourceAddress: "type1.replaced", Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: replacementIssueID}},
+		replacementIssueID:                {IssueID: replacementIssueID, IssueType: types.IssueTypeUnusedResourceID, ResourceAddress: "/rg/replacement", Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace}},
+		types.GetIssueID("type1.removed"): {IssueID: types.GetIssueID("type1.removed"), IssueType: types.IssueTypeNoResourceID, ResourceAddress: "type1.removed", Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}},
+	}
+	client := &MappingClient{Logger: logrus.New(), HasInputCsv: true}
+
+	exportIssues := client.carryForwardResolutions(graphResources, planResources, &resolvedIssues, map[string]types.Issue{})
+
+	ignored := exportIssues[types.GetIssueID("type1.ignored")]
+	assert.Equal(t, types.ActionTypeIgnore, ignored.Resolution.ActionType)
+
+	used := exportIssues[types.GetIssueID("type1.used")]
+	assert.Equal(t, types.ActionTypeUse, used.Resolution.ActionType)
+	assert.Equal(t, []string{"/rg/id-b"}, used.MappedResourceIDs)
+
+	changed := exportIssues[types.GetIssueID("type1.changed")]
+	assert.Equal(t, types.ActionTypeNone, changed.Resolution.ActionType)
+	assert.Len(t, changed.MappedResourceIDs, 2)
+	staleChanged := exportIssues[staleIssueKeyPrefix+types.GetIssueID("type1.changed")]
+	assert.Contains(t, staleChanged.StaleReason, "no longer a candidate")
+	assert.Equal(t, map[string]string{"Owner": "network"}, changed.ExtraColumns)
+	assert.Nil(t, staleChanged.ExtraColumns)
+
+	assert.Equal(t, types.ActionTypeDestroy, exportIssues[types.GetIssueID("/rg/unused")].Resolution.ActionType)
+
+	replaced := exportIssues[types.GetIssueID("type1.replaced")]
+	assert.Equal(t, types.ActionTypeReplace, replaced.Resolution.ActionType)
+	assert.Equal(t, types.GetIssueID("/rg/replacement"), replaced.Resolution.ActionID)
+	assert.Equal(t, types.ActionTypeReplace, exportIssues[types.GetIssueID("/rg/replacement")].Resolution.ActionType)
+
+	assert.Equal(t, types.ActionTypeNone, exportIssues[types.GetIssueID("type1.new")].Resolution.ActionType)
+
+	removed := exportIssues[staleIssueKeyPrefix+types.GetIssueID("type1.removed")]
+	assert.Equal(t, "the issue no longer exists", removed.StaleReason)
+
+	assert.Len(t, exportIssues, 9)
+}
+
+func Test_carryForwardResolution_UseMatchesWholeResourceID(t *testing.T) {
+	hubID := "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub"
+	resolvedIssue := types.Issue{IssueType: types.IssueTypeMultipleResourceIDs, MappedResourceIDs: []string{hubID}, Resolution: types.IssueResolution{ActionType: types.ActionTypeUse}}
+
+	issue := types.Issue{IssueType: types.IssueTypeMultipleResourceIDs, MappedResourceIDs: []string{hubID + "-2", hubID + "-3"}}
+	_, staleReason := carryForwardResolution(issue, resolvedIssue, nil)
+	assert.Equal(t, "the resource ID chosen with Use is no longer a candidate", staleReason)
+
+	issue.MappedResourceIDs = []string{hubID + "-2", strings.ToUpper(hubID)}
+	carried, staleReason := carryForwardResolution(issue, resolvedIssue, nil)
+	assert.Empty(t, staleReason)
+	assert.Equal(t, []string{strings.ToUpper(hubID)}, carried.MappedResourceIDs)
+}
@@ -0,0 +1,336 @@
+package analyzer
+
+import (
+	"context"
+	encodingjson "encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/hcl"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+var compareSkippedAttributes = []string{"id", "name", "resource_group_name", "parent_id", "timeouts", "type", "body", "output", "retry", "response_export_values"}
+
+func (mappingClient *MappingClient) Compare(ctx context.Context) (*CompareResult, error) {
+	result := &CompareResult{OutputFiles: []string{}}
+
+	graphResources, planResources, resolvedIssues, err := mappingClient.getResources(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	finalMappedResources, issues, _ := mappingClient.mapResourcesFromGraphToPlan(graphResources, planResources, resolvedIssues)
+	if len(issues) > 0 {
+		mappingClient.Logger.Warnf("Found %d unresolved issues, only resources that are already mapped will be compared", len(issues))
+	}
+
+	liveMappedResources, err := mappingClient.getLiveMappedResources(ctx, finalMappedResources, planResources, graphResources)
+	if err != nil {
+		return nil, err
+	}
+
+	result.AttributeDifferences = mappingClient.compareMappedResources(liveMappedResources)
+	mappingClient.Logger.Infof("Found %d attribute differences between Azure and the Terraform Plan", len(result.AttributeDifferences))
+
+	jsonFilePath, err := mappingClient.JsonClient.Export(result.AttributeDifferences, "attribute_differences.json")
+	if err != nil {
+		return result, fmt.Errorf("failed to export attribute differences: %w", err)
+	}
+	result.OutputFiles = append(result.OutputFiles, jsonFilePath)
+
+	reportFilePath, err := mappingClient.writeAttributeDifferencesReport(result.AttributeDifferences, "attribute_differences.txt")
+	if err != nil {
+		return result, err
+	}
+	result.OutputFiles = append(result.OutputFiles, reportFilePath)
+
+	if len(mappingClient.VariableMappings) > 0 {
+		result.VariableValues = mappingClient.suggestVariableValues(liveMappedResources)
+		variablesFilePath := filepath.Join(mappingClient.WorkingFolderPath, "suggested.auto.tfvars")
+		if err := mappingClient.HclClient.WriteVariableValues(result.VariableValues, variablesFilePath); err != nil {
+			return result, fmt.Errorf("failed to write suggested variable values: %w", err)
+		}
+		result.OutputFiles = append(result.OutputFiles, variablesFilePath)
+	}
+
+	return result, nil
+}
+
+type liveMappedResource struct {
+	PlanResource  *types.PlanResource
+	GraphResource *types.GraphResource
+	LiveResource  map[string]any
+}
+
+// getLiveMappedResources pairs each mapped plan resource with the live ARM body of the Azure resource it maps to.
+func (mappingClient *MappingClient) getLiveMappedResources(ctx context.Context, finalMappedResources []types.MappedResource, planResources []*types.PlanResource, graphResources []*types.GraphResource) ([]liveMappedResource, error) {
+	planResourcesByAddress := map[string]*types.PlanResource{}
+	for _, planResource := range planResources {
+		planResourcesByAddress[planResource.Address] = planResource
+	}
+	graphResourcesByID := map[string]*types.GraphResource{}
+	for _, graphResource := range graphResources {
+		graphResourcesByID[graphResource.ID] = graphResource
+	}
+
+	liveMappedResources := []liveMappedResource{}
+	for _, finalMappedResource := range finalMappedResources {
+		if finalMappedResource.Type != types.MappedResourceTypeTerraform || finalMappedResource.ResourceID == "" {
+			continue
+		}
+		if finalMappedResource.ActionType != types.ActionTypeUse && finalMappedResource.ActionType != types.ActionTypeReplace {
+			continue
+		}
+
+		planResource := planResourcesByAddress[finalMappedResource.ResourceAddress]
+		graphResource := graphResourcesByID[finalMappedResource.ResourceID]
+		if planResource == nil || graphResource == nil {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		liveResource, ok := mappingClient.getLiveResource(ctx, planResource, graphResource)
+		if !ok {
+			continue
+		}
+
+		liveMappedResources = append(liveMappedResources, liveMappedResource{
+			PlanResource:  planResource,
+			GraphResource: graphResource,
+			LiveResource:  liveResource,
+		})
+	}
+
+	sort.Slice(liveMappedResources, func(i, j int) bool {
+		return liveMappedResources[i].PlanResource.Address < liveMappedResources[j].PlanResource.Address
+	})
+
+	return liveMappedResources, nil
+}
+
+func (mappingClient *MappingClient) compareMappedResources(liveMappedResources []liveMappedResource) []types.AttributeDifference {
+	differences := []types.AttributeDifference{}
+	for _, liveMappedResource := range liveMappedResources {
+		for _, difference := range compareResource(liveMappedResource.PlanResource, liveMappedResource.LiveResource) {
+			difference.ResourceID = liveMappedResource.GraphResource.ID
+			suggestedValue, err := hcl.FormatValue(difference.LiveValue)
+			if err != nil {
+				mappingClient.Logger.Debugf("Unable to format suggested value for %s %s: %v", difference.ResourceAddress, difference.AttributePath, err)
+			}
+			difference.SuggestedValue = suggestedValue
+			differences = append(differences, difference)
+		}
+	}
+
+	sort.SliceStable(differences, func(i, j int) bool {
+		if differences[i].ResourceAddress != differences[j].ResourceAddress {
+			return differences[i].ResourceAddress < differences[j].ResourceAddress
+		}
+		return differences[i].AttributePath < differences[j].AttributePath
+	})
+
+	return differences
+}
+
+// getLiveResource returns the ARM body of a resource, using a GET with the azapi API version when there is one
+// and falling back to the properties returned by the Resource Graph query.
+func (mappingClient *MappingClient) getLiveResource(ctx context.Context, planResource *types.PlanResource, graphResource *types.GraphResource) (map[string]any, bool) {
+	if planResource.APIVersion != "" && mappingClient.ResourceClient != nil {
+		liveResource, err := mappingClient.ResourceClient.GetResource(ctx, graphResource.ID, planResource.APIVersion)
+		if err == nil {
+			return liveResource, true
+		}
+		mappingClient.Logger.Warnf("Unable to get resource %s with API version %s, falling back to Resource Graph properties: %v", graphResource.ID, planResource.APIVersion, err)
+	}
+
+	if _, ok := graphResource.Properties["properties"]; ok {
+		return graphResource.Properties, true
+	}
+
+	mappingClient.Logger.Warnf("No properties available to compare for Address: %s, Resource ID: %s, add 'properties' to the Resource Graph query projection", planResource.Address, graphResource.ID)
+	return nil, false
+}
+
+func compareResource(planResource *types.PlanResource, liveResource map[string]any) []types.AttributeDifference {
+	differences := []types.AttributeDifference{}
+	addDifference := func(attributePath string, plannedValue any, liveValue any) {
+		differences = append(differences, types.AttributeDifference{
+			ResourceAddress: planResource.Address,
+			ResourceType:    planResource.Type,
+			AttributePath:   attributePath,
+			PlannedValue:    plannedValue,
+			LiveValue:       liveValue,
+		})
+	}
+
+	if strings.HasPrefix(planResource.Type, "azapi_") {
+		body := planResource.Properties["body"]
+		if bodyString, ok := body.(string); ok {
+			if err := encodingjson.Unmarshal([]byte(bodyString), &body); err != nil {
+				body = nil
+			}
+		}
+		compareValues("body", body, liveResource, planResource.PropertiesCalculated["body"], addDifference)
+	}
+
+	attributeNames := []string{}
+	for attributeName := range planResource.Properties {
+		attributeNames = append(attributeNames, attributeName)
+	}
+	sort.Strings(attributeNames)
+
+	for _, attributeName := range attributeNames {
+		if strings.HasPrefix(attributeName, "meta.") || containsString(compareSkippedAttributes, attributeName) {
+			continue
+		}
+
+		// azurerm attributes are snake_case, so look for the camelCase equivalent at the root and in properties
+		liveName := snakeToCamel(attributeName)
+		liveValue, found := lookupKey(liveResource, liveName)
+		if !found {
+			if liveProperties, ok := liveResource["properties"].(map[string]any); ok {
+				liveValue, found = lookupKey(liveProperties, liveName)
+			}
+		}
+		if !found {
+			continue
+		}
+
+		compareValues(attributeName, planResource.Properties[attributeName], liveValue, planResource.PropertiesCalculated[attributeName], addDifference)
+	}
+
+	return differences
+}
+
+func compareValues(attributePath string, plannedValue any, liveValue any, unknown any, addDifference func(string, any, any)) {
+	if plannedValue == nil || unknown == true {
+		return
+	}
+
+	switch planned := plannedValue.(type) {
+	case map[string]any:
+		live, ok := liveValue.(map[string]any)
+		if !ok {
+			addDifference(attributePath, plannedValue, liveValue)
+			return
+		}
+		unknownMap, _ := unknown.(map[string]any)
+		keys := []string{}
+		for key := range planned {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			liveChild, found := lookupKey(live, key)
+			if !found {
+				continue
+			}
+			compareValues(attributePath+"."+key, planned[key], liveChild, unknownMap[key], addDifference)
+		}
+	case []any:
+		live, ok := liveValue.([]any)
+		if !ok || len(live) != len(planned) {
+			addDifference(attributePath, plannedValue, liveValue)
+			return
+		}
+		unknownSlice, _ := unknown.([]any)
+		for i := range planned {
+			var unknownChild any
+			if i < len(unknownSlice) {
+				unknownChild = unknownSlice[i]
+			}
+			switch planned[i].(type) {
+			case map[string]any, []any:
+				compareValues(fmt.Sprintf("%s[%d]", attributePath, i), planned[i], live[i], unknownChild, addDifference)
+				continue
+			}
+			if unknownChild != true && !scalarEqual(attributePath, planned[i], live[i]) {
+				addDifference(attributePath, plannedValue, liveValue)
+				return
+			}
+		}
+	default:
+		if !scalarEqual(attributePath, plannedValue, liveValue) {
+			addDifference(attributePath, plannedValue, liveValue)
+		}
+	}
+}
+
+func scalarEqual(attributePath string, plannedValue any, liveValue any) bool {
+	if plannedValue == liveValue {
+		return true
+	}
+
+	planned := fmt.Sprint(plannedValue)
+	live := fmt.Sprint(liveValue)
+	if strings.HasSuffix(strings.ToLower(attributePath), "location") {
+		planned = strings.ReplaceAll(planned, " ", "")
+		live = strings.ReplaceAll(live, " ", "")
+	}
+	return liveValue != nil && strings.EqualFold(planned, live)
+}
+
+func lookupKey(values map[string]any, key string) (any, bool) {
+	if value, ok := values[key]; ok {
+		return value, true
+	}
+	for candidateKey, value := range values {
+		if strings.EqualFold(candidateKey, key) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func snakeToCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (mappingClient *MappingClient) writeAttributeDifferencesReport(differences []types.AttributeDifference, fileName string) (string, error) {
+	lines := []string{}
+	currentAddress := ""
+	for _, difference := range differences {
+		if difference.ResourceAddress != currentAddress {
+			if currentAddress != "" {
+				lines = append(lines, "")
+			}
+			currentAddress = difference.ResourceAddress
+			lines = append(lines, fmt.Sprintf("# %s (%s)", difference.ResourceAddress, difference.ResourceID))
+		}
+
+		plannedValue, _ := hcl.FormatValue(difference.PlannedValue)
+		lines = append(lines, fmt.Sprintf("  %s", difference.AttributePath))
+		lines = append(lines, fmt.Sprintf("    planned:   %s", plannedValue))
+		lines = append(lines, fmt.Sprintf("    suggested: %s", difference.SuggestedValue))
+	}
+
+	reportFilePath := filepath.Join(mappingClient.WorkingFolderPath, fileName)
+	err := os.WriteFile(reportFilePath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	mappingClient.Logger.Infof("Attribute differences written to %s", reportFilePath)
+	return reportFilePath, nil
+}
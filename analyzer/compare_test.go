@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockResourceClient struct {
+	Resources map[string]map[string]any
+	Called    bool
+}
+
+func (m *mockResourceClient) GetResource(ctx context.Context, resourceID string, apiVersion string) (map[string]any, error) {
+	m.Called = true
+	return m.Resources[resourceID], nil
+}
+
+func Test_compareResource_AzapiBody(t *testing.T) {
+	planResource := &types.PlanResource{
+		Address: "azapi_resource.vnet",
+		Type:    "azapi_resource",
+		Properties: map[string]any{
+			"location": "uksouth",
+			"body": map[string]any{
+				"properties": map[string]any{
+					"addressSpace":         map[string]any{"addressPrefixes": []any{"10.1.0.0/16"}},
+					"enableDdosProtection": false,
+				},
+			},
+		},
+		PropertiesCalculated: map[string]any{},
+	}
+	liveResource := map[string]any{
+		"location": "UK South",
+		"properties": map[string]any{
+			"addressSpace":         map[string]any{"addressPrefixes": []any{"10.0.0.0/16"}},
+			"enableDdosProtection": false,
+			"provisioningState":    "Succeeded",
+		},
+	}
+
+	differences := compareResource(planResource, liveResource)
+
+	assert.Len(t, differences, 1)
+	assert.Equal(t, "body.properties.addressSpace.addressPrefixes", differences[0].AttributePath)
+	assert.Equal(t, []any{"10.0.0.0/16"}, differences[0].LiveValue)
+}
+
+func Test_compareResource_AzurermAttributes(t *testing.T) {
+	planResource := &types.PlanResource{
+		Address: "azurerm_public_ip.pip",
+		Type:    "azurerm_public_ip",
+		Properties: map[string]any{
+			"name":              "pip",
+			"sku":               "Standard",
+			"allocation_method": "Static",
+			"tags":              map[string]any{"env": "prod"},
+			"ip_address":        nil,
+		},
+		PropertiesCalculated: map[string]any{"ip_address": true},
+	}
+	liveResource := map[string]any{
+		"properties": map[string]any{
+			"publicIPAllocationMethod": "Static",
+			"allocationMethod":         "Dynamic",
+		},
+		"tags": map[string]any{"env": "dev"},
+	}
+
+	differences := compareResource(planResource, liveResource)
+
+	assert.Len(t, differences, 2)
+	assert.Equal(t, "allocation_method", differences[0].AttributePath)
+	assert.Equal(t, "tags.env", differences[1].AttributePath)
+}
+
+func Test_compareMappedResources_UsesApiVersionWhenAvailable(t *testing.T) {
+	resourceClient := &mockResourceClient{Resources: map[string]map[string]any{
+		"id1": {"properties": map[string]any{"sku": "Premium"}},
+	}}
+	client := &MappingClient{Logger: logrus.New(), ResourceClient: resourceClient}
+	planResources := []*types.PlanResource{{
+		Address: "azapi_resource.res1", Type: "azapi_resource", APIVersion: "2024-01-01",
+		Properties: map[string]any{"body": map[string]any{"properties": map[string]any{"sku": "Standard"}}},
+	}}
+	graphResources := []*types.GraphResource{{ID: "id1", Name: "res1"}}
+	finalMappedResources := []types.MappedResource{{
+		Type: types.MappedResourceTypeTerraform, ResourceAddress: "azapi_resource.res1", ResourceID: "id1", ActionType: types.ActionTypeUse,
+	}}
+
+	liveMappedResources, err := client.getLiveMappedResources(context.Background(), finalMappedResources, planResources, graphResources)
+	differences := client.compareMappedResources(liveMappedResources)
+
+	assert.NoError(t, err)
+	assert.True(t, resourceClient.Called)
+	assert.Len(t, differences, 1)
+	assert.Equal(t, "id1", differences[0].ResourceID)
+	assert.Equal(t, `"Premium"`, differences[0].SuggestedValue)
+}
@@ -1,9 +1,10 @@
 package analyzer
 
 import (
-	"crypto/sha256"
-
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -16,10 +17,22 @@ import (
 	"github.com/azure/terraform-state-importer/types"
 )
 
+const (
+	importsFileName = "imports.tf"
+	destroyFileName = "destroy.tf"
+	movedFileName   = "moved.tf"
+	removedFileName = "removed.tf"
+)
+
 type MappingClient struct {
 	WorkingFolderPath   string
 	HasInputCsv         bool
+	VariableMappings    []types.VariableMapping
+	CandidateScoring    types.CandidateScoring
+	ResolutionRules     []types.ResolutionRule
+	SplitImportsBy      types.SplitImportsBy
 	ResourceGraphClient azure.IResourceGraphClient
+	ResourceClient      azure.IResourceClient
 	PlanClient          terraform.IPlanClient
 	IssueCsvClient      csv.IIssueCsvClient
 	JsonClient          json.IJsonClient
@@ -27,11 +40,16 @@ type MappingClient struct {
 	Logger              *logrus.Logger
 }
 
-func NewMappingClient(workingFolderPath string, hasInputCsv bool, resourceGraphClient azure.IResourceGraphClient, planClient terraform.IPlanClient, issueCsvClient csv.IIssueCsvClient, jsonClient json.IJsonClient, hclClient hcl.IHclClient, logger *logrus.Logger) *MappingClient {
+func NewMappingClient(workingFolderPath string, hasInputCsv bool, variableMappings []types.VariableMapping, candidateScoring types.CandidateScoring, resolutionRules []types.ResolutionRule, splitImportsBy types.SplitImportsBy, resourceGraphClient azure.IResourceGraphClient, resourceClient azure.IResourceClient, planClient terraform.IPlanClient, issueCsvClient csv.IIssueCsvClient, jsonClient json.IJsonClient, hclClient hcl.IHclClient, logger *logrus.Logger) *MappingClient {
 	return &MappingClient{
 		WorkingFolderPath:   workingFolderPath,
 		HasInputCsv:         hasInputCsv,
+		VariableMappings:    variableMappings,
+		CandidateScoring:    candidateScoring,
+		ResolutionRules:     resolutionRules,
+		SplitImportsBy:      splitImportsBy,
 		ResourceGraphClient: resourceGraphClient,
+		ResourceClient:      resourceClient,
 		PlanClient:          planClient,
 		IssueCsvClient:      issueCsvClient,
 		JsonClient:          jsonClient,
@@ -40,45 +58,95 @@ func NewMappingClient(workingFolderPath string, hasInputCsv bool, resourceGraphC
 	}
 }
 
-func (mappingClient *MappingClient) Map() {
-	resolvedIssues := mappingClient.getResolvedIssues()
+func (mappingClient *MappingClient) Map(ctx context.Context) (*Result, error) {
+	result := &Result{
+		MappedResources: []types.MappedResource{},
+		Issues:          map[string]types.Issue{},
+		Errors:          []string{},
+		OutputFiles:     []string{},
+	}
 
-	graphResources, err := mappingClient.ResourceGraphClient.GetResources()
+	// The files of the previous run are removed, so imports split into files that are no longer written are not planned
+	filesToClean, err := mappingClient.getFilesToClean()
 	if err != nil {
-		mappingClient.Logger.Fatalf("Error getting resources from Resource Graph: %v", err)
+		return nil, err
+	}
+	result.CleanedFiles = filesToClean
+	graphResources, planResources, resolvedIssues, err := mappingClient.getResources(ctx, filesToClean)
+	if err != nil {
+		return nil, err
 	}
 
-	importsFileName := "imports.tf"
-	destroyFileName := "destroy.tf"
-
-	mappingClient.HclClient.CleanFiles([]string{importsFileName, destroyFileName})
+	finalMappedResources, issues, mappingErrors := mappingClient.mapResourcesFromGraphToPlan(graphResources, planResources, resolvedIssues)
 
-	planResources := mappingClient.PlanClient.PlanAndGetResources()
+	ruleResolvedIssues := mappingClient.applyResolutionRules(issues, resolvedIssues)
+	if len(ruleResolvedIssues) > 0 {
+		mappingClient.Logger.Infof("Resolved %d issues with resolution rules", len(ruleResolvedIssues))
+		finalMappedResources, issues, mappingErrors = mappingClient.mapResourcesFromGraphToPlan(graphResources, planResources, mergeResolvedIssues(resolvedIssues, ruleResolvedIssues))
+	}
 
-	finalMappedResources, issues, errors := mappingClient.mapResourcesFromGraphToPlan(graphResources, planResources, resolvedIssues)
+	result.MappedResources = finalMappedResources
+	result.Issues = issues
+	result.Errors = mappingErrors
 
-	mappingClient.JsonClient.Export(issues, "issues.json")
-	mappingClient.JsonClient.Export(planResources, "resources.json")
+	if err := mappingClient.exportJson(result, issues, "issues.json"); err != nil {
+		return result, err
+	}
+	if err := mappingClient.exportJson(result, planResources, "resources.json"); err != nil {
+		return result, err
+	}
 
 	if len(issues) > 0 {
 		mappingClient.Logger.Warnf("Found %d issues based on the Terraform Plan and Resource Graph Queries", len(issues))
-		mappingClient.IssueCsvClient.Export(issues)
-		if len(errors) > 0 {
-			mappingClient.Logger.Fatalf("Found %d errors during mapping: %v", len(errors), errors)
+	}
+	// Rule resolved issues are included, so reviewers can audit them alongside the issues still to resolve. They are
+	// written even when no issues are left, so the blocks generated by rules, such as Destroy, can be reviewed first.
+	if len(issues) > 0 || len(ruleResolvedIssues) > 0 {
+		csvIssues := map[string]types.Issue{}
+		if resolvedIssues != nil {
+			csvIssues = mappingClient.carryForwardResolutions(graphResources, planResources, resolvedIssues, ruleResolvedIssues)
+		} else {
+			for issueID, issue := range issues {
+				csvIssues[issueID] = issue
+			}
+			for issueID, issue := range ruleResolvedIssues {
+				csvIssues[issueID] = issue
+			}
 		}
-		return
-	} else {
-		mappingClient.Logger.Info("No issues found based on the Terraform Plan and Resource Graph Queries")
-		mappingClient.JsonClient.Export(finalMappedResources, "final.json")
-		if len(errors) > 0 {
-			mappingClient.Logger.Fatalf("Found %d errors during mapping: %v", len(errors), errors)
+		csvFilePath, err := mappingClient.IssueCsvClient.Export(csvIssues)
+		if err != nil {
+			return result, fmt.Errorf("failed to export issues: %w", err)
 		}
+		result.OutputFiles = append(result.OutputFiles, csvFilePath)
+		if len(issues) == 0 {
+			mappingClient.Logger.Infof("Wrote %d issues resolved by rules to %s, review them before applying the generated files", len(ruleResolvedIssues), csvFilePath)
+		}
+	}
+	if len(issues) > 0 {
+		return result, mappingErrorsToError(mappingErrors)
+	}
+
+	mappingClient.Logger.Info("No issues found based on the Terraform Plan and Resource Graph Queries")
+	stateResources, err := mappingClient.PlanClient.GetStateResources()
+	if err != nil {
+		return result, fmt.Errorf("failed to read the state: %w", err)
+	}
+	movedBlocks := mappingClient.getMovedBlocks(finalMappedResources, planResources, stateResources)
+	removedBlocks, removedErrors := mappingClient.getRemovedBlocks(finalMappedResources, planResources, stateResources)
+	mappingErrors = append(mappingErrors, removedErrors...)
+	result.Errors = mappingErrors
+
+	if err := mappingClient.exportJson(result, finalMappedResources, "final.json"); err != nil {
+		return result, err
+	}
+	if len(mappingErrors) > 0 {
+		return result, mappingErrorsToError(mappingErrors)
 	}
 
 	importBlocks := []types.ImportBlock{}
 	destroyBlocks := []types.DestroyBlock{}
 	for _, finalMappedResource := range finalMappedResources {
-		if finalMappedResource.ActionType == types.ActionTypeUse && finalMappedResource.Type == types.MappedResourceTypeTerraform {
+		if finalMappedResource.ActionType == types.ActionTypeUse && finalMappedResource.Type == types.MappedResourceTypeTerraform && finalMappedResource.MovedFrom == "" {
 			resourceID := finalMappedResource.ResourceID
 			if finalMappedResource.ResourceAPIVersion != "" {
 				resourceID = fmt.Sprintf("%s?api-version=%s", resourceID, finalMappedResource.ResourceAPIVersion)
@@ -100,21 +168,230 @@ func (mappingClient *MappingClient) Map() {
 		}
 	}
 
-	mappingClient.HclClient.WriteImportBlocks(importBlocks, importsFileName)
-	mappingClient.HclClient.WriteDestroyBlocks(destroyBlocks, destroyFileName)
+	manifest := types.ImportManifest{
+		SplitImportsBy: mappingClient.SplitImportsBy,
+		Files:          []types.ImportManifestFile{},
+		GeneratedFiles: []string{},
+	}
+	generatedFilesStart := len(result.OutputFiles)
+	if mappingClient.SplitImportsBy != types.SplitImportsByNone {
+		manifestFiles, err := mappingClient.writeSplitImportBlocks(result, importBlocks)
+		if err != nil {
+			return result, err
+		}
+		manifest.Files = manifestFiles
+	} else {
+		importsFilePath, err := mappingClient.HclClient.WriteImportBlocks(importBlocks, importsFileName)
+		if err != nil {
+			return result, fmt.Errorf("failed to write import blocks: %w", err)
+		}
+		result.OutputFiles = append(result.OutputFiles, importsFilePath)
+	}
+
+	if len(movedBlocks) > 0 {
+		movedFilePath, err := mappingClient.HclClient.WriteMovedBlocks(movedBlocks, movedFileName)
+		if err != nil {
+			return result, fmt.Errorf("failed to write moved blocks: %w", err)
+		}
+		result.OutputFiles = append(result.OutputFiles, movedFilePath)
+	}
+
+	if len(removedBlocks) > 0 {
+		removedFilePath, err := mappingClient.HclClient.WriteRemovedBlocks(removedBlocks, removedFileName)
+		if err != nil {
+			return result, fmt.Errorf("failed to write removed blocks: %w", err)
+		}
+		result.OutputFiles = append(result.OutputFiles, removedFilePath)
+	}
+
+	destroyFilePath, err := mappingClient.HclClient.WriteDestroyBlocks(destroyBlocks, destroyFileName)
+	if err != nil {
+		return result, fmt.Errorf("failed to write destroy blocks: %w", err)
+	}
+	result.OutputFiles = append(result.OutputFiles, destroyFilePath)
+
+	for _, generatedFilePath := range result.OutputFiles[generatedFilesStart:] {
+		manifest.GeneratedFiles = append(manifest.GeneratedFiles, filepath.Base(generatedFilePath))
+	}
+	if err := mappingClient.exportJson(result, manifest, importsManifestFileName); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// getMovedBlocks finds the mapped resources that are already in state under another address, for example when a module
+// upgrade changes a resource from count to for_each. These are moved to their new address rather than imported, and
+// are marked with the address they are moved from.
+func (mappingClient *MappingClient) getMovedBlocks(finalMappedResources []types.MappedResource, planResources []*types.PlanResource, stateResources []*types.StateResource) []types.MovedBlock {
+	movedBlocks := []types.MovedBlock{}
+	if len(stateResources) == 0 {
+		return movedBlocks
+	}
+
+	stateAddresses := map[string]bool{}
+	stateAddressesByID := map[string][]string{}
+	for _, stateResource := range stateResources {
+		stateAddresses[normalizeAddress(stateResource.Address)] = true
+		resourceID := azure.NormalizeResourceID(stateResource.ResourceID)
+		stateAddressesByID[resourceID] = append(stateAddressesByID[resourceID], stateResource.Address)
+	}
+	plannedAddresses := map[string]bool{}
+	for _, planResource := range planResources {
+		plannedAddresses[normalizeAddress(planResource.Address)] = true
+	}
+
+	for i, finalMappedResource := range finalMappedResources {
+		if finalMappedResource.Type != types.MappedResourceTypeTerraform || finalMappedResource.ActionType != types.ActionTypeUse {
+			continue
+		}
+		if stateAddresses[normalizeAddress(finalMappedResource.ResourceAddress)] {
+			continue
+		}
+
+		resourceStateAddresses := stateAddressesByID[azure.NormalizeResourceID(finalMappedResource.ResourceID)]
+		fromAddresses := []string{}
+		for _, stateAddress := range resourceStateAddresses {
+			// An address that is still in the configuration keeps its resource, so the resource cannot be moved from it
+			if !plannedAddresses[normalizeAddress(stateAddress)] {
+				fromAddresses = append(fromAddresses, stateAddress)
+			}
+		}
+
+		switch {
+		case len(fromAddresses) == 1:
+			mappingClient.Logger.Infof("Moving Resource ID: %s from Address: %s to Address: %s", finalMappedResource.ResourceID, fromAddresses[0], finalMappedResource.ResourceAddress)
+			movedBlocks = append(movedBlocks, types.MovedBlock{From: fromAddresses[0], To: finalMappedResource.ResourceAddress})
+			finalMappedResources[i].MovedFrom = fromAddresses[0]
+		case len(fromAddresses) > 1:
+			mappingClient.Logger.Warnf("Resource ID: %s is in state at more than one address (%s), importing it to Address: %s instead of moving it", finalMappedResource.ResourceID, strings.Join(fromAddresses, ", "), finalMappedResource.ResourceAddress)
+		case len(resourceStateAddresses) > 0:
+			mappingClient.Logger.Warnf("Resource ID: %s is already in state at Address: %s, which is still in the configuration, so it is also imported to Address: %s", finalMappedResource.ResourceID, strings.Join(resourceStateAddresses, ", "), finalMappedResource.ResourceAddress)
+		}
+	}
+	return movedBlocks
+}
+
+// getRemovedBlocks finds the state addresses of the unused resources resolved with Forget. The address is taken from
+// the action ID when it is set, otherwise it is looked up in state by resource ID. Removed blocks cannot have instance
+// keys, so every instance of the resource is forgotten and the resource must no longer be in the configuration.
+func (mappingClient *MappingClient) getRemovedBlocks(finalMappedResources []types.MappedResource, planResources []*types.PlanResource, stateResources []*types.StateResource) ([]types.RemovedBlock, []string) {
+	removedBlocks := []types.RemovedBlock{}
+	errors := []string{}
+
+	stateAddressesByID := map[string][]string{}
+	for _, stateResource := range stateResources {
+		resourceID := azure.NormalizeResourceID(stateResource.ResourceID)
+		stateAddressesByID[resourceID] = append(stateAddressesByID[resourceID], stateResource.Address)
+	}
+	plannedAddresses := map[string]bool{}
+	for _, planResource := range planResources {
+		plannedAddresses[normalizeAddress(terraform.GetConfigurationAddress(planResource.Address))] = true
+	}
+
+	removedAddresses := map[string]bool{}
+	for i, finalMappedResource := range finalMappedResources {
+		if finalMappedResource.Type != types.MappedResourceTypeGraph || finalMappedResource.ActionType != types.ActionTypeForget {
+			continue
+		}
+
+		addresses := stateAddressesByID[azure.NormalizeResourceID(finalMappedResource.ResourceID)]
+		if finalMappedResource.ResourceAddress != "" {
+			addresses = []string{finalMappedResource.ResourceAddress}
+		}
+		if len(addresses) == 0 {
+			errorMessage := fmt.Sprintf("Resource ID %s is resolved with Forget but is not in state, set the Action ID to its address in state or pass the state with --stateFile", finalMappedResource.ResourceID)
+			errors = append(errors, errorMessage)
+			mappingClient.Logger.Warn(errorMessage)
+			continue
+		}
+		if len(addresses) > 1 {
+			errorMessage := fmt.Sprintf("Resource ID %s is resolved with Forget but is in state at more than one address (%s), set the Action ID to the address to forget", finalMappedResource.ResourceID, strings.Join(addresses, ", "))
+			errors = append(errors, errorMessage)
+			mappingClient.Logger.Warn(errorMessage)
+			continue
+		}
+
+		finalMappedResources[i].ResourceAddress = addresses[0]
+		configurationAddress := terraform.GetConfigurationAddress(addresses[0])
+		if plannedAddresses[normalizeAddress(configurationAddress)] {
+			errorMessage := fmt.Sprintf("Resource ID %s is resolved with Forget but %s is still in the configuration, remove it from the module before forgetting it", finalMappedResource.ResourceID, configurationAddress)
+			errors = append(errors, errorMessage)
+			mappingClient.Logger.Warn(errorMessage)
+			continue
+		}
+		if removedAddresses[normalizeAddress(configurationAddress)] {
+			continue
+		}
+		removedAddresses[normalizeAddress(configurationAddress)] = true
+		mappingClient.Logger.Infof("Forgetting Resource ID: %s at Address: %s", finalMappedResource.ResourceID, configurationAddress)
+		removedBlocks = append(removedBlocks, types.RemovedBlock{From: configurationAddress})
+	}
+	return removedBlocks, errors
+}
+
+// getResources reads the resolved issues, queries Resource Graph and plans the module, checking for cancellation between each step.
+// Files previously generated in the module are removed before planning, so they do not affect the plan.
+func (mappingClient *MappingClient) getResources(ctx context.Context, filesToClean []string) ([]*types.GraphResource, []*types.PlanResource, *map[string]types.Issue, error) {
+	resolvedIssues, err := mappingClient.getResolvedIssues()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	graphResources, planResources, err := mappingClient.getGraphAndPlanResources(ctx, filesToClean)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return graphResources, planResources, resolvedIssues, nil
+}
+
+func (mappingClient *MappingClient) getGraphAndPlanResources(ctx context.Context, filesToClean []string) ([]*types.GraphResource, []*types.PlanResource, error) {
+	graphResources, err := mappingClient.ResourceGraphClient.GetResources(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get resources from Resource Graph: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(filesToClean) > 0 {
+		if err := mappingClient.HclClient.CleanFiles(filesToClean); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	planResources, err := mappingClient.PlanClient.PlanAndGetResources(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get resources from the Terraform plan: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return graphResources, planResources, nil
 }
 
-func (mappingClient *MappingClient) getResolvedIssues() *map[string]types.Issue {
+func (mappingClient *MappingClient) exportJson(result *Result, resources any, fileName string) error {
+	filePath, err := mappingClient.JsonClient.Export(resources, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", fileName, err)
+	}
+	result.OutputFiles = append(result.OutputFiles, filePath)
+	return nil
+}
+
+func (mappingClient *MappingClient) getResolvedIssues() (*map[string]types.Issue, error) {
 	if mappingClient.HasInputCsv {
 		mappingClient.Logger.Info("Importing issues from supplied CSV file")
 		resolvedIssues, err := mappingClient.IssueCsvClient.Import()
 		if err != nil {
-			mappingClient.Logger.Fatalf("Error importing issues from CSV: %v", err)
+			return nil, fmt.Errorf("failed to import issues from CSV: %w", err)
 		}
 		mappingClient.Logger.Infof("Imported %d resolved issues from CSV file", len(*resolvedIssues))
-		return resolvedIssues
+		return resolvedIssues, nil
 	}
-	return nil
+	return nil, nil
 }
 
 func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*types.GraphResource, planResources []*types.PlanResource, resolvedIssues *map[string]types.Issue) ([]types.MappedResource, map[string]types.Issue, []string) {
@@ -123,26 +400,41 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 	uniqueUsedResources := make(map[string]*types.GraphResource)
 	errors := []string{}
 
+	// Replace targets are matched on the whole normalized resource ID, as IDs can be prefixes of each other
+	graphResourcesByID := map[string]*types.GraphResource{}
+	for _, graphResource := range graphResources {
+		graphResourcesByID[azure.NormalizeResourceID(graphResource.ID)] = graphResource
+	}
+	renames := map[string]*types.ResourceRename{}
+
 	for _, resource := range planResources {
-		finalMappedResource := types.MappedResource{
-			Type:               types.MappedResourceTypeTerraform,
-			ResourceAddress:    resource.Address,
-			ResourceAPIVersion: resource.APIVersion,
-			ResourceType:       resource.Type,
+		resource.MappedResources = nil
+		matcher, ok := GetMatcher(resource.ResourceNameMatchType)
+		if !ok && resource.ResourceNameMatchType != "" {
+			importer.Logger.Warnf("Unknown name match type %s for Address: %s", resource.ResourceNameMatchType, resource.Address)
 		}
 
 		for _, graphResource := range graphResources {
-			if resource.ResourceNameMatchType == types.NameMatchTypeExact && strings.ToLower(graphResource.Name) == strings.ToLower(resource.ResourceName) {
+			if ok && matcher.Matches(resource, graphResource) {
+				if !resourceTypeMatches(resource, graphResource) {
+					importer.Logger.Debugf("Skipping Resource ID: %s of Type: %s for Address: %s, expected Type: %s", graphResource.ID, graphResource.Type, resource.Address, resource.AzureResourceType)
+					continue
+				}
 				resource.MappedResources = append(resource.MappedResources, graphResource)
 			}
+		}
+	}
 
-			if resource.ResourceNameMatchType == types.NameMatchTypeIDContains && strings.Contains(strings.ToLower(graphResource.ID), strings.ToLower(resource.ResourceName)) {
-				resource.MappedResources = append(resource.MappedResources, graphResource)
-			}
+	importer.applyParentAffinity(planResources)
 
-			if resource.ResourceNameMatchType == types.NameMatchTypeIDEndsWith && strings.HasSuffix(strings.ToLower(graphResource.ID), strings.ToLower(resource.ResourceName)) {
-				resource.MappedResources = append(resource.MappedResources, graphResource)
-			}
+	placements := getSiblingPlacements(planResources)
+
+	for _, resource := range planResources {
+		finalMappedResource := types.MappedResource{
+			Type:               types.MappedResourceTypeTerraform,
+			ResourceAddress:    resource.Address,
+			ResourceAPIVersion: resource.APIVersion,
+			ResourceType:       resource.Type,
 		}
 
 		hadIssue := false
@@ -152,8 +444,9 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 			issue := IssueFromPlanResource(resource)
 
 			resolved := false
-			if importer.HasInputCsv {
-				if resolvedIssue, exists := (*resolvedIssues)[getIdentityHash(resource.Address)]; exists {
+			if resolvedIssues != nil {
+				if resolvedIssue, exists := getResolvedIssue(resolvedIssues, issue); exists {
+					finalMappedResource.ResolvedBy = resolvedIssue.ResolvedBy
 					if resolvedIssue.Resolution.ActionType == types.ActionTypeIgnore {
 						importer.Logger.Debugf("Ignoring Issue ID: %s, Action: %s", resolvedIssue.IssueID, resolvedIssue.Resolution.ActionType)
 						finalMappedResource.IssueType = types.IssueTypeNoResourceID
@@ -161,19 +454,31 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 						resolved = true
 					}
 					if resolvedIssue.Resolution.ActionType == types.ActionTypeReplace {
-						matchedGraphResourceID := (*resolvedIssues)[resolvedIssue.Resolution.ActionID].ResourceAddress
-						for _, graphResource := range graphResources {
-							if strings.Contains(strings.ToLower(graphResource.ID), strings.ToLower(matchedGraphResourceID)) {
-								resource.MappedResources = []*types.GraphResource{graphResource}
-								finalMappedResource.ResourceID = graphResource.ID
-								finalMappedResource.IssueType = types.IssueTypeNoResourceID
-								finalMappedResource.ActionType = types.ActionTypeReplace
-								resolved = true
-								break
+						targetIssue, graphResource, err := getReplaceTarget(resolvedIssues, resolvedIssue, graphResourcesByID)
+						if err == nil && renames[azure.NormalizeResourceID(graphResource.ID)] != nil {
+							err = fmt.Errorf("the Replace target %s already replaces %s", graphResource.ID, renames[azure.NormalizeResourceID(graphResource.ID)].ToResourceAddress)
+						}
+						if err != nil {
+							errorMessage := fmt.Sprintf("Invalid Replace resolution for Issue ID, check your CSV file and try again: %s Name: %s, Type: %s, Address: %s, %v", issue.IssueID, resource.ResourceName, resource.Type, resource.Address, err)
+							errors = append(errors, errorMessage)
+							importer.Logger.Warn(errorMessage)
+						} else {
+							resource.MappedResources = []*types.GraphResource{graphResource}
+							finalMappedResource.ResourceID = graphResource.ID
+							finalMappedResource.IssueType = types.IssueTypeNoResourceID
+							finalMappedResource.ActionType = types.ActionTypeReplace
+							finalMappedResource.Rename = &types.ResourceRename{
+								FromIssueID:       types.GetIssueID(graphResource.ID),
+								FromResourceID:    graphResource.ID,
+								ToIssueID:         issue.IssueID,
+								ToResourceAddress: resource.Address,
 							}
+							renames[azure.NormalizeResourceID(graphResource.ID)] = finalMappedResource.Rename
+							importer.Logger.Debugf("Replacing Address: %s with Resource ID: %s (from target %s)", resource.Address, graphResource.ID, targetIssue.ResourceAddress)
+							resolved = true
 						}
 					}
-				} else {
+				} else if importer.HasInputCsv {
 					errorMessage := fmt.Sprintf("No matching issue resolution found for Issue ID, check your CSV file and try again: %s Name: %s, Type: %s, Address: %s", issue.IssueID, resource.ResourceName, resource.Type, resource.Address)
 					errors = append(errors, errorMessage)
 					importer.Logger.Warn(errorMessage)
@@ -182,7 +487,7 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 
 			if !resolved {
 				importer.Logger.Warnf("No matching resource ID found for Name: %s, Type: %s, Address: %s", resource.ResourceName, resource.Type, resource.Address)
-				addIssue(issues, issue, types.IssueTypeNoResourceID)
+				errors = addIssue(issues, issue, types.IssueTypeNoResourceID, errors, importer.Logger)
 			} else {
 				finalMappedResources = append(finalMappedResources, finalMappedResource)
 			}
@@ -206,31 +511,54 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 				}
 			}
 
+			candidateScores := []types.CandidateScore{}
+			if len(mappedResourceIDsBasedOnLocation) != 1 {
+				candidateScores = scoreCandidates(resource, resource.MappedResources, placements)
+				if candidateScore, picked := pickCandidate(candidateScores, importer.CandidateScoring); picked {
+					importer.Logger.Infof("Picked Resource ID: %s with score %d (%s) for Address: %s", candidateScore.ResourceID, candidateScore.Score, strings.Join(candidateScore.Reasons, ", "), resource.Address)
+					finalMappedResource.PickedBy = &candidateScore
+					for _, mappedResource := range resource.MappedResources {
+						if mappedResource.ID == candidateScore.ResourceID {
+							mappedResourceIDsBasedOnLocation = []*types.GraphResource{mappedResource}
+						}
+					}
+				}
+			}
+
 			if len(mappedResourceIDsBasedOnLocation) == 1 {
 				resource.MappedResources = mappedResourceIDsBasedOnLocation
 			} else {
 				hadIssue = true
 				issue := IssueFromPlanResource(resource)
+				issue.CandidateScores = candidateScores
 				resolved := false
-				if importer.HasInputCsv {
-					if resolvedIssue, exists := (*resolvedIssues)[getIdentityHash(resource.Address)]; exists {
-						for _, mappedResource := range resource.MappedResources {
-							if len(resolvedIssue.MappedResourceIDs) == 0 {
-								errorMessage := fmt.Sprintf("No 'Use' match resolution specified for Issue ID, check your CSV file and try again: %s Name: %s, Type: %s, Address: %s", issue.IssueID, resource.ResourceName, resource.Type, resource.Address)
-								errors = append(errors, errorMessage)
-								importer.Logger.Warn(errorMessage)
-								break
-							}
-							if strings.Contains(strings.ToLower(mappedResource.ID), strings.ToLower(resolvedIssue.MappedResourceIDs[0])) {
-								resource.MappedResources = []*types.GraphResource{mappedResource}
-								finalMappedResource.ResourceID = mappedResource.ID
-								finalMappedResource.IssueType = types.IssueTypeMultipleResourceIDs
-								finalMappedResource.ActionType = types.ActionTypeUse
-								resolved = true
-								break
+				if resolvedIssues != nil {
+					if resolvedIssue, exists := getResolvedIssue(resolvedIssues, issue); exists {
+						finalMappedResource.ResolvedBy = resolvedIssue.ResolvedBy
+						if resolvedIssue.Resolution.ActionType == types.ActionTypeIgnore {
+							// Ignore only drops a candidate in a CSV file, a resolution for the address must pick one
+							errorMessage := fmt.Sprintf("Ignore is not valid for Issue ID, as it has more than one Resource ID, use Use with the Resource ID to import: %s Name: %s, Type: %s, Address: %s", issue.IssueID, resource.ResourceName, resource.Type, resource.Address)
+							errors = append(errors, errorMessage)
+							importer.Logger.Warn(errorMessage)
+						} else {
+							for _, mappedResource := range resource.MappedResources {
+								if len(resolvedIssue.MappedResourceIDs) == 0 {
+									errorMessage := fmt.Sprintf("No 'Use' match resolution specified for Issue ID, check your CSV file and try again: %s Name: %s, Type: %s, Address: %s", issue.IssueID, resource.ResourceName, resource.Type, resource.Address)
+									errors = append(errors, errorMessage)
+									importer.Logger.Warn(errorMessage)
+									break
+								}
+								if strings.Contains(strings.ToLower(mappedResource.ID), strings.ToLower(resolvedIssue.MappedResourceIDs[0])) {
+									resource.MappedResources = []*types.GraphResource{mappedResource}
+									finalMappedResource.ResourceID = mappedResource.ID
+									finalMappedResource.IssueType = types.IssueTypeMultipleResourceIDs
+									finalMappedResource.ActionType = types.ActionTypeUse
+									resolved = true
+									break
+								}
 							}
 						}
-					} else {
+					} else if importer.HasInputCsv {
 						errorMessage := fmt.Sprintf("No matching issue resolution found for Issue ID, check your CSV file and try again: %s Name: %s, Type: %s, Address: %s", issue.IssueID, resource.ResourceName, resource.Type, resource.Address)
 						errors = append(errors, errorMessage)
 						importer.Logger.Warn(errorMessage)
@@ -239,7 +567,7 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 
 				if !resolved {
 					importer.Logger.Warnf("More than 1 Resource ID has been matched for Name: %s, Type: %s, Address: %s", resource.ResourceName, resource.Type, resource.Address)
-					addIssue(issues, issue, types.IssueTypeMultipleResourceIDs)
+					errors = addIssue(issues, issue, types.IssueTypeMultipleResourceIDs, errors, importer.Logger)
 				} else {
 					finalMappedResources = append(finalMappedResources, finalMappedResource)
 				}
@@ -265,19 +593,29 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 
 			issue := IssueFromGraphResource(graphResource)
 			resolved := false
-			if importer.HasInputCsv {
-				if resolvedIssue, exists := (*resolvedIssues)[issue.IssueID]; exists {
+			if resolvedIssues != nil {
+				if resolvedIssue, exists := getResolvedIssue(resolvedIssues, issue); exists {
+					finalMappedResource.ResolvedBy = resolvedIssue.ResolvedBy
 					if resolvedIssue.Resolution.ActionType == types.ActionTypeIgnore {
 						importer.Logger.Debugf("Ignoring Issue ID: %s, Action: %s", resolvedIssue.IssueID, resolvedIssue.Resolution.ActionType)
 						finalMappedResource.ActionType = resolvedIssue.Resolution.ActionType
 						resolved = true
 					}
+					if resolvedIssue.Resolution.ActionType == types.ActionTypeForget {
+						importer.Logger.Debugf("Forgetting Issue ID: %s, Action: %s", resolvedIssue.IssueID, resolvedIssue.Resolution.ActionType)
+						finalMappedResource.ResourceAddress = resolvedIssue.Resolution.ActionID
+						finalMappedResource.ActionType = resolvedIssue.Resolution.ActionType
+						resolved = true
+					}
 					if resolvedIssue.Resolution.ActionType == types.ActionTypeReplace || resolvedIssue.Resolution.ActionType == types.ActionTypeDestroy {
 						importer.Logger.Debugf("Destroying via Replace or Destroy Issue ID: %s, Action: %s", resolvedIssue.IssueID, resolvedIssue.Resolution.ActionType)
 						finalMappedResource.ActionType = resolvedIssue.Resolution.ActionType
+						if resolvedIssue.Resolution.ActionType == types.ActionTypeReplace {
+							finalMappedResource.Rename = renames[azure.NormalizeResourceID(graphResource.ID)]
+						}
 						resolved = true
 					}
-				} else {
+				} else if importer.HasInputCsv {
 					errorMessage := fmt.Sprintf("No matching issue resolution found for Issue ID, check your CSV file and try again: %s Name: %s, Type: %s, Address: %s", issue.IssueID, graphResource.Name, graphResource.Type, graphResource.ID)
 					errors = append(errors, errorMessage)
 					importer.Logger.Warn(errorMessage)
@@ -286,7 +624,7 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 
 			if !resolved {
 				importer.Logger.Warnf("Resource ID %s is not used in the Terraform plan", graphResource.ID)
-				addIssue(issues, IssueFromGraphResource(graphResource), types.IssueTypeUnusedResourceID)
+				errors = addIssue(issues, issue, types.IssueTypeUnusedResourceID, errors, importer.Logger)
 			} else {
 				finalMappedResources = append(finalMappedResources, finalMappedResource)
 			}
@@ -295,14 +633,119 @@ func (importer *MappingClient) mapResourcesFromGraphToPlan(graphResources []*typ
 	return finalMappedResources, issues, errors
 }
 
-func addIssue(issues map[string]types.Issue, issue types.Issue, issueType types.IssueType) {
+// getReplaceTarget finds the unused resource paired with a Replace resolution. The target must be resolved as an
+// UnusedResourceID issue, and its resource ID must match a Resource Graph resource exactly once normalized.
+func getReplaceTarget(resolvedIssues *map[string]types.Issue, resolvedIssue types.Issue, graphResourcesByID map[string]*types.GraphResource) (types.Issue, *types.GraphResource, error) {
+	actionID := resolvedIssue.Resolution.ActionID
+	if actionID == "" {
+		return types.Issue{}, nil, errors.New("the Replace target is missing")
+	}
+	targetIssue, exists := (*resolvedIssues)[actionID]
+	if !exists {
+		return types.Issue{}, nil, fmt.Errorf("the Replace target %s is not in the resolved issues", actionID)
+	}
+	if targetIssue.IssueType != types.IssueTypeUnusedResourceID {
+		return targetIssue, nil, fmt.Errorf("the Replace target %s is a %s issue, it must be an UnusedResourceID issue", actionID, targetIssue.IssueType)
+	}
+	if targetIssue.Resolution.ActionType != types.ActionTypeReplace {
+		return targetIssue, nil, fmt.Errorf("the Replace target %s is resolved with %s, it must be Replace", actionID, targetIssue.Resolution.ActionType)
+	}
+	graphResource, exists := graphResourcesByID[azure.NormalizeResourceID(targetIssue.ResourceAddress)]
+	if !exists {
+		return targetIssue, nil, fmt.Errorf("the Replace target %s does not exist in Resource Graph", targetIssue.ResourceAddress)
+	}
+	return targetIssue, graphResource, nil
+}
+
+// addIssue adds an issue keyed by its ID. An ID that is already used by an issue for another resource is reported
+// as an error rather than overwriting that issue.
+func addIssue(issues map[string]types.Issue, issue types.Issue, issueType types.IssueType, errors []string, logger *logrus.Logger) []string {
+	if existingIssue, exists := issues[issue.IssueID]; exists && existingIssue.ResourceAddress != issue.ResourceAddress {
+		errorMessage := fmt.Sprintf("Issue ID %s collides for %s and %s, please raise an issue so the ID length can be increased", issue.IssueID, existingIssue.ResourceAddress, issue.ResourceAddress)
+		logger.Warn(errorMessage)
+		return append(errors, errorMessage)
+	}
 	issue.IssueType = issueType
 	issues[issue.IssueID] = issue
+	return errors
+}
+
+// getResolvedIssue finds the resolution for an issue, falling back to the short ID written by earlier versions.
+// Short IDs can collide, so a resolution found by short ID is only used when it is for the same resource.
+func getResolvedIssue(resolvedIssues *map[string]types.Issue, issue types.Issue) (types.Issue, bool) {
+	if resolvedIssues == nil {
+		return types.Issue{}, false
+	}
+	// Resolutions keyed by address or resource ID take precedence, as they are what reviewers wrote
+	if resolvedIssue, exists := (*resolvedIssues)[csv.GetNaturalKey(issue.ResourceAddress)]; exists {
+		return resolvedIssue, true
+	}
+	if resolvedIssue, exists := getPatternResolvedIssue(resolvedIssues, issue); exists {
+		return resolvedIssue, true
+	}
+	if resolvedIssue, exists := (*resolvedIssues)[issue.IssueID]; exists {
+		return resolvedIssue, true
+	}
+	// Files converted from resolutions keyed by resource ID hash the normalized ID, as Resource Graph may use another case
+	if strings.HasPrefix(issue.ResourceAddress, "/") {
+		if resolvedIssue, exists := (*resolvedIssues)[types.GetIssueID(azure.NormalizeResourceID(issue.ResourceAddress))]; exists && strings.EqualFold(azure.NormalizeResourceID(resolvedIssue.ResourceAddress), azure.NormalizeResourceID(issue.ResourceAddress)) {
+			return resolvedIssue, true
+		}
+	}
+	if len(issue.IssueID) <= types.LegacyIssueIDLength {
+		return types.Issue{}, false
+	}
+	resolvedIssue, exists := (*resolvedIssues)[issue.IssueID[:types.LegacyIssueIDLength]]
+	if !exists || !strings.EqualFold(resolvedIssue.ResourceAddress, issue.ResourceAddress) {
+		return types.Issue{}, false
+	}
+	return resolvedIssue, true
+}
+
+// getPatternResolvedIssue finds the resolution with an address pattern that matches the issue, the most specific
+// pattern wins when several match.
+func getPatternResolvedIssue(resolvedIssues *map[string]types.Issue, issue types.Issue) (types.Issue, bool) {
+	naturalKey := csv.GetNaturalKey(issue.ResourceAddress)
+	matchedKey := ""
+	for key := range *resolvedIssues {
+		if !strings.HasPrefix(key, csv.NaturalKeyPrefix) || !csv.IsAddressPattern(key) || !matchesAddressPattern(key, naturalKey) {
+			continue
+		}
+		if matchedKey == "" || getPatternSpecificity(key) > getPatternSpecificity(matchedKey) || (getPatternSpecificity(key) == getPatternSpecificity(matchedKey) && key < matchedKey) {
+			matchedKey = key
+		}
+	}
+	if matchedKey == "" {
+		return types.Issue{}, false
+	}
+	return (*resolvedIssues)[matchedKey], true
+}
+
+// matchesAddressPattern matches a value against a pattern where * matches any characters, including dots and
+// brackets, so module.hub["*"].azurerm_subnet.* matches every subnet in every instance of the hub module.
+func matchesAddressPattern(pattern string, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(value, part)
+		if index < 0 {
+			return false
+		}
+		value = value[index+len(part):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
+
+func getPatternSpecificity(pattern string) int {
+	return len(pattern) - strings.Count(pattern, "*")
 }
 
 func IssueFromGraphResource(graphResource *types.GraphResource) types.Issue {
 	issue := types.Issue{}
-	issue.IssueID = getIdentityHash(graphResource.ID)
+	issue.IssueID = types.GetIssueID(graphResource.ID)
 	issue.ResourceAddress = graphResource.ID
 	issue.ResourceName = graphResource.Name
 	issue.ResourceType = graphResource.Type
@@ -314,7 +757,7 @@ func IssueFromGraphResource(graphResource *types.GraphResource) types.Issue {
 
 func IssueFromPlanResource(planResource *types.PlanResource) types.Issue {
 	issue := types.Issue{}
-	issue.IssueID = getIdentityHash(planResource.Address)
+	issue.IssueID = types.GetIssueID(planResource.Address)
 	issue.ResourceAddress = planResource.Address
 	issue.ResourceName = planResource.ResourceName
 	issue.ResourceType = planResource.Type
@@ -328,8 +771,3 @@ func IssueFromPlanResource(planResource *types.PlanResource) types.Issue {
 
 	return issue
 }
-
-func getIdentityHash(id string) string {
-	sha256ID := sha256.Sum256([]byte(id))
-	return fmt.Sprintf("i-%x", sha256ID)[0:7]
-}
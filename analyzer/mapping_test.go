@@ -1,9 +1,11 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
+	"github.com/azure/terraform-state-importer/csv"
 	"github.com/azure/terraform-state-importer/types"
 	"github.com/sirupsen/logrus"
 
@@ -16,37 +18,56 @@ type mockResourceGraphClient struct {
 	Called    bool
 }
 
-func (m *mockResourceGraphClient) GetResources() ([]*types.GraphResource, error) {
+func (m *mockResourceGraphClient) GetResources(ctx context.Context) ([]*types.GraphResource, error) {
 	m.Called = true
 	return m.Resources, m.Err
 }
 
 type mockPlanClient struct {
-	Resources []*types.PlanResource
-	Called    bool
+	Resources      []*types.PlanResource
+	ImportChanges  []*types.ImportChange
+	ImportError    error
+	StateResources []*types.StateResource
+	Called         bool
+}
+
+func (m *mockPlanClient) PlanAndGetResources(ctx context.Context) ([]*types.PlanResource, error) {
+	m.Called = true
+	return m.Resources, nil
 }
 
-func (m *mockPlanClient) PlanAndGetResources() []*types.PlanResource {
+func (m *mockPlanClient) PlanAsText(ctx context.Context) error {
 	m.Called = true
-	return m.Resources
+	return nil
 }
 
-func (m *mockPlanClient) PlanAsText() {
+func (m *mockPlanClient) PlanAndGetImportChanges(ctx context.Context) ([]*types.ImportChange, error) {
 	m.Called = true
+	return m.ImportChanges, m.ImportError
+}
+
+func (m *mockPlanClient) GetStateResources() ([]*types.StateResource, error) {
+	return m.StateResources, nil
 }
 
 type mockJsonClient struct {
 	Called    bool
 	Resources map[string]any
+	Exported  map[string]any
 }
 
-func (m *mockJsonClient) Export(resources any, fileName string) {
+func (m *mockJsonClient) Export(resources any, fileName string) (string, error) {
 	m.Called = true
+	if m.Exported == nil {
+		m.Exported = map[string]any{}
+	}
+	m.Exported[fileName] = resources
+	return fileName, nil
 }
 
-func (m *mockJsonClient) Import(fileName string) map[string]any {
+func (m *mockJsonClient) Import(fileName string) (map[string]any, error) {
 	m.Called = true
-	return m.Resources
+	return m.Resources, nil
 }
 
 type mockIssueCsvClient struct {
@@ -54,9 +75,10 @@ type mockIssueCsvClient struct {
 	Called bool
 }
 
-func (m *mockIssueCsvClient) Export(issues map[string]types.Issue) {
+func (m *mockIssueCsvClient) Export(issues map[string]types.Issue) (string, error) {
 	m.Issues = &issues
 	m.Called = true
+	return "issues.csv", nil
 }
 
 func (m *mockIssueCsvClient) Import() (*map[string]types.Issue, error) {
@@ -65,20 +87,58 @@ func (m *mockIssueCsvClient) Import() (*map[string]types.Issue, error) {
 }
 
 type mockHclClient struct {
-	Called           bool
-	CleanFilesCalled bool
+	Called              bool
+	CleanFilesCalled    bool
+	FilesToClean        []string
+	FilesSetAside       []string
+	FilesRestored       bool
+	ImportBlocks        []types.ImportBlock
+	WrittenImportBlocks []types.ImportBlock
+	MovedBlocks         []types.MovedBlock
+	RemovedBlocks       []types.RemovedBlock
+}
+
+func (m *mockHclClient) WriteImportBlocks(importBlocks []types.ImportBlock, fileName string) (string, error) {
+	m.Called = true
+	m.WrittenImportBlocks = importBlocks
+	return fileName, nil
+}
+
+func (m *mockHclClient) WriteMovedBlocks(movedBlocks []types.MovedBlock, fileName string) (string, error) {
+	m.Called = true
+	m.MovedBlocks = movedBlocks
+	return fileName, nil
+}
+
+func (m *mockHclClient) WriteRemovedBlocks(removedBlocks []types.RemovedBlock, fileName string) (string, error) {
+	m.Called = true
+	m.RemovedBlocks = removedBlocks
+	return fileName, nil
 }
 
-func (m *mockHclClient) WriteImportBlocks(importBlocks []types.ImportBlock, fileName string) {
+func (m *mockHclClient) WriteDestroyBlocks(destroyBlocks []types.DestroyBlock, fileName string) (string, error) {
 	m.Called = true
+	return fileName, nil
 }
 
-func (m *mockHclClient) WriteDestroyBlocks(destroyBlocks []types.DestroyBlock, fileName string) {
+func (m *mockHclClient) WriteVariableValues(variableValues []types.VariableValue, filePath string) error {
 	m.Called = true
+	return nil
 }
 
-func (m *mockHclClient) CleanFiles(filesToRemove []string) {
+func (m *mockHclClient) CleanFiles(filesToRemove []string) error {
 	m.CleanFilesCalled = true
+	m.FilesToClean = filesToRemove
+	return nil
+}
+
+func (m *mockHclClient) SetFilesAside(fileNames []string) (func(), error) {
+	m.FilesSetAside = fileNames
+	return func() { m.FilesRestored = true }, nil
+}
+
+func (m *mockHclClient) ReadImportBlocks(fileName string) ([]types.ImportBlock, error) {
+	return m.ImportBlocks, nil
 }
 
 func TestMappingClient_Map_WithNoIssues(t *testing.T) {
@@ -97,7 +157,7 @@ func TestMappingClient_Map_WithNoIssues(t *testing.T) {
 		Logger:              logger,
 	}
 
-	mappingClient.Map()
+	mappingClient.Map(context.Background())
 
 	assert.True(t, mappingClient.ResourceGraphClient.(*mockResourceGraphClient).Called)
 	assert.True(t, mappingClient.PlanClient.(*mockPlanClient).Called)
@@ -122,7 +182,7 @@ func TestMappingClient_Map_WithIssues(t *testing.T) {
 		Logger:              logger,
 	}
 
-	mappingClient.Map()
+	mappingClient.Map(context.Background())
 
 	assert.True(t, mappingClient.ResourceGraphClient.(*mockResourceGraphClient).Called)
 	assert.True(t, mappingClient.PlanClient.(*mockPlanClient).Called)
@@ -133,8 +193,8 @@ func TestMappingClient_Map_WithIssues(t *testing.T) {
 
 	assert.Equal(t, len(*mappingClient.IssueCsvClient.(*mockIssueCsvClient).Issues), 2)
 
-	graphResourceIssueId := getIdentityHash(graphResources[0].ID)
-	planResourceIssueId := getIdentityHash(planResources[0].Address)
+	graphResourceIssueId := types.GetIssueID(graphResources[0].ID)
+	planResourceIssueId := types.GetIssueID(planResources[0].Address)
 
 	assert.Equal(t, (*mappingClient.IssueCsvClient.(*mockIssueCsvClient).Issues)[planResourceIssueId].IssueType, types.IssueTypeNoResourceID)
 	assert.Equal(t, (*mappingClient.IssueCsvClient.(*mockIssueCsvClient).Issues)[graphResourceIssueId].IssueType, types.IssueTypeUnusedResourceID)
@@ -142,8 +202,6 @@ func TestMappingClient_Map_WithIssues(t *testing.T) {
 
 func TestMappingClient_Map_WithErrorFromResourceGraphClient(t *testing.T) {
 	logger := logrus.New()
-	// Setting output to discard to avoid panic stack trace in test output
-	logger.SetOutput(nil)
 
 	graphClient := &mockResourceGraphClient{
 		Err: fmt.Errorf("failed to connect to resource graph"),
@@ -159,15 +217,12 @@ func TestMappingClient_Map_WithErrorFromResourceGraphClient(t *testing.T) {
 		Logger:              logger,
 	}
 
-	// This should panic with a log.Fatalf, we're using recover to catch it
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("Expected panic when ResourceGraphClient returns error, but got none")
-		}
-		assert.True(t, graphClient.Called)
-	}()
+	result, err := mappingClient.Map(context.Background())
 
-	mappingClient.Map()
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, "failed to connect to resource graph")
+	assert.True(t, graphClient.Called)
+	assert.False(t, mappingClient.PlanClient.(*mockPlanClient).Called)
 }
 
 func TestMappingClient_Map_WithIDContainsMatch(t *testing.T) {
@@ -186,7 +241,7 @@ func TestMappingClient_Map_WithIDContainsMatch(t *testing.T) {
 		Logger:              logger,
 	}
 
-	mappingClient.Map()
+	mappingClient.Map(context.Background())
 
 	assert.True(t, mappingClient.ResourceGraphClient.(*mockResourceGraphClient).Called)
 	assert.True(t, mappingClient.PlanClient.(*mockPlanClient).Called)
@@ -217,7 +272,7 @@ func TestMappingClient_Map_WithMultipleResourcesSameLocation(t *testing.T) {
 		Logger:              logger,
 	}
 
-	mappingClient.Map()
+	mappingClient.Map(context.Background())
 
 	assert.True(t, mappingClient.ResourceGraphClient.(*mockResourceGraphClient).Called)
 	assert.True(t, mappingClient.PlanClient.(*mockPlanClient).Called)
@@ -249,7 +304,7 @@ func TestMappingClient_Map_WithEmptyResources(t *testing.T) {
 		Logger:              logger,
 	}
 
-	mappingClient.Map()
+	mappingClient.Map(context.Background())
 
 	assert.True(t, mappingClient.ResourceGraphClient.(*mockResourceGraphClient).Called)
 	assert.True(t, mappingClient.PlanClient.(*mockPlanClient).Called)
@@ -276,7 +331,7 @@ func TestMappingClient_Map_LocationBasedFiltering(t *testing.T) {
 		Logger:              logger,
 	}
 
-	mappingClient.Map()
+	mappingClient.Map(context.Background())
 
 	assert.True(t, mappingClient.ResourceGraphClient.(*mockResourceGraphClient).Called)
 	assert.True(t, mappingClient.PlanClient.(*mockPlanClient).Called)
@@ -303,7 +358,7 @@ func TestMappingClient_Map_WithResourceIDContainedInLocation(t *testing.T) {
 		Logger:              logger,
 	}
 
-	mappingClient.Map()
+	mappingClient.Map(context.Background())
 
 	assert.True(t, mappingClient.ResourceGraphClient.(*mockResourceGraphClient).Called)
 	assert.True(t, mappingClient.PlanClient.(*mockPlanClient).Called)
@@ -437,7 +492,7 @@ func Test_mapResourcesFromGraphToPlan_ResolvedIssue_Ignore(t *testing.T) {
 			ResourceNameMatchType: types.NameMatchTypeExact,
 		},
 	}
-	issueID := getIdentityHash("addr1")
+	issueID := types.GetIssueID("addr1")
 	resolvedIssues := map[string]types.Issue{
 		issueID: {
 			IssueID: issueID,
@@ -460,7 +515,7 @@ func Test_mapResourcesFromGraphToPlan_ResolvedIssue_UnusedGraphResource_Destroy(
 		{ID: "1", Name: "res1", Type: "type1", Location: "eastus"},
 	}
 	planResources := []*types.PlanResource{}
-	issueID := getIdentityHash("1")
+	issueID := types.GetIssueID("1")
 	resolvedIssues := map[string]types.Issue{
 		issueID: {
 			IssueID: issueID,
@@ -489,7 +544,7 @@ func Test_mapResourcesFromGraphToPlan_ResolvedIssue_MultipleResourceIDs_Use(t *t
 			ResourceNameMatchType: types.NameMatchTypeExact,
 		},
 	}
-	issueID := getIdentityHash("addr1")
+	issueID := types.GetIssueID("addr1")
 	resolvedIssues := map[string]types.Issue{
 		issueID: {
 			IssueID:           issueID,
@@ -505,6 +560,119 @@ func Test_mapResourcesFromGraphToPlan_ResolvedIssue_MultipleResourceIDs_Use(t *t
 	assert.Empty(t, errs)
 }
 
+func Test_mapResourcesFromGraphToPlan_ResolvedIssue_MultipleResourceIDs_IgnoreIsInvalid(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "id1", Name: "res1", Type: "type1", Location: "eastus"},
+		{ID: "id2", Name: "res1", Type: "type1", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{
+		{
+			Address: "addr1", ResourceName: "res1", Type: "type1", Location: "eastus",
+			ResourceNameMatchType: types.NameMatchTypeExact,
+		},
+	}
+	naturalKey := csv.GetNaturalKey("addr1")
+	resolvedIssues := map[string]types.Issue{
+		naturalKey: {
+			IssueID:         naturalKey,
+			IssueType:       types.IssueTypeNoResourceID,
+			ResourceAddress: "addr1",
+			Resolution:      types.IssueResolution{ActionType: types.ActionTypeIgnore},
+		},
+	}
+	client := &MappingClient{Logger: logger, HasInputCsv: true}
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, planResources, &resolvedIssues)
+	assert.Empty(t, mapped)
+	assert.Len(t, issues, 1)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "Ignore is not valid for Issue ID")
+}
+
+func Test_mapResourcesFromGraphToPlan_ResolvedIssue_Replace_ExactResourceID(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub-2", Name: "vnet-hub-2", Type: "type1", Location: "eastus"},
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub", Name: "vnet-hub", Type: "type1", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{
+		{
+			Address: "addr1", ResourceName: "vnet-new", Type: "type1", Location: "eastus",
+			ResourceNameMatchType: types.NameMatchTypeExact,
+		},
+	}
+	issueID := types.GetIssueID("addr1")
+	targetIssueID := types.GetIssueID(graphResources[1].ID)
+	otherIssueID := types.GetIssueID(graphResources[0].ID)
+	resolvedIssues := map[string]types.Issue{
+		issueID: {
+			IssueID: issueID, IssueType: types.IssueTypeNoResourceID, ResourceAddress: "addr1",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: targetIssueID},
+		},
+		// The target is written with another case and a trailing slash, so it only matches once normalized
+		targetIssueID: {
+			IssueID: targetIssueID, IssueType: types.IssueTypeUnusedResourceID, ResourceAddress: "/subscriptions/sub1/resourcegroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub/",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace},
+		},
+		otherIssueID: {
+			IssueID: otherIssueID, IssueType: types.IssueTypeUnusedResourceID, ResourceAddress: graphResources[0].ID,
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore},
+		},
+	}
+	client := &MappingClient{Logger: logger, HasInputCsv: true}
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, planResources, &resolvedIssues)
+	assert.Empty(t, issues)
+	assert.Empty(t, errs)
+	assert.Len(t, mapped, 3)
+
+	expectedRename := &types.ResourceRename{
+		FromIssueID:       targetIssueID,
+		FromResourceID:    graphResources[1].ID,
+		ToIssueID:         issueID,
+		ToResourceAddress: "addr1",
+	}
+	for _, mappedResource := range mapped {
+		switch mappedResource.ResourceID {
+		case graphResources[1].ID:
+			assert.Equal(t, types.ActionTypeReplace, mappedResource.ActionType)
+			assert.Equal(t, expectedRename, mappedResource.Rename)
+		case graphResources[0].ID:
+			assert.Equal(t, types.ActionTypeIgnore, mappedResource.ActionType)
+			assert.Nil(t, mappedResource.Rename)
+		default:
+			t.Errorf("unexpected mapped resource %s", mappedResource.ResourceID)
+		}
+	}
+}
+
+func Test_mapResourcesFromGraphToPlan_ResolvedIssue_Replace_TargetMustBeUnused(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{}
+	planResources := []*types.PlanResource{
+		{Address: "addr1", ResourceName: "notfound1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: "addr2", ResourceName: "notfound2", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+	issueID := types.GetIssueID("addr1")
+	targetIssueID := types.GetIssueID("addr2")
+	resolvedIssues := map[string]types.Issue{
+		issueID: {
+			IssueID: issueID, IssueType: types.IssueTypeNoResourceID, ResourceAddress: "addr1",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: targetIssueID},
+		},
+		targetIssueID: {
+			IssueID: targetIssueID, IssueType: types.IssueTypeNoResourceID, ResourceAddress: "addr2",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore},
+		},
+	}
+	client := &MappingClient{Logger: logger, HasInputCsv: true}
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, planResources, &resolvedIssues)
+	assert.Len(t, mapped, 1)
+	assert.Len(t, issues, 1)
+	assert.Contains(t, issues, issueID)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "it must be an UnusedResourceID issue")
+}
+
 func Test_mapResourcesFromGraphToPlan_ResolvedIssue_MissingResolution_Error(t *testing.T) {
 	logger := logrus.New()
 	graphResources := []*types.GraphResource{}
@@ -521,3 +689,343 @@ func Test_mapResourcesFromGraphToPlan_ResolvedIssue_MissingResolution_Error(t *t
 	assert.Len(t, issues, 1)
 	assert.Len(t, errs, 1)
 }
+
+func Test_mapResourcesFromGraphToPlan_MultipleMatches_TypeFilter(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "/subscriptions/sub1/resourceGroups/hub", Name: "hub", Type: "microsoft.resources/subscriptions/resourcegroups", Location: "eastus"},
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/hub", Name: "hub", Type: "microsoft.network/virtualnetworks", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{
+		{
+			Address: "azurerm_virtual_network.hub", ResourceName: "hub", Type: "azurerm_virtual_network", Location: "eastus",
+			AzureResourceType: "Microsoft.Network/virtualNetworks", ResourceNameMatchType: types.NameMatchTypeExact,
+		},
+	}
+	client := &MappingClient{Logger: logger}
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, planResources, nil)
+	assert.Len(t, mapped, 1)
+	assert.Equal(t, "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/hub", mapped[0].ResourceID)
+	assert.Equal(t, types.ActionTypeUse, mapped[0].ActionType)
+	assert.Len(t, issues, 1)
+	assert.Empty(t, errs)
+}
+
+func Test_mapResourcesFromGraphToPlan_MultipleMatches_PickedByScore(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/hub", Name: "hub", Type: "microsoft.network/virtualnetworks", Location: "eastus", SubscriptionID: "sub1", ResourceGroup: "rg1"},
+		{ID: "/subscriptions/sub1/resourceGroups/rg2/providers/Microsoft.Network/virtualNetworks/hub", Name: "hub", Type: "microsoft.network/virtualnetworks", Location: "eastus", SubscriptionID: "sub1", ResourceGroup: "rg2"},
+	}
+	planResources := []*types.PlanResource{
+		{
+			Address: "azurerm_virtual_network.hub", ResourceName: "hub", Type: "azurerm_virtual_network", Location: "eastus",
+			AzureResourceType: "Microsoft.Network/virtualNetworks", ResourceNameMatchType: types.NameMatchTypeExact,
+			Properties: map[string]any{"resource_group_name": "rg2"},
+		},
+	}
+
+	client := &MappingClient{Logger: logger, CandidateScoring: types.CandidateScoring{Threshold: 70, Margin: 15}}
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, planResources, nil)
+	assert.Len(t, mapped, 1)
+	assert.Equal(t, graphResources[1].ID, mapped[0].ResourceID)
+	assert.Equal(t, types.ActionTypeUse, mapped[0].ActionType)
+	if assert.NotNil(t, mapped[0].PickedBy) {
+		assert.Equal(t, graphResources[1].ID, mapped[0].PickedBy.ResourceID)
+		assert.Equal(t, 85, mapped[0].PickedBy.Score)
+		assert.NotEmpty(t, mapped[0].PickedBy.Reasons)
+	}
+	assert.Empty(t, issues)
+	assert.Empty(t, errs)
+
+	planResources[0].MappedResources = nil
+	client.CandidateScoring = types.CandidateScoring{}
+	_, issues, _ = client.mapResourcesFromGraphToPlan(graphResources, planResources, nil)
+	issue := issues[types.GetIssueID("azurerm_virtual_network.hub")]
+	assert.Equal(t, types.IssueTypeMultipleResourceIDs, issue.IssueType)
+	assert.Equal(t, graphResources[1].ID, issue.CandidateScores[0].ResourceID)
+	assert.Equal(t, 85, issue.CandidateScores[0].Score)
+}
+
+func TestMappingClient_Map_ReturnsResult(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{{ID: "1", Name: "res1", Type: "type1", Location: "eastus"}}
+	planResources := []*types.PlanResource{{
+		Address: "addr1", ResourceName: "res1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact,
+	}}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.Map(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, result.MappedResources, 1)
+	assert.Empty(t, result.Issues)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []string{"issues.json", "resources.json", "final.json", "imports.tf", "destroy.tf", "imports_manifest.json"}, result.OutputFiles)
+}
+
+func TestMappingClient_Map_MovesResourcesWithChangedAddresses(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub", Name: "vnet-hub", Type: "type1", Location: "eastus"},
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-spoke", Name: "vnet-spoke", Type: "type1", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{
+		{Address: `azurerm_virtual_network.vnet["hub"]`, ResourceName: "vnet-hub", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: `azurerm_virtual_network.vnet["spoke"]`, ResourceName: "vnet-spoke", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+	// The hub was created with count, and the spoke is not in state yet
+	stateResources := []*types.StateResource{
+		{Address: "azurerm_virtual_network.vnet[0]", Type: "type1", ResourceID: "/subscriptions/sub1/resourcegroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub"},
+	}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources, StateResources: stateResources},
+		IssueCsvClient:      &mockIssueCsvClient{},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.Map(context.Background())
+
+	assert.NoError(t, err)
+	hclClient := mappingClient.HclClient.(*mockHclClient)
+	assert.Equal(t, []types.MovedBlock{{From: "azurerm_virtual_network.vnet[0]", To: `azurerm_virtual_network.vnet["hub"]`}}, hclClient.MovedBlocks)
+	assert.Equal(t, []types.ImportBlock{{To: `azurerm_virtual_network.vnet["spoke"]`, ID: graphResources[1].ID}}, hclClient.WrittenImportBlocks)
+	assert.Equal(t, "azurerm_virtual_network.vnet[0]", result.MappedResources[0].MovedFrom)
+	assert.Contains(t, result.OutputFiles, "moved.tf")
+}
+
+func TestMappingClient_Map_ForgetsResourcesInState(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub", Name: "vnet-hub", Type: "type1", Location: "eastus"},
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-spoke", Name: "vnet-spoke", Type: "type1", Location: "eastus"},
+	}
+	stateResources := []*types.StateResource{
+		{Address: `module.network.azurerm_virtual_network.vnet["hub"]`, Type: "type1", ResourceID: graphResources[0].ID},
+		{Address: `module.network.azurerm_virtual_network.vnet["spoke"]`, Type: "type1", ResourceID: graphResources[1].ID},
+	}
+	resolvedIssues := map[string]types.Issue{}
+	for _, graphResource := range graphResources {
+		issueID := types.GetIssueID(graphResource.ID)
+		resolvedIssues[issueID] = types.Issue{
+			IssueID: issueID, IssueType: types.IssueTypeUnusedResourceID, ResourceAddress: graphResource.ID,
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeForget},
+		}
+	}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		HasInputCsv:         true,
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: []*types.PlanResource{}, StateResources: stateResources},
+		IssueCsvClient:      &mockIssueCsvClient{Issues: &resolvedIssues},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.Map(context.Background())
+
+	assert.NoError(t, err)
+	// Removed blocks cannot have instance keys, so both instances are forgotten with one block
+	assert.Equal(t, []types.RemovedBlock{{From: "module.network.azurerm_virtual_network.vnet"}}, mappingClient.HclClient.(*mockHclClient).RemovedBlocks)
+	assert.Contains(t, result.OutputFiles, "removed.tf")
+	for _, mappedResource := range result.MappedResources {
+		assert.Equal(t, types.ActionTypeForget, mappedResource.ActionType)
+		assert.NotEmpty(t, mappedResource.ResourceAddress)
+	}
+}
+
+func Test_getRemovedBlocks_ActionIDAndConfiguration(t *testing.T) {
+	client := &MappingClient{Logger: logrus.New()}
+	mappedResources := []types.MappedResource{
+		{Type: types.MappedResourceTypeGraph, ResourceID: "/rg/1", ResourceAddress: "azurerm_resource_group.old[0]", ActionType: types.ActionTypeForget},
+		{Type: types.MappedResourceTypeGraph, ResourceID: "/rg/2", ActionType: types.ActionTypeForget},
+		{Type: types.MappedResourceTypeGraph, ResourceID: "/rg/3", ActionType: types.ActionTypeDestroy},
+		{Type: types.MappedResourceTypeGraph, ResourceID: "/rg/4", ActionType: types.ActionTypeForget},
+	}
+	planResources := []*types.PlanResource{{Address: "azurerm_resource_group.kept[0]"}}
+	stateResources := []*types.StateResource{{Address: "azurerm_resource_group.kept[1]", ResourceID: "/rg/2"}}
+
+	removedBlocks, errs := client.getRemovedBlocks(mappedResources, planResources, stateResources)
+
+	assert.Equal(t, []types.RemovedBlock{{From: "azurerm_resource_group.old"}}, removedBlocks)
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs[0], "azurerm_resource_group.kept is still in the configuration")
+	assert.Contains(t, errs[1], "/rg/4 is resolved with Forget but is not in state")
+	assert.Equal(t, "azurerm_resource_group.kept[1]", mappedResources[1].ResourceAddress)
+}
+
+func TestMappingClient_Map_WithMissingResolutionReturnsMappingErrors(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{{ID: "1", Name: "res1", Type: "type1", Location: "eastus"}}
+	planResources := []*types.PlanResource{{
+		Address: "addr1", ResourceName: "res2", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact,
+	}}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		HasInputCsv:         true,
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{Issues: &map[string]types.Issue{}},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.Map(context.Background())
+
+	assert.ErrorIs(t, err, ErrMappingErrors)
+	assert.Len(t, result.Errors, 2)
+	assert.Len(t, result.Issues, 2)
+	assert.Contains(t, result.OutputFiles, "issues.csv")
+}
+
+func TestMappingClient_Map_WithCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mappingClient := &MappingClient{
+		ResourceGraphClient: &mockResourceGraphClient{},
+		PlanClient:          &mockPlanClient{},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logrus.New(),
+	}
+
+	_, err := mappingClient.Map(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, mappingClient.PlanClient.(*mockPlanClient).Called)
+}
+
+func Test_GetIssueID(t *testing.T) {
+	issueID := types.GetIssueID("azurerm_resource_group.rg")
+
+	assert.Len(t, issueID, 18)
+	assert.Regexp(t, `^i-[0-9a-f]{16}$`, issueID)
+	assert.Equal(t, issueID, types.GetIssueID("azurerm_resource_group.rg"))
+}
+
+func Test_mapResourcesFromGraphToPlan_ResolvedIssue_LegacyIssueID(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{{ID: "1", Name: "res1", Type: "type1", Location: "eastus"}}
+	planResources := []*types.PlanResource{
+		{Address: "addr1", ResourceName: "notfound", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+	planIssueID := types.GetIssueID("addr1")[:7]
+	graphIssueID := types.GetIssueID("1")[:7]
+	resolvedIssues := map[string]types.Issue{
+		planIssueID:  {IssueID: planIssueID, ResourceAddress: "addr1", Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}},
+		graphIssueID: {IssueID: graphIssueID, ResourceAddress: "1", Resolution: types.IssueResolution{ActionType: types.ActionTypeDestroy}},
+	}
+	client := &MappingClient{Logger: logger, HasInputCsv: true}
+
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, planResources, &resolvedIssues)
+
+	assert.Len(t, mapped, 2)
+	assert.Empty(t, issues)
+	assert.Empty(t, errs)
+}
+
+func Test_getResolvedIssue_LegacyIssueIDForAnotherResource(t *testing.T) {
+	issue := IssueFromPlanResource(&types.PlanResource{Address: "addr1"})
+	legacyIssueID := issue.IssueID[:7]
+	resolvedIssues := map[string]types.Issue{
+		legacyIssueID: {IssueID: legacyIssueID, ResourceAddress: "addr2", Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}},
+	}
+
+	_, exists := getResolvedIssue(&resolvedIssues, issue)
+
+	assert.False(t, exists)
+}
+
+func Test_getResolvedIssue_NaturalKeyTakesPrecedence(t *testing.T) {
+	issue := IssueFromPlanResource(&types.PlanResource{Address: `module.hub["a"].azurerm_subnet.this`})
+	naturalKey := csv.GetNaturalKey(issue.ResourceAddress)
+	resolvedIssues := map[string]types.Issue{
+		issue.IssueID: {IssueID: issue.IssueID, ResourceAddress: issue.ResourceAddress, Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace}},
+		naturalKey:    {IssueID: naturalKey, ResourceAddress: issue.ResourceAddress, Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}},
+	}
+
+	resolvedIssue, exists := getResolvedIssue(&resolvedIssues, issue)
+
+	assert.True(t, exists)
+	assert.Equal(t, types.ActionTypeIgnore, resolvedIssue.Resolution.ActionType)
+}
+
+func Test_getResolvedIssue_ResourceIDIsNormalized(t *testing.T) {
+	issue := IssueFromGraphResource(&types.GraphResource{ID: "/subscriptions/1/resourceGroups/RG-Hub"})
+	naturalKey := csv.GetNaturalKey("/subscriptions/1/resourcegroups/rg-hub/")
+	resolvedIssues := map[string]types.Issue{
+		naturalKey: {IssueID: naturalKey, Resolution: types.IssueResolution{ActionType: types.ActionTypeDestroy}},
+	}
+
+	resolvedIssue, exists := getResolvedIssue(&resolvedIssues, issue)
+
+	assert.True(t, exists)
+	assert.Equal(t, types.ActionTypeDestroy, resolvedIssue.Resolution.ActionType)
+}
+
+func Test_getResolvedIssue_ConvertedResourceIDInAnotherCase(t *testing.T) {
+	issue := IssueFromGraphResource(&types.GraphResource{ID: "/subscriptions/1/resourceGroups/RG-Hub"})
+	convertedIssueID := types.GetIssueID("/subscriptions/1/resourcegroups/rg-hub")
+	resolvedIssues := map[string]types.Issue{
+		convertedIssueID: {IssueID: convertedIssueID, ResourceAddress: "/Subscriptions/1/resourcegroups/RG-HUB", Resolution: types.IssueResolution{ActionType: types.ActionTypeDestroy}},
+	}
+
+	resolvedIssue, exists := getResolvedIssue(&resolvedIssues, issue)
+
+	assert.True(t, exists)
+	assert.Equal(t, types.ActionTypeDestroy, resolvedIssue.Resolution.ActionType)
+}
+
+func Test_getResolvedIssue_AddressPattern(t *testing.T) {
+	resolvedIssues := map[string]types.Issue{
+		csv.GetNaturalKey(`module.hub["*"].azurerm_subnet.*`):        {Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}, ResolvedBy: "subnets"},
+		csv.GetNaturalKey(`module.hub["*"].azurerm_subnet.firewall`): {Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}, ResolvedBy: "firewall"},
+	}
+
+	tests := []struct {
+		address    string
+		exists     bool
+		resolvedBy string
+	}{
+		{`module.hub["a"].azurerm_subnet.this["x"]`, true, "subnets"},
+		{`module.hub["b"].azurerm_subnet.firewall`, true, "firewall"},
+		{`module.hub["a"].azurerm_virtual_network.this`, false, ""},
+		{`module.spoke["a"].azurerm_subnet.this`, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			resolvedIssue, exists := getResolvedIssue(&resolvedIssues, IssueFromPlanResource(&types.PlanResource{Address: tt.address}))
+
+			assert.Equal(t, tt.exists, exists)
+			assert.Equal(t, tt.resolvedBy, resolvedIssue.ResolvedBy)
+		})
+	}
+}
+
+func Test_addIssue_ReportsCollisions(t *testing.T) {
+	issues := map[string]types.Issue{}
+	errs := []string{}
+
+	errs = addIssue(issues, types.Issue{IssueID: "i-1", ResourceAddress: "addr1"}, types.IssueTypeNoResourceID, errs, logrus.New())
+	errs = addIssue(issues, types.Issue{IssueID: "i-1", ResourceAddress: "addr2"}, types.IssueTypeNoResourceID, errs, logrus.New())
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "collides")
+	assert.Equal(t, "addr1", issues["i-1"].ResourceAddress)
+}
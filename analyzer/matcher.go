@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// Matcher decides whether a Resource Graph resource is a candidate for a Terraform plan resource.
+type Matcher interface {
+	Matches(planResource *types.PlanResource, graphResource *types.GraphResource) bool
+}
+
+// MatcherFunc adapts a plain function to the Matcher interface.
+type MatcherFunc func(planResource *types.PlanResource, graphResource *types.GraphResource) bool
+
+func (matcherFunc MatcherFunc) Matches(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	return matcherFunc(planResource, graphResource)
+}
+
+var matchers = map[types.NameMatchType]Matcher{
+	types.NameMatchTypeExact:                MatcherFunc(matchExact),
+	types.NameMatchTypeIDContains:           MatcherFunc(matchIDContains),
+	types.NameMatchTypeIDEndsWith:           MatcherFunc(matchIDEndsWith),
+	types.NameMatchTypeIDEquals:             MatcherFunc(matchIDEquals),
+	types.NameMatchTypeRegex:                MatcherFunc(matchRegex),
+	types.NameMatchTypeNameAndType:          MatcherFunc(matchNameAndType),
+	types.NameMatchTypeNameAndResourceGroup: MatcherFunc(matchNameAndResourceGroup),
+	types.NameMatchTypeTagEquals:            MatcherFunc(matchTagEquals),
+}
+
+// RegisterMatcher adds or replaces the matcher used for a name match type.
+func RegisterMatcher(nameMatchType types.NameMatchType, matcher Matcher) {
+	matchers[nameMatchType] = matcher
+}
+
+// GetMatcher returns the matcher registered for a name match type.
+func GetMatcher(nameMatchType types.NameMatchType) (Matcher, bool) {
+	matcher, ok := matchers[nameMatchType]
+	return matcher, ok
+}
+
+func matchExact(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	return strings.ToLower(graphResource.Name) == strings.ToLower(planResource.ResourceName)
+}
+
+func matchIDContains(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	return strings.Contains(strings.ToLower(graphResource.ID), strings.ToLower(planResource.ResourceName))
+}
+
+func matchIDEndsWith(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	return strings.HasSuffix(strings.ToLower(graphResource.ID), strings.ToLower(planResource.ResourceName))
+}
+
+func matchIDEquals(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	return azure.NormalizeResourceID(graphResource.ID) == azure.NormalizeResourceID(planResource.ResourceName)
+}
+
+func matchNameAndType(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	return planResource.AzureResourceType != "" && matchExact(planResource, graphResource) && strings.EqualFold(graphResource.Type, planResource.AzureResourceType)
+}
+
+func matchNameAndResourceGroup(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	resourceGroupName := getPlanResourceGroupName(planResource)
+	return resourceGroupName != "" && matchExact(planResource, graphResource) && strings.EqualFold(graphResource.ResourceGroup, resourceGroupName)
+}
+
+func matchTagEquals(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	key, value, found := strings.Cut(planResource.ResourceName, "=")
+	if !found {
+		return false
+	}
+	for tagKey, tagValue := range graphResource.Tags {
+		if strings.EqualFold(tagKey, key) {
+			return tagValue == value
+		}
+	}
+	return false
+}
+
+// matchRegex treats the resource name as a case insensitive regular expression for the resource ID. The plan client
+// compiles and validates the expression when the names are formatted, it is only compiled here for plan resources that
+// were built elsewhere.
+func matchRegex(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	if planResource.ResourceNamePattern == nil {
+		pattern, err := regexp.Compile("(?i)" + planResource.ResourceName)
+		if err != nil {
+			return false
+		}
+		planResource.ResourceNamePattern = pattern
+	}
+	return planResource.ResourceNamePattern.MatchString(graphResource.ID)
+}
+
+// resourceTypeMatches checks the Azure resource type of a candidate when the plan resource type is known.
+func resourceTypeMatches(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+	return planResource.AzureResourceType == "" || strings.EqualFold(graphResource.Type, planResource.AzureResourceType)
+}
+
+func getPlanResourceGroupName(planResource *types.PlanResource) string {
+	if resourceGroupName, ok := planResource.Properties["resource_group_name"].(string); ok && resourceGroupName != "" {
+		return resourceGroupName
+	}
+	if parentID, ok := planResource.Properties["parent_id"].(string); ok {
+		if resourceGroupName := azure.ParseResourceGroupName(parentID); resourceGroupName != "" {
+			return resourceGroupName
+		}
+		// An azapi resource group has the subscription as its parent, so the name is the resource group
+		if strings.EqualFold(planResource.SubType, "Microsoft.Resources/resourceGroups") {
+			return planResource.ResourceName
+		}
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package analyzer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchers(t *testing.T) {
+	vnet := &types.GraphResource{
+		ID:            "/subscriptions/123/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub",
+		Name:          "hub",
+		Type:          "microsoft.network/virtualnetworks",
+		ResourceGroup: "rg-hub",
+		Tags:          map[string]string{"hidden-title": "Hub Network"},
+	}
+
+	tests := []struct {
+		name          string
+		nameMatchType types.NameMatchType
+		planResource  *types.PlanResource
+		expected      bool
+	}{
+		{"Exact match", types.NameMatchTypeExact, &types.PlanResource{ResourceName: "HUB"}, true},
+		{"Exact no match", types.NameMatchTypeExact, &types.PlanResource{ResourceName: "hub-2"}, false},
+		{"IDContains match", types.NameMatchTypeIDContains, &types.PlanResource{ResourceName: "resourcegroups/rg-hub"}, true},
+		{"IDEndsWith match", types.NameMatchTypeIDEndsWith, &types.PlanResource{ResourceName: "virtualnetworks/hub"}, true},
+		{"IDEndsWith no match", types.NameMatchTypeIDEndsWith, &types.PlanResource{ResourceName: "virtualnetworks/hu"}, false},
+		{"IDEquals match after normalization", types.NameMatchTypeIDEquals, &types.PlanResource{ResourceName: "/subscriptions/123/resourcegroups/RG-HUB/providers/Microsoft.Network/virtualNetworks/hub/"}, true},
+		{"IDEquals does not match prefix", types.NameMatchTypeIDEquals, &types.PlanResource{ResourceName: "/subscriptions/123/resourceGroups/rg-hub"}, false},
+		{"Regex match", types.NameMatchTypeRegex, &types.PlanResource{ResourceName: `/resourcegroups/rg-[a-z]+/providers/microsoft\.network/virtualnetworks/hub$`}, true},
+		{"Regex no match", types.NameMatchTypeRegex, &types.PlanResource{ResourceName: `/virtualnetworks/spoke$`}, false},
+		{"Regex invalid pattern", types.NameMatchTypeRegex, &types.PlanResource{ResourceName: `(`}, false},
+		{"Regex compiled pattern", types.NameMatchTypeRegex, &types.PlanResource{ResourceName: `(`, ResourceNamePattern: regexp.MustCompile(`(?i)/virtualnetworks/hub$`)}, true},
+		{"NameAndType match", types.NameMatchTypeNameAndType, &types.PlanResource{ResourceName: "hub", AzureResourceType: "Microsoft.Network/virtualNetworks"}, true},
+		{"NameAndType wrong type", types.NameMatchTypeNameAndType, &types.PlanResource{ResourceName: "hub", AzureResourceType: "Microsoft.Network/publicIPAddresses"}, false},
+		{"NameAndType unknown type", types.NameMatchTypeNameAndType, &types.PlanResource{ResourceName: "hub"}, false},
+		{"NameAndResourceGroup match", types.NameMatchTypeNameAndResourceGroup, &types.PlanResource{ResourceName: "hub", Properties: map[string]any{"resource_group_name": "RG-HUB"}}, true},
+		{"NameAndResourceGroup match from parent_id", types.NameMatchTypeNameAndResourceGroup, &types.PlanResource{ResourceName: "hub", Properties: map[string]any{"parent_id": "/subscriptions/123/resourceGroups/rg-hub"}}, true},
+		{"NameAndResourceGroup wrong resource group", types.NameMatchTypeNameAndResourceGroup, &types.PlanResource{ResourceName: "hub", Properties: map[string]any{"resource_group_name": "rg-spoke"}}, false},
+		{"TagEquals match", types.NameMatchTypeTagEquals, &types.PlanResource{ResourceName: "Hidden-Title=Hub Network"}, true},
+		{"TagEquals wrong value", types.NameMatchTypeTagEquals, &types.PlanResource{ResourceName: "hidden-title=Spoke Network"}, false},
+		{"TagEquals malformed", types.NameMatchTypeTagEquals, &types.PlanResource{ResourceName: "hidden-title"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matcher, ok := GetMatcher(test.nameMatchType)
+			assert.True(t, ok)
+			assert.Equal(t, test.expected, matcher.Matches(test.planResource, vnet))
+		})
+	}
+}
+
+func TestRegisterMatcher(t *testing.T) {
+	customMatchType := types.NameMatchType("Custom")
+	RegisterMatcher(customMatchType, MatcherFunc(func(planResource *types.PlanResource, graphResource *types.GraphResource) bool {
+		return graphResource.Location == planResource.Location
+	}))
+	defer delete(matchers, customMatchType)
+
+	graphResources := []*types.GraphResource{{ID: "1", Name: "res1", Location: "uksouth"}}
+	planResources := []*types.PlanResource{{Address: "addr1", Location: "uksouth", ResourceNameMatchType: customMatchType}}
+
+	client := &MappingClient{Logger: logrus.New()}
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, planResources, nil)
+	assert.Len(t, mapped, 1)
+	assert.Equal(t, "1", mapped[0].ResourceID)
+	assert.Empty(t, issues)
+	assert.Empty(t, errs)
+}
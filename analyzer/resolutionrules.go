@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// applyResolutionRules resolves issues with the first matching rule and returns them keyed by issue ID.
+// Issues that already have a resolution from the CSV file are left alone.
+func (mappingClient *MappingClient) applyResolutionRules(issues map[string]types.Issue, resolvedIssues *map[string]types.Issue) map[string]types.Issue {
+	ruleResolvedIssues := map[string]types.Issue{}
+
+	for issueID, issue := range issues {
+		if _, exists := getResolvedIssue(resolvedIssues, issue); exists {
+			continue
+		}
+
+		for _, resolutionRule := range mappingClient.ResolutionRules {
+			if !mappingClient.resolutionRuleMatches(resolutionRule, issue) {
+				continue
+			}
+
+			resolvedIssue, ok := mappingClient.resolveIssueWithRule(resolutionRule, issue)
+			if !ok {
+				continue
+			}
+
+			mappingClient.Logger.Infof("Resolved Issue ID: %s, Address: %s with Action: %s by rule %s", issueID, issue.ResourceAddress, resolutionRule.ActionType, resolutionRule.Name)
+			ruleResolvedIssues[issueID] = resolvedIssue
+			break
+		}
+	}
+
+	return ruleResolvedIssues
+}
+
+func (mappingClient *MappingClient) resolutionRuleMatches(resolutionRule types.ResolutionRule, issue types.Issue) bool {
+	if resolutionRule.IssueType != "" && resolutionRule.IssueType != issue.IssueType {
+		return false
+	}
+	if resolutionRule.Type != "" && !strings.EqualFold(resolutionRule.Type, issue.ResourceType) && !strings.EqualFold(resolutionRule.Type, issue.ResourceSubType) {
+		return false
+	}
+	if resolutionRule.NamePattern != "" && !mappingClient.patternMatches(resolutionRule.NamePattern, issue.ResourceName) {
+		return false
+	}
+	if resolutionRule.AddressPattern != "" && !mappingClient.patternMatches(resolutionRule.AddressPattern, issue.ResourceAddress) {
+		return false
+	}
+	if resolutionRule.IDPattern != "" {
+		matched := false
+		for _, mappedResourceID := range issue.MappedResourceIDs {
+			if mappingClient.patternMatches(resolutionRule.IDPattern, mappedResourceID) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (mappingClient *MappingClient) resolveIssueWithRule(resolutionRule types.ResolutionRule, issue types.Issue) (types.Issue, bool) {
+	// A MultipleResourceIDs issue is resolved by picking a candidate, there is nothing to ignore
+	if resolutionRule.ActionType == types.ActionTypeIgnore && issue.IssueType == types.IssueTypeMultipleResourceIDs {
+		return issue, false
+	}
+	if resolutionRule.ActionType == types.ActionTypeUse {
+		candidateIDs := []string{}
+		for _, mappedResourceID := range issue.MappedResourceIDs {
+			if mappingClient.patternMatches(resolutionRule.CandidateIDPattern, mappedResourceID) {
+				candidateIDs = append(candidateIDs, mappedResourceID)
+			}
+		}
+		if len(candidateIDs) != 1 {
+			mappingClient.Logger.Warnf("Rule %s selected %d candidates for Issue ID: %s, Address: %s, expected exactly 1", resolutionRule.Name, len(candidateIDs), issue.IssueID, issue.ResourceAddress)
+			return issue, false
+		}
+		issue.MappedResourceIDs = candidateIDs
+	}
+
+	issue.Resolution = types.IssueResolution{
+		ActionType: resolutionRule.ActionType,
+		ActionID:   "",
+	}
+	issue.ResolvedBy = fmt.Sprintf("rule:%s", resolutionRule.Name)
+	return issue, true
+}
+
+func (mappingClient *MappingClient) patternMatches(pattern string, value string) bool {
+	matched, err := regexp.MatchString(pattern, value)
+	if err != nil {
+		mappingClient.Logger.Debugf("Error matching pattern %s: %v", pattern, err)
+		return false
+	}
+	return matched
+}
+
+func mergeResolvedIssues(resolvedIssues *map[string]types.Issue, ruleResolvedIssues map[string]types.Issue) *map[string]types.Issue {
+	mergedIssues := map[string]types.Issue{}
+	if resolvedIssues != nil {
+		for issueID, issue := range *resolvedIssues {
+			mergedIssues[issueID] = issue
+		}
+	}
+	for issueID, issue := range ruleResolvedIssues {
+		mergedIssues[issueID] = issue
+	}
+	return &mergedIssues
+}
@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_applyResolutionRules(t *testing.T) {
+	issues := map[string]types.Issue{
+		"i-1": {IssueID: "i-1", IssueType: types.IssueTypeUnusedResourceID, ResourceName: "ds1", ResourceType: "microsoft.insights/diagnosticsettings", MappedResourceIDs: []string{"/subscriptions/sub1/providers/microsoft.insights/diagnosticSettings/ds1"}},
+		"i-2": {IssueID: "i-2", IssueType: types.IssueTypeUnusedResourceID, ResourceName: "NetworkWatcher_uksouth", ResourceType: "microsoft.network/networkwatchers", MappedResourceIDs: []string{"/subscriptions/sub1/resourceGroups/NetworkWatcherRG/providers/Microsoft.Network/networkWatchers/NetworkWatcher_uksouth"}},
+		"i-3": {IssueID: "i-3", IssueType: types.IssueTypeMultipleResourceIDs, ResourceAddress: "azurerm_virtual_network.hub", ResourceType: "azurerm_virtual_network", MappedResourceIDs: []string{"/subscriptions/sub1/vnet", "/subscriptions/sub2/vnet"}},
+		"i-4": {IssueID: "i-4", IssueType: types.IssueTypeNoResourceID, ResourceAddress: "azurerm_subnet.this", ResourceType: "azurerm_subnet"},
+		"i-5": {IssueID: "i-5", IssueType: types.IssueTypeUnusedResourceID, ResourceName: "ds2", ResourceType: "microsoft.insights/diagnosticsettings"},
+	}
+	resolvedIssues := &map[string]types.Issue{"i-5": {IssueID: "i-5"}}
+
+	client := &MappingClient{Logger: logrus.New(), ResolutionRules: []types.ResolutionRule{
+		{Name: "diagnostics", Type: "microsoft.insights/diagnosticsettings", IssueType: types.IssueTypeUnusedResourceID, ActionType: types.ActionTypeIgnore},
+		{Name: "network-watchers", NamePattern: "^NetworkWatcher_", IssueType: types.IssueTypeUnusedResourceID, ActionType: types.ActionTypeDestroy},
+		{Name: "prefer-sub2", IssueType: types.IssueTypeMultipleResourceIDs, AddressPattern: `\.hub$`, ActionType: types.ActionTypeUse, CandidateIDPattern: "/subscriptions/sub2/"},
+		{Name: "ignore-everything", ActionType: types.ActionTypeIgnore},
+	}}
+
+	ruleResolvedIssues := client.applyResolutionRules(issues, resolvedIssues)
+
+	assert.Len(t, ruleResolvedIssues, 4)
+	assert.Equal(t, types.ActionTypeIgnore, ruleResolvedIssues["i-1"].Resolution.ActionType)
+	assert.Equal(t, "rule:diagnostics", ruleResolvedIssues["i-1"].ResolvedBy)
+	assert.Equal(t, types.ActionTypeDestroy, ruleResolvedIssues["i-2"].Resolution.ActionType)
+	assert.Equal(t, types.ActionTypeUse, ruleResolvedIssues["i-3"].Resolution.ActionType)
+	assert.Equal(t, []string{"/subscriptions/sub2/vnet"}, ruleResolvedIssues["i-3"].MappedResourceIDs)
+	assert.Equal(t, "rule:ignore-everything", ruleResolvedIssues["i-4"].ResolvedBy)
+	assert.NotContains(t, ruleResolvedIssues, "i-5")
+}
+
+func Test_applyResolutionRules_CandidateSelectorMustPickOne(t *testing.T) {
+	issues := map[string]types.Issue{
+		"i-1": {IssueID: "i-1", IssueType: types.IssueTypeMultipleResourceIDs, MappedResourceIDs: []string{"/subscriptions/sub1/a", "/subscriptions/sub1/b"}},
+	}
+	client := &MappingClient{Logger: logrus.New(), ResolutionRules: []types.ResolutionRule{
+		{Name: "prefer-sub1", IssueType: types.IssueTypeMultipleResourceIDs, ActionType: types.ActionTypeUse, CandidateIDPattern: "/subscriptions/sub1/"},
+	}}
+
+	assert.Empty(t, client.applyResolutionRules(issues, nil))
+}
+
+func TestMappingClient_Map_WithResolutionRules(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "1", Name: "res1", Type: "type1", Location: "eastus"},
+		{ID: "2", Name: "leftover", Type: "microsoft.insights/diagnosticsettings", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{{
+		Address: "addr1", ResourceName: "res1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact,
+	}}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		ResolutionRules:     []types.ResolutionRule{{Name: "diagnostics", Type: "microsoft.insights/diagnosticsettings", ActionType: types.ActionTypeIgnore}},
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	_, err := mappingClient.Map(context.Background())
+	assert.NoError(t, err)
+
+	// The rule resolved issue is written for review, even though no issues are left to resolve
+	assert.True(t, mappingClient.IssueCsvClient.(*mockIssueCsvClient).Called)
+	assert.True(t, mappingClient.HclClient.(*mockHclClient).Called)
+	assert.Len(t, planResources[0].MappedResources, 1)
+}
+
+func TestMappingClient_Map_WithDestroyResolutionRule(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "1", Name: "res1", Type: "type1", Location: "eastus"},
+		{ID: "2", Name: "NetworkWatcher_eastus", Type: "microsoft.network/networkwatchers", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{{
+		Address: "addr1", ResourceName: "res1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact,
+	}}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		ResolutionRules:     []types.ResolutionRule{{Name: "network-watchers", NamePattern: "^NetworkWatcher_", ActionType: types.ActionTypeDestroy}},
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.Map(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, result.Issues)
+	assert.Equal(t, []string{"issues.json", "resources.json", "issues.csv", "final.json", "imports.tf", "destroy.tf", "imports_manifest.json"}, result.OutputFiles)
+	exportedIssues := *mappingClient.IssueCsvClient.(*mockIssueCsvClient).Issues
+	assert.Len(t, exportedIssues, 1)
+	exportedIssue := exportedIssues[types.GetIssueID("2")]
+	assert.Equal(t, types.ActionTypeDestroy, exportedIssue.Resolution.ActionType)
+	assert.Equal(t, "rule:network-watchers", exportedIssue.ResolvedBy)
+}
+
+func Test_mapResourcesFromGraphToPlan_MarksRuleResolved(t *testing.T) {
+	graphResources := []*types.GraphResource{{ID: "2", Name: "leftover", Type: "microsoft.insights/diagnosticsettings"}}
+	resolvedIssues := &map[string]types.Issue{
+		types.GetIssueID("2"): {IssueID: types.GetIssueID("2"), Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore}, ResolvedBy: "rule:diagnostics"},
+	}
+
+	client := &MappingClient{Logger: logrus.New()}
+	mapped, issues, errs := client.mapResourcesFromGraphToPlan(graphResources, []*types.PlanResource{}, resolvedIssues)
+
+	assert.Len(t, mapped, 1)
+	assert.Equal(t, types.ActionTypeIgnore, mapped[0].ActionType)
+	assert.Equal(t, "rule:diagnostics", mapped[0].ResolvedBy)
+	assert.Empty(t, issues)
+	assert.Empty(t, errs)
+}
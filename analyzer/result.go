@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// ErrMappingErrors is returned alongside the result when issue resolutions could not be applied, such as a missing CSV row.
+var ErrMappingErrors = errors.New("errors found during mapping")
+
+// Result is the outcome of a mapping run.
+type Result struct {
+	MappedResources []types.MappedResource
+	Issues          map[string]types.Issue
+	Errors          []string
+	OutputFiles     []string
+	// CleanedFiles are the names of the generated files removed from the module before planning, including those
+	// written by the previous run
+	CleanedFiles []string
+}
+
+// CompareResult is the outcome of a compare run.
+type CompareResult struct {
+	AttributeDifferences []types.AttributeDifference
+	VariableValues       []types.VariableValue
+	OutputFiles          []string
+}
+
+// ValidationResult is the outcome of validating the resolved issues against the current run.
+type ValidationResult struct {
+	Problems []types.ResolutionProblem
+	Counts   map[types.ResolutionProblemType]int
+	Issues   int
+}
+
+func mappingErrorsToError(mappingErrors []string) error {
+	if len(mappingErrors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d errors: %s", ErrMappingErrors, len(mappingErrors), strings.Join(mappingErrors, "; "))
+}
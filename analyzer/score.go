@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+const (
+	scoreWeightName                 = 30
+	scoreWeightType                 = 20
+	scoreWeightLocation             = 20
+	scoreWeightResourceGroup        = 15
+	scoreWeightSiblingResourceGroup = 10
+	scoreWeightSubscription         = 10
+	scoreWeightTags                 = 5
+)
+
+// siblingPlacements holds the resource groups and subscriptions of the resources in each module that matched a single candidate.
+type siblingPlacements struct {
+	ResourceGroups map[string]map[string]bool
+	Subscriptions  map[string]map[string]bool
+}
+
+func getSiblingPlacements(planResources []*types.PlanResource) siblingPlacements {
+	placements := siblingPlacements{
+		ResourceGroups: map[string]map[string]bool{},
+		Subscriptions:  map[string]map[string]bool{},
+	}
+	for _, planResource := range planResources {
+		if len(planResource.MappedResources) != 1 {
+			continue
+		}
+		modulePath := getModulePath(planResource)
+		graphResource := planResource.MappedResources[0]
+		if graphResource.ResourceGroup != "" {
+			if placements.ResourceGroups[modulePath] == nil {
+				placements.ResourceGroups[modulePath] = map[string]bool{}
+			}
+			placements.ResourceGroups[modulePath][strings.ToLower(graphResource.ResourceGroup)] = true
+		}
+		if graphResource.SubscriptionID != "" {
+			if placements.Subscriptions[modulePath] == nil {
+				placements.Subscriptions[modulePath] = map[string]bool{}
+			}
+			placements.Subscriptions[modulePath][strings.ToLower(graphResource.SubscriptionID)] = true
+		}
+	}
+	return placements
+}
+
+// getModulePath returns the module part of a resource address, e.g. module.hub["primary"] for module.hub["primary"].azurerm_subnet.this.
+func getModulePath(planResource *types.PlanResource) string {
+	if index := strings.LastIndex(planResource.Address, "."+planResource.Type+"."); index >= 0 {
+		return planResource.Address[:index]
+	}
+	return ""
+}
+
+// scoreCandidates scores every candidate for a plan resource and returns them highest score first.
+func scoreCandidates(planResource *types.PlanResource, candidates []*types.GraphResource, placements siblingPlacements) []types.CandidateScore {
+	modulePath := getModulePath(planResource)
+	resourceGroupName := getPlanResourceGroupName(planResource)
+	subscriptionID := getPlanSubscriptionID(planResource)
+	tags, _ := planResource.Properties["tags"].(map[string]any)
+
+	candidateScores := []types.CandidateScore{}
+	for _, candidate := range candidates {
+		candidateScore := types.CandidateScore{ResourceID: candidate.ID, Reasons: []string{}}
+		addScore := func(weight int, reason string) {
+			candidateScore.Score += weight
+			candidateScore.Reasons = append(candidateScore.Reasons, reason)
+		}
+
+		if strings.EqualFold(candidate.Name, planResource.ResourceName) || strings.HasSuffix(azure.NormalizeResourceID(candidate.ID), "/"+strings.ToLower(planResource.ResourceName)) {
+			addScore(scoreWeightName, "name")
+		}
+		if planResource.AzureResourceType != "" && strings.EqualFold(candidate.Type, planResource.AzureResourceType) {
+			addScore(scoreWeightType, "type")
+		}
+		if planResource.Location != "" && (planResource.Location == candidate.Location || strings.Contains(strings.ToLower(candidate.ID), strings.ToLower(planResource.Location))) {
+			addScore(scoreWeightLocation, "location")
+		}
+		if resourceGroupName != "" && strings.EqualFold(candidate.ResourceGroup, resourceGroupName) {
+			addScore(scoreWeightResourceGroup, fmt.Sprintf("resource group %s", candidate.ResourceGroup))
+		} else if placements.ResourceGroups[modulePath][strings.ToLower(candidate.ResourceGroup)] {
+			addScore(scoreWeightSiblingResourceGroup, fmt.Sprintf("sibling resource group %s", candidate.ResourceGroup))
+		}
+		if subscriptionID != "" && strings.EqualFold(candidate.SubscriptionID, subscriptionID) {
+			addScore(scoreWeightSubscription, "subscription")
+		} else if subscriptionID == "" && placements.Subscriptions[modulePath][strings.ToLower(candidate.SubscriptionID)] {
+			addScore(scoreWeightSubscription, "sibling subscription")
+		}
+		if len(tags) > 0 && tagsMatch(tags, candidate.Tags) {
+			addScore(scoreWeightTags, "tags")
+		}
+
+		candidateScores = append(candidateScores, candidateScore)
+	}
+
+	sort.SliceStable(candidateScores, func(i, j int) bool {
+		return candidateScores[i].Score > candidateScores[j].Score
+	})
+	return candidateScores
+}
+
+// pickCandidate returns the top candidate if it reaches the threshold and beats the runner up by the margin.
+func pickCandidate(candidateScores []types.CandidateScore, candidateScoring types.CandidateScoring) (types.CandidateScore, bool) {
+	if candidateScoring.Threshold <= 0 || len(candidateScores) == 0 {
+		return types.CandidateScore{}, false
+	}
+	top := candidateScores[0]
+	if top.Score < candidateScoring.Threshold {
+		return top, false
+	}
+	if len(candidateScores) > 1 && top.Score-candidateScores[1].Score < candidateScoring.Margin {
+		return top, false
+	}
+	return top, true
+}
+
+func getPlanSubscriptionID(planResource *types.PlanResource) string {
+	for _, property := range []string{"parent_id", "resource_group_id", "scope"} {
+		if value, ok := planResource.Properties[property].(string); ok {
+			if subscriptionID := azure.ParseSubscriptionID(value); subscriptionID != "" {
+				return subscriptionID
+			}
+		}
+	}
+	return ""
+}
+
+func tagsMatch(planTags map[string]any, graphTags map[string]string) bool {
+	for key, value := range planTags {
+		graphValue, ok := graphTags[key]
+		if !ok || fmt.Sprint(value) != graphValue {
+			return false
+		}
+	}
+	return true
+}
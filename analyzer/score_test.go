@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_scoreCandidates(t *testing.T) {
+	planResource := &types.PlanResource{
+		Address:           "module.hub.azurerm_virtual_network.this",
+		Type:              "azurerm_virtual_network",
+		ResourceName:      "hub",
+		AzureResourceType: "Microsoft.Network/virtualNetworks",
+		Location:          "eastus",
+		Properties: map[string]any{
+			"resource_group_name": "rg-hub",
+			"tags":                map[string]any{"env": "prod"},
+		},
+	}
+	candidates := []*types.GraphResource{
+		{ID: "/subscriptions/sub2/resourceGroups/rg-old/providers/Microsoft.Network/virtualNetworks/hub", Name: "hub", Type: "microsoft.network/virtualnetworks", Location: "eastus", SubscriptionID: "sub2", ResourceGroup: "rg-old"},
+		{ID: "/subscriptions/sub1/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/hub", Name: "hub", Type: "microsoft.network/virtualnetworks", Location: "eastus", SubscriptionID: "sub1", ResourceGroup: "rg-hub", Tags: map[string]string{"env": "prod"}},
+	}
+	placements := siblingPlacements{
+		ResourceGroups: map[string]map[string]bool{},
+		Subscriptions:  map[string]map[string]bool{"module.hub": {"sub1": true}},
+	}
+
+	candidateScores := scoreCandidates(planResource, candidates, placements)
+
+	assert.Len(t, candidateScores, 2)
+	assert.Equal(t, candidates[1].ID, candidateScores[0].ResourceID)
+	assert.Equal(t, 100, candidateScores[0].Score)
+	assert.Equal(t, []string{"name", "type", "location", "resource group rg-hub", "sibling subscription", "tags"}, candidateScores[0].Reasons)
+	assert.Equal(t, 70, candidateScores[1].Score)
+}
+
+func Test_pickCandidate(t *testing.T) {
+	scoring := types.CandidateScoring{Threshold: 70, Margin: 15}
+
+	tests := []struct {
+		name             string
+		candidateScores  []types.CandidateScore
+		candidateScoring types.CandidateScoring
+		picked           bool
+	}{
+		{"above threshold and margin", []types.CandidateScore{{ResourceID: "1", Score: 85}, {ResourceID: "2", Score: 70}}, scoring, true},
+		{"below threshold", []types.CandidateScore{{ResourceID: "1", Score: 65}, {ResourceID: "2", Score: 30}}, scoring, false},
+		{"within margin", []types.CandidateScore{{ResourceID: "1", Score: 85}, {ResourceID: "2", Score: 75}}, scoring, false},
+		{"disabled", []types.CandidateScore{{ResourceID: "1", Score: 100}, {ResourceID: "2", Score: 0}}, types.CandidateScoring{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, picked := pickCandidate(test.candidateScores, test.candidateScoring)
+			assert.Equal(t, test.picked, picked)
+		})
+	}
+}
+
+func Test_getModulePath(t *testing.T) {
+	assert.Equal(t, `module.hub["primary"]`, getModulePath(&types.PlanResource{Address: `module.hub["primary"].azurerm_subnet.this`, Type: "azurerm_subnet"}))
+	assert.Equal(t, "", getModulePath(&types.PlanResource{Address: "azurerm_subnet.this", Type: "azurerm_subnet"}))
+}
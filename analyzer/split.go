@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/terraform"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// importsManifestFileName is the manifest of the files written to the module, which is also read to remove them on the
+// next run
+const importsManifestFileName = "imports_manifest.json"
+
+var invalidFileNameCharactersRegex = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// writeSplitImportBlocks writes the import blocks of each group to its own imports.<group>.tf file, and returns the
+// files and the imports in each for the manifest, so the imports can be applied in waves.
+func (mappingClient *MappingClient) writeSplitImportBlocks(result *Result, importBlocks []types.ImportBlock) ([]types.ImportManifestFile, error) {
+	importBlocksByGroup := map[string][]types.ImportBlock{}
+	for _, importBlock := range importBlocks {
+		group := getImportGroup(importBlock, mappingClient.SplitImportsBy)
+		importBlocksByGroup[group] = append(importBlocksByGroup[group], importBlock)
+	}
+
+	groups := []string{}
+	for group := range importBlocksByGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	manifestFiles := []types.ImportManifestFile{}
+	for _, group := range groups {
+		fileName := fmt.Sprintf("imports.%s.tf", group)
+		importsFilePath, err := mappingClient.HclClient.WriteImportBlocks(importBlocksByGroup[group], fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write import blocks for %s: %w", group, err)
+		}
+		result.OutputFiles = append(result.OutputFiles, importsFilePath)
+		manifestFiles = append(manifestFiles, types.ImportManifestFile{
+			Group:        group,
+			FileName:     fileName,
+			ImportBlocks: importBlocksByGroup[group],
+		})
+	}
+	mappingClient.Logger.Infof("Split %d import blocks by %s into %d files", len(importBlocks), mappingClient.SplitImportsBy, len(manifestFiles))
+	return manifestFiles, nil
+}
+
+// getPreviouslyGeneratedFiles returns the files written to the module by the previous run, read from its manifest.
+// Only plain .tf file names are returned, so an edited manifest cannot remove anything else.
+func (mappingClient *MappingClient) getPreviouslyGeneratedFiles() ([]string, error) {
+	manifest, err := mappingClient.JsonClient.Import(importsManifestFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of the previous run: %w", importsManifestFileName, err)
+	}
+
+	fileNames := []any{}
+	if generatedFiles, ok := manifest["GeneratedFiles"].([]any); ok {
+		fileNames = append(fileNames, generatedFiles...)
+	}
+	// Manifests written before GeneratedFiles was added only list the split import files
+	if files, ok := manifest["Files"].([]any); ok {
+		for _, file := range files {
+			if file, ok := file.(map[string]any); ok {
+				fileNames = append(fileNames, file["FileName"])
+			}
+		}
+	}
+
+	generatedFiles := []string{}
+	for _, fileName := range fileNames {
+		fileName, ok := fileName.(string)
+		if !ok || fileName != filepath.Base(fileName) || filepath.Ext(fileName) != ".tf" || strings.ContainsAny(fileName, "*?[") {
+			continue
+		}
+		if !slices.Contains(generatedFiles, fileName) {
+			generatedFiles = append(generatedFiles, fileName)
+		}
+	}
+	return generatedFiles, nil
+}
+
+// getFilesToClean returns the files generated by a run and the files generated by the previous run, which are removed
+// before the module is planned so the plan does not include the imports of the previous run.
+func (mappingClient *MappingClient) getFilesToClean() ([]string, error) {
+	previouslyGeneratedFiles, err := mappingClient.getPreviouslyGeneratedFiles()
+	if err != nil {
+		return nil, err
+	}
+	filesToClean := []string{importsFileName, destroyFileName, movedFileName, removedFileName}
+	for _, fileName := range previouslyGeneratedFiles {
+		if !slices.Contains(filesToClean, fileName) {
+			filesToClean = append(filesToClean, fileName)
+		}
+	}
+	return filesToClean, nil
+}
+
+// getImportGroup returns the group of an import block, made safe to use in a file name. Imports in the root module,
+// or of resources that are not in a subscription or resource group, are grouped as root, tenant or none.
+func getImportGroup(importBlock types.ImportBlock, splitImportsBy types.SplitImportsBy) string {
+	configurationAddress := terraform.GetConfigurationAddress(importBlock.To)
+	resourceID := azure.NormalizeResourceID(importBlock.ID)
+
+	group := ""
+	switch splitImportsBy {
+	case types.SplitImportsByModule:
+		modules := []string{}
+		segments := strings.Split(configurationAddress, ".")
+		for i := 0; i < len(segments)-1; i++ {
+			if segments[i] == "module" {
+				modules = append(modules, segments[i+1])
+				i++
+			}
+		}
+		group = strings.Join(modules, ".")
+		if group == "" {
+			group = "root"
+		}
+	case types.SplitImportsBySubscription:
+		group = azure.ParseSubscriptionID(resourceID)
+		if group == "" {
+			group = "tenant"
+		}
+	case types.SplitImportsByResourceGroup:
+		group = azure.ParseResourceGroupName(resourceID)
+		if group == "" {
+			group = "none"
+		}
+	case types.SplitImportsByResourceType:
+		segments := strings.Split(configurationAddress, ".")
+		if len(segments) >= 2 {
+			group = segments[len(segments)-2]
+		}
+	}
+	return strings.Trim(invalidFileNameCharactersRegex.ReplaceAllString(strings.ToLower(group), "-"), "-")
+}
@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/json"
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getImportGroup(t *testing.T) {
+	vnetID := "/subscriptions/00000000-0000-0000-0000-000000000001/resourceGroups/RG-Hub/providers/Microsoft.Network/virtualNetworks/vnet-hub?api-version=2024-01-01"
+	tests := []struct {
+		name           string
+		importBlock    types.ImportBlock
+		splitImportsBy types.SplitImportsBy
+		expected       string
+	}{
+		{"module", types.ImportBlock{To: `module.hub["primary"].module.network.azurerm_virtual_network.this[0]`, ID: vnetID}, types.SplitImportsByModule, "hub.network"},
+		{"root module", types.ImportBlock{To: "azurerm_resource_group.this", ID: vnetID}, types.SplitImportsByModule, "root"},
+		{"module key with dots", types.ImportBlock{To: `module.hub.azurerm_subnet.this["a.b"]`, ID: vnetID}, types.SplitImportsByModule, "hub"},
+		{"subscription", types.ImportBlock{To: "azurerm_virtual_network.this", ID: vnetID}, types.SplitImportsBySubscription, "00000000-0000-0000-0000-000000000001"},
+		{"tenant", types.ImportBlock{To: "azurerm_management_group.this", ID: "/providers/Microsoft.Management/managementGroups/alz"}, types.SplitImportsBySubscription, "tenant"},
+		{"resource group", types.ImportBlock{To: "azurerm_virtual_network.this", ID: vnetID}, types.SplitImportsByResourceGroup, "rg-hub"},
+		{"no resource group", types.ImportBlock{To: "azurerm_management_group.this", ID: "/providers/Microsoft.Management/managementGroups/alz"}, types.SplitImportsByResourceGroup, "none"},
+		{"resource type", types.ImportBlock{To: `module.hub.azurerm_subnet.this["a.b"]`, ID: vnetID}, types.SplitImportsByResourceType, "azurerm_subnet"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, getImportGroup(test.importBlock, test.splitImportsBy))
+		})
+	}
+}
+
+func TestMappingClient_Map_SplitImportsByModule(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "/subscriptions/sub1/resourceGroups/rg1", Name: "rg1", Type: "type1", Location: "eastus"},
+		{ID: "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub", Name: "vnet-hub", Type: "type2", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{
+		{Address: "azurerm_resource_group.rg", ResourceName: "rg1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: "module.network.azurerm_virtual_network.vnet", ResourceName: "vnet-hub", Type: "type2", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		SplitImportsBy:      types.SplitImportsByModule,
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.Map(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"issues.json", "resources.json", "final.json", "imports.network.tf", "imports.root.tf", "destroy.tf", "imports_manifest.json"}, result.OutputFiles)
+	assert.Equal(t, []types.ImportBlock{{To: "azurerm_resource_group.rg", ID: graphResources[0].ID}}, mappingClient.HclClient.(*mockHclClient).WrittenImportBlocks)
+	manifest := mappingClient.JsonClient.(*mockJsonClient).Exported["imports_manifest.json"].(types.ImportManifest)
+	assert.Equal(t, []string{"network", "root"}, []string{manifest.Files[0].Group, manifest.Files[1].Group})
+	assert.Equal(t, []string{"imports.network.tf", "imports.root.tf", "destroy.tf"}, manifest.GeneratedFiles)
+}
+
+func TestMappingClient_Map_CleansFilesOfPreviousRun(t *testing.T) {
+	graphResources := []*types.GraphResource{{ID: "/subscriptions/sub1/resourceGroups/rg1", Name: "rg1", Type: "type1", Location: "eastus"}}
+	planResources := []*types.PlanResource{{Address: "azurerm_resource_group.rg", ResourceName: "rg1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact}}
+	previousManifest := map[string]any{
+		"SplitImportsBy": "module",
+		"Files":          []any{map[string]any{"Group": "network", "FileName": "imports.network.tf"}},
+		"GeneratedFiles": []any{"imports.network.tf", "imports.root.tf", "moved.tf", "../main.tf", "imports.*.tf"},
+	}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{},
+		JsonClient:          &mockJsonClient{Resources: previousManifest},
+		HclClient:           &mockHclClient{},
+		Logger:              logrus.New(),
+	}
+
+	result, err := mappingClient.Map(context.Background())
+
+	// Only the files written by the tool are removed, so files such as imports.manual.tf are kept
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"imports.tf", "destroy.tf", "moved.tf", "removed.tf", "imports.network.tf", "imports.root.tf"}, mappingClient.HclClient.(*mockHclClient).FilesToClean)
+	assert.Equal(t, mappingClient.HclClient.(*mockHclClient).FilesToClean, result.CleanedFiles)
+	assert.Contains(t, result.OutputFiles, "imports.tf")
+	manifest := mappingClient.JsonClient.(*mockJsonClient).Exported["imports_manifest.json"].(types.ImportManifest)
+	assert.Equal(t, types.SplitImportsByNone, manifest.SplitImportsBy)
+	assert.Empty(t, manifest.Files)
+	assert.Equal(t, []string{"imports.tf", "destroy.tf"}, manifest.GeneratedFiles)
+}
+
+func TestMappingClient_getPreviouslyGeneratedFiles_NoManifest(t *testing.T) {
+	mappingClient := &MappingClient{JsonClient: json.NewJsonClient(t.TempDir(), logrus.New()), Logger: logrus.New()}
+
+	generatedFiles, err := mappingClient.getPreviouslyGeneratedFiles()
+
+	assert.NoError(t, err)
+	assert.Empty(t, generatedFiles)
+}
@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	encodingjson "encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+var attributePathIndexRegex = regexp.MustCompile(`^([^\[]*)((?:\[\d+\])*)$`)
+
+func (mappingClient *MappingClient) suggestVariableValues(liveMappedResources []liveMappedResource) []types.VariableValue {
+	variableValues := []types.VariableValue{}
+
+	for _, variableMapping := range mappingClient.VariableMappings {
+		sources := []types.VariableValueSource{}
+		for _, liveMappedResource := range liveMappedResources {
+			if !mappingClient.variableMappingMatches(variableMapping, liveMappedResource.PlanResource) {
+				continue
+			}
+
+			value, found := lookupAttributePath(liveMappedResource.LiveResource, variableMapping.AttributePath)
+			if !found {
+				mappingClient.Logger.Debugf("Attribute %s not found for Address: %s, Resource ID: %s", variableMapping.AttributePath, liveMappedResource.PlanResource.Address, liveMappedResource.GraphResource.ID)
+				continue
+			}
+
+			sources = append(sources, types.VariableValueSource{
+				ResourceAddress: liveMappedResource.PlanResource.Address,
+				ResourceID:      liveMappedResource.GraphResource.ID,
+				Value:           value,
+			})
+		}
+
+		if len(sources) == 0 {
+			mappingClient.Logger.Warnf("No value found in Azure for variable %s from attribute %s", variableMapping.Variable, variableMapping.AttributePath)
+			continue
+		}
+
+		value, conflicting := pickVariableValue(sources)
+		if conflicting {
+			mappingClient.Logger.Warnf("Conflicting values found in Azure for variable %s across %d resources, using the most common value", variableMapping.Variable, len(sources))
+		}
+
+		variableValues = append(variableValues, types.VariableValue{
+			Variable:    variableMapping.Variable,
+			Value:       value,
+			Conflicting: conflicting,
+			Sources:     sources,
+		})
+	}
+
+	return variableValues
+}
+
+func (mappingClient *MappingClient) variableMappingMatches(variableMapping types.VariableMapping, planResource *types.PlanResource) bool {
+	if variableMapping.Type != "" && !strings.EqualFold(variableMapping.Type, planResource.Type) {
+		return false
+	}
+	if variableMapping.SubType != "" && !strings.EqualFold(variableMapping.SubType, planResource.SubType) {
+		return false
+	}
+	if variableMapping.AddressPattern != "" {
+		matched, err := regexp.MatchString(variableMapping.AddressPattern, planResource.Address)
+		if err != nil {
+			mappingClient.Logger.Debugf("Error matching pattern %s: %v", variableMapping.AddressPattern, err)
+			return false
+		}
+		return matched
+	}
+	return true
+}
+
+// pickVariableValue returns the most common value across the sources, preferring the first source on a tie,
+// and whether the sources disagree.
+func pickVariableValue(sources []types.VariableValueSource) (any, bool) {
+	counts := map[string]int{}
+	values := map[string]any{}
+	order := []string{}
+	for _, source := range sources {
+		key, _ := encodingjson.Marshal(source.Value)
+		if _, exists := counts[string(key)]; !exists {
+			order = append(order, string(key))
+			values[string(key)] = source.Value
+		}
+		counts[string(key)]++
+	}
+
+	bestKey := order[0]
+	for _, key := range order[1:] {
+		if counts[key] > counts[bestKey] {
+			bestKey = key
+		}
+	}
+	return values[bestKey], len(order) > 1
+}
+
+// lookupAttributePath reads a dot separated path such as properties.subnets[0].name from an ARM body,
+// matching keys case insensitively.
+func lookupAttributePath(value any, attributePath string) (any, bool) {
+	for _, segment := range strings.Split(attributePath, ".") {
+		match := attributePathIndexRegex.FindStringSubmatch(segment)
+		if match == nil {
+			return nil, false
+		}
+
+		if match[1] != "" {
+			values, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if value, ok = lookupKey(values, match[1]); !ok {
+				return nil, false
+			}
+		}
+
+		for _, index := range strings.FieldsFunc(match[2], func(r rune) bool { return r == '[' || r == ']' }) {
+			values, ok := value.([]any)
+			position, _ := strconv.Atoi(index)
+			if !ok || position >= len(values) {
+				return nil, false
+			}
+			value = values[position]
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_suggestVariableValues(t *testing.T) {
+	client := &MappingClient{
+		Logger: logrus.New(),
+		VariableMappings: []types.VariableMapping{
+			{Variable: "hub.address_space", Type: "azurerm_virtual_network", AttributePath: "properties.addressSpace.addressPrefixes"},
+			{Variable: "firewall_sku", Type: "azurerm_firewall", AttributePath: "properties.sku.tier"},
+			{Variable: "first_subnet", AddressPattern: `\.hub$`, AttributePath: "properties.subnets[0].name"},
+			{Variable: "missing", AttributePath: "properties.missing"},
+		},
+	}
+	liveMappedResources := []liveMappedResource{
+		{
+			PlanResource:  &types.PlanResource{Address: "azurerm_virtual_network.hub", Type: "azurerm_virtual_network"},
+			GraphResource: &types.GraphResource{ID: "vnet1"},
+			LiveResource: map[string]any{"properties": map[string]any{
+				"addressSpace": map[string]any{"addressPrefixes": []any{"10.0.0.0/16"}},
+				"subnets":      []any{map[string]any{"name": "snet1"}},
+			}},
+		},
+		{
+			PlanResource:  &types.PlanResource{Address: "azurerm_firewall.a", Type: "azurerm_firewall"},
+			GraphResource: &types.GraphResource{ID: "fw1"},
+			LiveResource:  map[string]any{"properties": map[string]any{"sku": map[string]any{"tier": "Standard"}}},
+		},
+		{
+			PlanResource:  &types.PlanResource{Address: "azurerm_firewall.b", Type: "azurerm_firewall"},
+			GraphResource: &types.GraphResource{ID: "fw2"},
+			LiveResource:  map[string]any{"properties": map[string]any{"sku": map[string]any{"tier": "Premium"}}},
+		},
+		{
+			PlanResource:  &types.PlanResource{Address: "azurerm_firewall.c", Type: "azurerm_firewall"},
+			GraphResource: &types.GraphResource{ID: "fw3"},
+			LiveResource:  map[string]any{"properties": map[string]any{"sku": map[string]any{"tier": "Premium"}}},
+		},
+	}
+
+	variableValues := client.suggestVariableValues(liveMappedResources)
+
+	assert.Len(t, variableValues, 3)
+	assert.Equal(t, "hub.address_space", variableValues[0].Variable)
+	assert.Equal(t, []any{"10.0.0.0/16"}, variableValues[0].Value)
+	assert.False(t, variableValues[0].Conflicting)
+	assert.Equal(t, "firewall_sku", variableValues[1].Variable)
+	assert.Equal(t, "Premium", variableValues[1].Value)
+	assert.True(t, variableValues[1].Conflicting)
+	assert.Len(t, variableValues[1].Sources, 3)
+	assert.Equal(t, "snet1", variableValues[2].Value)
+}
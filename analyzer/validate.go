@@ -0,0 +1,195 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/csv"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// ErrInvalidResolutions is returned alongside the result when resolutions cannot be applied or issues are unresolved.
+var ErrInvalidResolutions = errors.New("resolved issues are not valid for this run")
+
+// ValidateResolutions checks every resolution in the resolved issues file against the issues of the current run,
+// without writing any files, so mistakes are found before the import blocks are generated. The files generated by the
+// previous run are set aside while the module is planned and restored afterwards, so the plan is the one a run makes.
+func (mappingClient *MappingClient) ValidateResolutions(ctx context.Context) (*ValidationResult, error) {
+	if !mappingClient.HasInputCsv {
+		return nil, errors.New("a resolved issues file is required to validate resolutions")
+	}
+
+	// Problems in the file are reported with the problems found against the run, rather than stopping the validation
+	resolvedIssues, err := mappingClient.IssueCsvClient.Import()
+	importError := &csv.ImportError{}
+	if err != nil && (!errors.As(err, &importError) || resolvedIssues == nil) {
+		return nil, fmt.Errorf("failed to import issues: %w", err)
+	}
+	problems := append([]types.ResolutionProblem{}, importError.Problems...)
+	if resolvedIssues == nil {
+		resolvedIssues = &map[string]types.Issue{}
+	}
+
+	filesToSetAside, err := mappingClient.getFilesToClean()
+	if err != nil {
+		return nil, err
+	}
+	restoreFiles, err := mappingClient.HclClient.SetFilesAside(filesToSetAside)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set aside generated files: %w", err)
+	}
+	graphResources, planResources, err := mappingClient.getGraphAndPlanResources(ctx, nil)
+	restoreFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	_, currentIssues, _ := mappingClient.mapResourcesFromGraphToPlan(graphResources, planResources, nil)
+	ruleResolvedIssues := mappingClient.applyResolutionRules(currentIssues, resolvedIssues)
+
+	result := &ValidationResult{
+		Problems: append(problems, validateResolutions(currentIssues, resolvedIssues, ruleResolvedIssues, graphResources, problems)...),
+		Counts:   map[types.ResolutionProblemType]int{},
+		Issues:   len(currentIssues),
+	}
+	for _, problem := range result.Problems {
+		result.Counts[problem.Type]++
+	}
+
+	if len(result.Problems) > 0 {
+		mappingClient.Logger.Warnf("Found %d problems with the resolved issues", len(result.Problems))
+		return result, ErrInvalidResolutions
+	}
+	mappingClient.Logger.Infof("All %d issues are resolved", result.Issues)
+	return result, nil
+}
+
+// validateResolutions checks the resolution of each current issue, and reports resolutions that match no issue. Issues
+// with a problem in the resolved issues file are not checked again.
+func validateResolutions(currentIssues map[string]types.Issue, resolvedIssues *map[string]types.Issue, ruleResolvedIssues map[string]types.Issue, graphResources []*types.GraphResource, fileProblems []types.ResolutionProblem) []types.ResolutionProblem {
+	problems := []types.ResolutionProblem{}
+	addProblem := func(issue types.Issue, problemType types.ResolutionProblemType, format string, args ...any) {
+		problems = append(problems, types.ResolutionProblem{
+			Type:            problemType,
+			IssueID:         issue.IssueID,
+			ResourceAddress: issue.ResourceAddress,
+			Message:         fmt.Sprintf(format, args...),
+		})
+	}
+
+	problemIssueIDs := map[string]bool{}
+	for _, problem := range fileProblems {
+		if problem.IssueID != "" {
+			problemIssueIDs[problem.IssueID] = true
+		}
+		if problem.ResourceAddress != "" {
+			problemIssueIDs[types.GetIssueID(problem.ResourceAddress)] = true
+		}
+	}
+
+	unusedResourceIDs := map[string]bool{}
+	for _, issue := range currentIssues {
+		if issue.IssueType == types.IssueTypeUnusedResourceID {
+			unusedResourceIDs[azure.NormalizeResourceID(issue.ResourceAddress)] = true
+		}
+	}
+	graphResourcesByID := map[string]*types.GraphResource{}
+	for _, graphResource := range graphResources {
+		graphResourcesByID[azure.NormalizeResourceID(graphResource.ID)] = graphResource
+	}
+
+	issueIDs := []string{}
+	for issueID := range currentIssues {
+		issueIDs = append(issueIDs, issueID)
+	}
+	sort.Strings(issueIDs)
+
+	matchedKeys := map[string]bool{}
+	for _, issueID := range issueIDs {
+		issue := currentIssues[issueID]
+		if _, exists := ruleResolvedIssues[issueID]; exists {
+			continue
+		}
+
+		resolvedIssue, exists := getResolvedIssue(resolvedIssues, issue)
+		if exists {
+			// Multiple resource ID issues with every candidate ignored are keyed by the issue ID alone
+			matchedKeys[resolvedIssue.IssueID] = true
+			matchedKeys[issueID] = true
+			matchedKeys[issueID[:types.LegacyIssueIDLength]] = true
+		}
+		if problemIssueIDs[issueID] || problemIssueIDs[issueID[:types.LegacyIssueIDLength]] {
+			continue
+		}
+		if !exists {
+			addProblem(issue, types.ResolutionProblemTypeUnresolved, "no resolution for the %s issue", issue.IssueType)
+			continue
+		}
+		if resolvedIssue.IssueID == "" {
+			addProblem(issue, types.ResolutionProblemTypeUnresolved, "every candidate is ignored, choose one with Use")
+			continue
+		}
+		if resolvedIssue.IssueType != "" && resolvedIssue.IssueType != issue.IssueType {
+			addProblem(issue, types.ResolutionProblemTypeIssueTypeChanged, "the resolution is for a %s issue but the issue is now %s", resolvedIssue.IssueType, issue.IssueType)
+			continue
+		}
+
+		action := resolvedIssue.Resolution.ActionType
+		if !slices.Contains(issue.IssueType.ValidActionTypes(), action) {
+			addProblem(issue, types.ResolutionProblemTypeMalformed, "action %s is not valid for a %s issue", action, issue.IssueType)
+			continue
+		}
+
+		switch {
+		case action == types.ActionTypeUse:
+			candidateID := ""
+			if len(resolvedIssue.MappedResourceIDs) > 0 {
+				candidateID = resolvedIssue.MappedResourceIDs[0]
+			}
+			isCandidate := candidateID != "" && slices.ContainsFunc(issue.MappedResourceIDs, func(mappedResourceID string) bool {
+				return azure.NormalizeResourceID(mappedResourceID) == azure.NormalizeResourceID(candidateID)
+			})
+			if isCandidate {
+				continue
+			}
+			if _, exists := graphResourcesByID[azure.NormalizeResourceID(candidateID)]; exists {
+				addProblem(issue, types.ResolutionProblemTypeMissingCandidate, "resource %s is no longer a candidate for the issue", candidateID)
+			} else {
+				addProblem(issue, types.ResolutionProblemTypeMissingCandidate, "resource %q no longer exists in Resource Graph", candidateID)
+			}
+		case action == types.ActionTypeReplace && issue.IssueType == types.IssueTypeNoResourceID:
+			targetIssue, _, err := getReplaceTarget(resolvedIssues, resolvedIssue, graphResourcesByID)
+			if err != nil {
+				addProblem(issue, types.ResolutionProblemTypeInvalidReplaceTarget, "%v", err)
+				continue
+			}
+			if !unusedResourceIDs[azure.NormalizeResourceID(targetIssue.ResourceAddress)] {
+				addProblem(issue, types.ResolutionProblemTypeInvalidReplaceTarget, "the Replace target %s is not an unused resource in this run", targetIssue.ResourceAddress)
+			}
+		}
+	}
+
+	keys := []string{}
+	for key := range *resolvedIssues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		resolvedIssue := (*resolvedIssues)[key]
+		if matchedKeys[key] || problemIssueIDs[key] || (resolvedIssue.IssueID != "" && matchedKeys[resolvedIssue.IssueID]) {
+			continue
+		}
+		problems = append(problems, types.ResolutionProblem{
+			Type:            types.ResolutionProblemTypeUnknownIssue,
+			IssueID:         resolvedIssue.IssueID,
+			ResourceAddress: resolvedIssue.ResourceAddress,
+			Message:         fmt.Sprintf("%s matches no issue in this run", key),
+		})
+	}
+
+	return problems
+}
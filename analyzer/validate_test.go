@@ -0,0 +1,165 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMappingClient_ValidateResolutions(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{
+		{ID: "id1", Name: "res1", Type: "type1", Location: "eastus"},
+		{ID: "id2", Name: "res1", Type: "type1", Location: "eastus"},
+		{ID: "id3", Name: "res3", Type: "type3", Location: "eastus"},
+	}
+	planResources := []*types.PlanResource{
+		{Address: "addr1", ResourceName: "res1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+		{Address: "addr2", ResourceName: "notfound", Type: "type2", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+	multipleIssueID := types.GetIssueID("addr1")
+	noResourceIssueID := types.GetIssueID("addr2")
+	unknownIssueID := types.GetIssueID("addr9")
+	resolvedIssues := map[string]types.Issue{
+		multipleIssueID: {
+			IssueID: multipleIssueID, IssueType: types.IssueTypeMultipleResourceIDs, ResourceAddress: "addr1",
+			MappedResourceIDs: []string{"id9"}, Resolution: types.IssueResolution{ActionType: types.ActionTypeUse},
+		},
+		noResourceIssueID: {
+			IssueID: noResourceIssueID, IssueType: types.IssueTypeNoResourceID, ResourceAddress: "addr2",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: multipleIssueID},
+		},
+		unknownIssueID: {
+			IssueID: unknownIssueID, IssueType: types.IssueTypeNoResourceID, ResourceAddress: "addr9",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeIgnore},
+		},
+	}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		HasInputCsv:         true,
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{Issues: &resolvedIssues},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.ValidateResolutions(context.Background())
+
+	assert.ErrorIs(t, err, ErrInvalidResolutions)
+	assert.Equal(t, 3, result.Issues)
+	assert.Len(t, result.Problems, 4)
+	assert.Equal(t, 1, result.Counts[types.ResolutionProblemTypeMissingCandidate])
+	assert.Equal(t, 1, result.Counts[types.ResolutionProblemTypeInvalidReplaceTarget])
+	assert.Equal(t, 1, result.Counts[types.ResolutionProblemTypeUnresolved])
+	assert.Equal(t, 1, result.Counts[types.ResolutionProblemTypeUnknownIssue])
+	for _, problem := range result.Problems {
+		if problem.Type == types.ResolutionProblemTypeUnresolved {
+			assert.Equal(t, "id3", problem.ResourceAddress)
+		}
+		if problem.Type == types.ResolutionProblemTypeUnknownIssue {
+			assert.Equal(t, "addr9", problem.ResourceAddress)
+		}
+	}
+	// Nothing is written when validating, and the generated files are only set aside while planning
+	hclClient := mappingClient.HclClient.(*mockHclClient)
+	assert.Nil(t, mappingClient.JsonClient.(*mockJsonClient).Exported)
+	assert.False(t, hclClient.Called)
+	assert.False(t, hclClient.CleanFilesCalled)
+	assert.Equal(t, []string{"imports.tf", "destroy.tf", "moved.tf", "removed.tf"}, hclClient.FilesSetAside)
+	assert.True(t, hclClient.FilesRestored)
+}
+
+func TestMappingClient_ValidateResolutions_AllResolved(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{{ID: "id1", Name: "res1", Type: "type1", Location: "eastus"}}
+	planResources := []*types.PlanResource{
+		{Address: "addr1", ResourceName: "notfound", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+	noResourceIssueID := types.GetIssueID("addr1")
+	unusedIssueID := types.GetIssueID("id1")
+	resolvedIssues := map[string]types.Issue{
+		noResourceIssueID: {
+			IssueID: noResourceIssueID, IssueType: types.IssueTypeNoResourceID, ResourceAddress: "addr1",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: unusedIssueID},
+		},
+		unusedIssueID: {
+			IssueID: unusedIssueID, IssueType: types.IssueTypeUnusedResourceID, ResourceAddress: "id1",
+			Resolution: types.IssueResolution{ActionType: types.ActionTypeReplace},
+		},
+	}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		HasInputCsv:         true,
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{Issues: &resolvedIssues},
+		JsonClient:          &mockJsonClient{},
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.ValidateResolutions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, result.Problems)
+	assert.Equal(t, 2, result.Issues)
+}
+
+func TestMappingClient_ValidateResolutions_SetsAsideFilesOfPreviousRun(t *testing.T) {
+	logger := logrus.New()
+	graphResources := []*types.GraphResource{{ID: "id1", Name: "res1", Type: "type1", Location: "eastus"}}
+	planResources := []*types.PlanResource{
+		{Address: "addr1", ResourceName: "res1", Type: "type1", Location: "eastus", ResourceNameMatchType: types.NameMatchTypeExact},
+	}
+	resolvedIssues := map[string]types.Issue{}
+	jsonClient := &mockJsonClient{Resources: map[string]any{
+		"GeneratedFiles": []any{"imports.network.tf", "imports.root.tf", "destroy.tf", "../main.tf"},
+	}}
+	mappingClient := &MappingClient{
+		WorkingFolderPath:   ".",
+		HasInputCsv:         true,
+		ResourceGraphClient: &mockResourceGraphClient{Resources: graphResources},
+		PlanClient:          &mockPlanClient{Resources: planResources},
+		IssueCsvClient:      &mockIssueCsvClient{Issues: &resolvedIssues},
+		JsonClient:          jsonClient,
+		HclClient:           &mockHclClient{},
+		Logger:              logger,
+	}
+
+	result, err := mappingClient.ValidateResolutions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Issues)
+	hclClient := mappingClient.HclClient.(*mockHclClient)
+	assert.Equal(t, []string{"imports.tf", "destroy.tf", "moved.tf", "removed.tf", "imports.network.tf", "imports.root.tf"}, hclClient.FilesSetAside)
+	assert.True(t, hclClient.FilesRestored)
+	assert.False(t, hclClient.CleanFilesCalled)
+}
+
+func Test_validateResolutions_UseMatchesWholeResourceID(t *testing.T) {
+	hubID := "/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet-hub"
+	issueID := types.GetIssueID("azurerm_virtual_network.hub")
+	currentIssues := map[string]types.Issue{
+		issueID: {IssueID: issueID, IssueType: types.IssueTypeMultipleResourceIDs, ResourceAddress: "azurerm_virtual_network.hub", MappedResourceIDs: []string{hubID + "-2", hubID + "-3"}},
+	}
+	resolvedIssues := map[string]types.Issue{
+		issueID: {IssueID: issueID, IssueType: types.IssueTypeMultipleResourceIDs, ResourceAddress: "azurerm_virtual_network.hub", MappedResourceIDs: []string{hubID}, Resolution: types.IssueResolution{ActionType: types.ActionTypeUse}},
+	}
+	graphResources := []*types.GraphResource{{ID: hubID}, {ID: hubID + "-2"}, {ID: hubID + "-3"}}
+
+	problems := validateResolutions(currentIssues, &resolvedIssues, nil, graphResources, nil)
+	if assert.Len(t, problems, 1) {
+		assert.Equal(t, types.ResolutionProblemTypeMissingCandidate, problems[0].Type)
+	}
+
+	issue := currentIssues[issueID]
+	issue.MappedResourceIDs = []string{hubID + "-2", hubID + "/"}
+	currentIssues[issueID] = issue
+	assert.Empty(t, validateResolutions(currentIssues, &resolvedIssues, nil, graphResources, nil))
+}
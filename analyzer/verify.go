@@ -0,0 +1,217 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/azure/terraform-state-importer/hcl"
+	"github.com/azure/terraform-state-importer/json"
+	"github.com/azure/terraform-state-importer/terraform"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// ErrVerifyBlockers is returned alongside the result when imports failed or would replace a resource.
+var ErrVerifyBlockers = errors.New("blocking imports found during verification")
+
+// VerifyResult is the outcome of a verify run.
+type VerifyResult struct {
+	ImportVerifications []types.ImportVerification
+	Counts              map[types.ImportStatus]int
+	Blockers            int
+	OutputFiles         []string
+}
+
+type VerifyClient struct {
+	ImportsFileName string
+	FailOnUpdate    bool
+	PlanClient      terraform.IPlanClient
+	JsonClient      json.IJsonClient
+	HclClient       hcl.IHclClient
+	Logger          *logrus.Logger
+}
+
+func NewVerifyClient(importsFileName string, failOnUpdate bool, planClient terraform.IPlanClient, jsonClient json.IJsonClient, hclClient hcl.IHclClient, logger *logrus.Logger) *VerifyClient {
+	return &VerifyClient{
+		ImportsFileName: importsFileName,
+		FailOnUpdate:    failOnUpdate,
+		PlanClient:      planClient,
+		JsonClient:      jsonClient,
+		HclClient:       hclClient,
+		Logger:          logger,
+	}
+}
+
+// Verify plans the module with the generated import blocks and classifies each import by the change the plan makes to it.
+func (verifyClient *VerifyClient) Verify(ctx context.Context) (*VerifyResult, error) {
+	importBlocks, err := verifyClient.HclClient.ReadImportBlocks(verifyClient.ImportsFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import blocks: %w", err)
+	}
+	verifyClient.Logger.Infof("Verifying %d import blocks from %s", len(importBlocks), verifyClient.ImportsFileName)
+
+	// Imports that terraform cannot plan fail the whole plan, they are reported from the diagnostics of the plan
+	importChanges, err := verifyClient.PlanClient.PlanAndGetImportChanges(ctx)
+	planError := &terraform.PlanError{}
+	if err != nil && !errors.As(err, &planError) {
+		return nil, fmt.Errorf("failed to plan the imports: %w", err)
+	}
+	importVerifications := []types.ImportVerification{}
+	if err != nil {
+		failedImportVerifications, ok := classifyFailedImports(importBlocks, planError.Diagnostics)
+		if !ok {
+			return nil, fmt.Errorf("failed to plan the imports: %w", err)
+		}
+		importVerifications = failedImportVerifications
+	} else {
+		importVerifications = verifyClient.classifyImports(importBlocks, importChanges)
+	}
+
+	result := &VerifyResult{
+		ImportVerifications: importVerifications,
+		Counts:              map[types.ImportStatus]int{},
+		OutputFiles:         []string{},
+	}
+	for _, importVerification := range result.ImportVerifications {
+		result.Counts[importVerification.Status]++
+		if importVerification.Blocker {
+			result.Blockers++
+			verifyClient.Logger.Warnf("Blocking import %s: %s, %s", importVerification.ResourceAddress, importVerification.Status, importVerification.Reason)
+		}
+	}
+
+	verifyClient.Logger.Infof("Verified imports: %d clean, %d updated, %d replaced, %d failed, %d unverified",
+		result.Counts[types.ImportStatusClean], result.Counts[types.ImportStatusUpdated], result.Counts[types.ImportStatusReplaced], result.Counts[types.ImportStatusFailed], result.Counts[types.ImportStatusUnverified])
+
+	filePath, err := verifyClient.JsonClient.Export(result.ImportVerifications, "verify.json")
+	if err != nil {
+		return result, fmt.Errorf("failed to export verify.json: %w", err)
+	}
+	result.OutputFiles = append(result.OutputFiles, filePath)
+
+	if result.Blockers > 0 {
+		return result, fmt.Errorf("%w: %d of %d imports", ErrVerifyBlockers, result.Blockers, len(result.ImportVerifications))
+	}
+	return result, nil
+}
+
+// classifyImports pairs each import block with the planned change for its address. Import blocks with no
+// importing change in the plan have failed, and imports that delete the resource would replace it.
+func (verifyClient *VerifyClient) classifyImports(importBlocks []types.ImportBlock, importChanges []*types.ImportChange) []types.ImportVerification {
+	importChangesByAddress := map[string]*types.ImportChange{}
+	for _, importChange := range importChanges {
+		importChangesByAddress[normalizeAddress(importChange.Address)] = importChange
+	}
+
+	importVerifications := []types.ImportVerification{}
+	verifiedAddresses := map[string]bool{}
+	for _, importBlock := range importBlocks {
+		address := normalizeAddress(importBlock.To)
+		verifiedAddresses[address] = true
+
+		importChange, ok := importChangesByAddress[address]
+		if !ok {
+			importVerifications = append(importVerifications, types.ImportVerification{
+				ResourceAddress: importBlock.To,
+				ResourceID:      importBlock.ID,
+				Status:          types.ImportStatusFailed,
+				Blocker:         true,
+				Reason:          "the plan does not import this resource",
+			})
+			continue
+		}
+		importVerifications = append(importVerifications, verifyClient.classifyImportChange(importChange, importBlock.ID))
+	}
+
+	// Imports declared elsewhere in the module are reported too, as they are part of the same plan
+	for _, importChange := range importChanges {
+		if !verifiedAddresses[normalizeAddress(importChange.Address)] {
+			importVerifications = append(importVerifications, verifyClient.classifyImportChange(importChange, importChange.ImportID))
+		}
+	}
+
+	sort.SliceStable(importVerifications, func(i, j int) bool {
+		return importVerifications[i].ResourceAddress < importVerifications[j].ResourceAddress
+	})
+	return importVerifications
+}
+
+// classifyFailedImports marks the import blocks with an error diagnostic in a failed plan as failed. Diagnostics are
+// matched by their address, or by the import address in their detail when they have none, as for an import target that
+// is not in the configuration. The other imports are unverified, as the plan stopped before their changes were known.
+// It returns false when no diagnostic is for an import, so the plan failed for another reason.
+func classifyFailedImports(importBlocks []types.ImportBlock, diagnostics []types.PlanDiagnostic) ([]types.ImportVerification, bool) {
+	importVerifications := []types.ImportVerification{}
+	failed := 0
+	for _, importBlock := range importBlocks {
+		address := normalizeAddress(importBlock.To)
+		reasons := []string{}
+		for _, diagnostic := range diagnostics {
+			matched := normalizeAddress(diagnostic.Address) == address
+			if diagnostic.Address == "" {
+				matched = diagnosticMentionsAddress(diagnostic, importBlock.To)
+			}
+			if matched && !slices.Contains(reasons, diagnostic.Summary) {
+				reasons = append(reasons, diagnostic.Summary)
+			}
+		}
+
+		importVerification := types.ImportVerification{
+			ResourceAddress: importBlock.To,
+			ResourceID:      importBlock.ID,
+			Status:          types.ImportStatusUnverified,
+			Reason:          "the plan failed before this import was verified",
+		}
+		if len(reasons) > 0 {
+			importVerification.Status = types.ImportStatusFailed
+			importVerification.Blocker = true
+			importVerification.Reason = strings.Join(reasons, "; ")
+			failed++
+		}
+		importVerifications = append(importVerifications, importVerification)
+	}
+
+	sort.SliceStable(importVerifications, func(i, j int) bool {
+		return importVerifications[i].ResourceAddress < importVerifications[j].ResourceAddress
+	})
+	return importVerifications, failed > 0
+}
+
+// diagnosticMentionsAddress checks whether the detail of a diagnostic names the address, and not a longer address that
+// starts with it.
+func diagnosticMentionsAddress(diagnostic types.PlanDiagnostic, address string) bool {
+	addressRegex := regexp.MustCompile(`(?:^|[\s"'])` + regexp.QuoteMeta(address) + `(?:$|[\s"',:]|\.(?:\s|$))`)
+	return addressRegex.MatchString(diagnostic.Detail)
+}
+
+func (verifyClient *VerifyClient) classifyImportChange(importChange *types.ImportChange, resourceID string) types.ImportVerification {
+	importVerification := types.ImportVerification{
+		ResourceAddress: importChange.Address,
+		ResourceID:      resourceID,
+		Actions:         importChange.Actions,
+	}
+
+	switch {
+	case slices.Contains(importChange.Actions, "delete"):
+		importVerification.Status = types.ImportStatusReplaced
+		importVerification.Blocker = true
+		importVerification.Reason = fmt.Sprintf("the resource would be destroyed and recreated (%s)", strings.Join(importChange.Actions, ", "))
+	case slices.Contains(importChange.Actions, "update"):
+		importVerification.Status = types.ImportStatusUpdated
+		importVerification.Blocker = verifyClient.FailOnUpdate
+		importVerification.Reason = "the resource would be updated in-place"
+	default:
+		importVerification.Status = types.ImportStatusClean
+	}
+	return importVerification
+}
+
+func normalizeAddress(address string) string {
+	return strings.Join(strings.Fields(address), "")
+}
@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/terraform"
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyClient_Verify(t *testing.T) {
+	hclClient := &mockHclClient{ImportBlocks: []types.ImportBlock{
+		{ID: "/id/clean", To: "azurerm_resource_group.clean"},
+		{ID: "/id/updated", To: `module.hub["a"].azurerm_virtual_network.updated`},
+		{ID: "/id/replaced", To: "azurerm_subnet.replaced"},
+		{ID: "/id/failed", To: "azurerm_subnet.failed"},
+	}}
+	planClient := &mockPlanClient{ImportChanges: []*types.ImportChange{
+		{Address: "azurerm_resource_group.clean", ImportID: "/id/clean", Actions: []string{"no-op"}},
+		{Address: `module.hub["a"].azurerm_virtual_network.updated`, ImportID: "/id/updated", Actions: []string{"update"}},
+		{Address: "azurerm_subnet.replaced", ImportID: "/id/replaced", Actions: []string{"delete", "create"}},
+	}}
+	verifyClient := NewVerifyClient("imports.tf", false, planClient, &mockJsonClient{}, hclClient, logrus.New())
+
+	result, err := verifyClient.Verify(context.Background())
+
+	assert.ErrorIs(t, err, ErrVerifyBlockers)
+	assert.Equal(t, 2, result.Blockers)
+	assert.Equal(t, []string{"verify.json"}, result.OutputFiles)
+
+	statuses := map[string]types.ImportStatus{}
+	for _, importVerification := range result.ImportVerifications {
+		statuses[importVerification.ResourceAddress] = importVerification.Status
+	}
+	assert.Equal(t, map[string]types.ImportStatus{
+		"azurerm_resource_group.clean":                    types.ImportStatusClean,
+		`module.hub["a"].azurerm_virtual_network.updated`: types.ImportStatusUpdated,
+		"azurerm_subnet.replaced":                         types.ImportStatusReplaced,
+		"azurerm_subnet.failed":                           types.ImportStatusFailed,
+	}, statuses)
+}
+
+func TestVerifyClient_Verify_WithFailedPlan(t *testing.T) {
+	hclClient := &mockHclClient{ImportBlocks: []types.ImportBlock{
+		{ID: "/id/rg", To: "azurerm_resource_group.rg"},
+		{ID: "/id/missing", To: "azurerm_resource_group.missing"},
+		{ID: "/id/vnet-hub", To: `module.hub["a"].azurerm_subnet.this["firewall"]`},
+		{ID: "/id/pip", To: "azurerm_public_ip.removed"},
+		{ID: "/id/pip-2", To: "azurerm_public_ip.removed_2"},
+	}}
+	// The diagnostics terraform plan -json reports for a missing resource, a resource ID of the wrong type and an
+	// import target that is not in the configuration
+	planClient := &mockPlanClient{ImportError: &terraform.PlanError{
+		Err: errors.New("terraform plan failed: exit status 1"),
+		Diagnostics: []types.PlanDiagnostic{
+			{
+				Summary: "Cannot import non-existent remote object",
+				Detail:  `While attempting to import an existing object to "azurerm_resource_group.missing", the provider detected that no object exists with the given id.`,
+				Address: "azurerm_resource_group.missing",
+			},
+			{
+				Summary: `parsing "/id/vnet-hub": parsing segment "staticSubnets": parsing the Subnet ID: the segment at position 8 didn't match`,
+				Address: `module.hub["a"].azurerm_subnet.this["firewall"]`,
+			},
+			{
+				Summary: "Configuration for import target does not exist",
+				Detail:  "The configuration for the given import azurerm_public_ip.removed does not exist. All target instances must have an associated configuration to be imported.",
+			},
+		},
+	}}
+	jsonClient := &mockJsonClient{}
+	verifyClient := NewVerifyClient("imports.tf", false, planClient, jsonClient, hclClient, logrus.New())
+
+	result, err := verifyClient.Verify(context.Background())
+
+	assert.ErrorIs(t, err, ErrVerifyBlockers)
+	assert.Equal(t, 3, result.Blockers)
+	assert.Equal(t, map[types.ImportStatus]int{types.ImportStatusFailed: 3, types.ImportStatusUnverified: 2}, result.Counts)
+	assert.Equal(t, []types.ImportVerification{
+		{ResourceAddress: "azurerm_public_ip.removed", ResourceID: "/id/pip", Status: types.ImportStatusFailed, Blocker: true, Reason: "Configuration for import target does not exist"},
+		{ResourceAddress: "azurerm_public_ip.removed_2", ResourceID: "/id/pip-2", Status: types.ImportStatusUnverified, Reason: "the plan failed before this import was verified"},
+		{ResourceAddress: "azurerm_resource_group.missing", ResourceID: "/id/missing", Status: types.ImportStatusFailed, Blocker: true, Reason: "Cannot import non-existent remote object"},
+		{ResourceAddress: "azurerm_resource_group.rg", ResourceID: "/id/rg", Status: types.ImportStatusUnverified, Reason: "the plan failed before this import was verified"},
+		{ResourceAddress: `module.hub["a"].azurerm_subnet.this["firewall"]`, ResourceID: "/id/vnet-hub", Status: types.ImportStatusFailed, Blocker: true, Reason: `parsing "/id/vnet-hub": parsing segment "staticSubnets": parsing the Subnet ID: the segment at position 8 didn't match`},
+	}, result.ImportVerifications)
+	assert.Equal(t, result.ImportVerifications, jsonClient.Exported["verify.json"])
+}
+
+func TestVerifyClient_Verify_WithPlanFailingOutsideImports(t *testing.T) {
+	hclClient := &mockHclClient{ImportBlocks: []types.ImportBlock{{ID: "/id/rg", To: "azurerm_resource_group.rg"}}}
+	planClient := &mockPlanClient{ImportError: &terraform.PlanError{
+		Err:         errors.New("terraform plan failed: exit status 1"),
+		Diagnostics: []types.PlanDiagnostic{{Summary: "Unsupported argument", Detail: `An argument named "sku_nmae" is not expected here.`}},
+	}}
+
+	_, err := NewVerifyClient("imports.tf", false, planClient, &mockJsonClient{}, hclClient, logrus.New()).Verify(context.Background())
+
+	assert.NotErrorIs(t, err, ErrVerifyBlockers)
+	assert.ErrorContains(t, err, "failed to plan the imports: terraform plan failed")
+}
+
+func TestVerifyClient_Verify_WithFailOnUpdate(t *testing.T) {
+	hclClient := &mockHclClient{ImportBlocks: []types.ImportBlock{{ID: "/id/1", To: "azurerm_resource_group.rg"}}}
+	planClient := &mockPlanClient{ImportChanges: []*types.ImportChange{
+		{Address: "azurerm_resource_group.rg", ImportID: "/id/1", Actions: []string{"update"}},
+	}}
+
+	result, err := NewVerifyClient("imports.tf", false, planClient, &mockJsonClient{}, hclClient, logrus.New()).Verify(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Blockers)
+
+	result, err = NewVerifyClient("imports.tf", true, planClient, &mockJsonClient{}, hclClient, logrus.New()).Verify(context.Background())
+	assert.ErrorIs(t, err, ErrVerifyBlockers)
+	assert.Equal(t, 1, result.Blockers)
+}
+
+func TestVerifyClient_classifyImports_IncludesOtherImports(t *testing.T) {
+	verifyClient := &VerifyClient{Logger: logrus.New()}
+
+	importVerifications := verifyClient.classifyImports(
+		[]types.ImportBlock{{ID: "/id/1", To: "azurerm_resource_group.rg"}},
+		[]*types.ImportChange{
+			{Address: "azurerm_resource_group.rg", ImportID: "/id/1", Actions: []string{"no-op"}},
+			{Address: "azurerm_key_vault.kv", ImportID: "/id/2", Actions: []string{"create", "delete"}},
+		})
+
+	assert.Len(t, importVerifications, 2)
+	assert.Equal(t, "azurerm_key_vault.kv", importVerifications[0].ResourceAddress)
+	assert.Equal(t, "/id/2", importVerifications[0].ResourceID)
+	assert.True(t, importVerifications[0].Blocker)
+	assert.Equal(t, types.ImportStatusClean, importVerifications[1].Status)
+}
@@ -2,7 +2,9 @@ package azure
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/azure/terraform-state-importer/types"
 
@@ -17,7 +19,7 @@ import (
 )
 
 type IResourceGraphClient interface {
-	GetResources() ([]*types.GraphResource, error)
+	GetResources(ctx context.Context) ([]*types.GraphResource, error)
 }
 
 type ResourceGraphClient struct {
@@ -26,10 +28,11 @@ type ResourceGraphClient struct {
 	SubscriptionIDs          []*string
 	IgnoreResourceIDPatterns []string
 	ResourceGraphQueries     []types.ResourceGraphQuery
+	Timeout                  time.Duration
 	Logger                   *logrus.Logger
 }
 
-func NewResourceGraphClient(cloudConfiguration string, managementGroupIDs []string, subscriptionIDs []string, ignoreResourceIDPatterns []string, resourceGraphQueries []types.ResourceGraphQuery, logger *logrus.Logger) *ResourceGraphClient {
+func NewResourceGraphClient(cloudConfiguration string, managementGroupIDs []string, subscriptionIDs []string, ignoreResourceIDPatterns []string, resourceGraphQueries []types.ResourceGraphQuery, timeout time.Duration, logger *logrus.Logger) (*ResourceGraphClient, error) {
 	// Convert string slices to pointer slices
 	managementGroupIDsPtr := make([]*string, len(managementGroupIDs))
 	for i, id := range managementGroupIDs {
@@ -38,23 +41,14 @@ func NewResourceGraphClient(cloudConfiguration string, managementGroupIDs []stri
 	subscriptionIDsPtr := make([]*string, len(subscriptionIDs))
 	for i, id := range subscriptionIDs {
 		if id == "" || id == "00000000-0000-0000-0000-000000000000" {
-			logger.Fatalf("Subscription ID is not valid, please update your config file with valid subscription IDs: %s", id)
+			return nil, fmt.Errorf("subscription ID is not valid, please update your config file with valid subscription IDs: %s", id)
 		}
 		subscriptionIDsPtr[i] = &id
 	}
 
-	var cloudConfigurationFinal cloud.Configuration
-	switch cloudConfiguration {
-	case "AzurePublic":
-		cloudConfigurationFinal = cloud.AzurePublic
-	case "AzureUSGovernment":
-		cloudConfigurationFinal = cloud.AzureGovernment
-	case "AzureGovernment":
-		cloudConfigurationFinal = cloud.AzureGovernment
-	case "AzureChina":
-		cloudConfigurationFinal = cloud.AzureChina
-	default:
-		logger.Fatalf("Unsupported cloud specified: %s", cloudConfiguration)
+	cloudConfigurationFinal, err := getCloudConfiguration(cloudConfiguration)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ResourceGraphClient{
@@ -63,14 +57,22 @@ func NewResourceGraphClient(cloudConfiguration string, managementGroupIDs []stri
 		SubscriptionIDs:          subscriptionIDsPtr,
 		IgnoreResourceIDPatterns: ignoreResourceIDPatterns,
 		ResourceGraphQueries:     resourceGraphQueries,
+		Timeout:                  timeout,
 		Logger:                   logger,
-	}
+	}, nil
 }
 
-func (graph *ResourceGraphClient) GetResources() ([]*types.GraphResource, error) {
+// GetResources runs the Resource Graph queries for the configured scopes, giving up when the context is cancelled or the timeout is reached.
+func (graph *ResourceGraphClient) GetResources(ctx context.Context) ([]*types.GraphResource, error) {
+	if graph.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, graph.Timeout)
+		defer cancel()
+	}
+
 	cred, err := azidentity.NewDefaultAzureCredential(nil)
 	if err != nil {
-		graph.Logger.Fatal(err)
+		return nil, fmt.Errorf("failed to create credential: %w", err)
 	}
 
 	resourceMap := make(map[string]*types.GraphResource)
@@ -80,21 +82,24 @@ func (graph *ResourceGraphClient) GetResources() ([]*types.GraphResource, error)
 		guidRegex := regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
 		for _, subscriptionID := range graph.SubscriptionIDs {
 			if subscriptionID == &emptyGuid || !guidRegex.MatchString(*subscriptionID) {
-				graph.Logger.Fatalf("Invalid Subscription ID: %s", *subscriptionID)
-				continue
+				return nil, fmt.Errorf("invalid subscription ID: %s", *subscriptionID)
 			}
 		}
 		graph.Logger.Info("Running graph queries for Subscriptions")
-		graph.getResourcesBySubscriptionID(cred, resourceMap)
+		if err := graph.getResourcesBySubscriptionID(ctx, cred, resourceMap); err != nil {
+			return nil, err
+		}
 	}
 
 	if len(graph.ManagementGroupIDs) > 0 {
 		graph.Logger.Info("Running graph queries for Management Groups")
-		graph.getResourcesByManagementGroupID(cred, resourceMap)
+		if err := graph.getResourcesByManagementGroupID(ctx, cred, resourceMap); err != nil {
+			return nil, err
+		}
 	}
 
 	if len(graph.SubscriptionIDs) == 0 && len(graph.ManagementGroupIDs) == 0 {
-		graph.Logger.Fatal("Subscription IDs or Management Group IDs must be provided")
+		return nil, fmt.Errorf("subscription IDs or management group IDs must be provided")
 	}
 
 	resources := make([]*types.GraphResource, 0, len(resourceMap))
@@ -105,7 +110,7 @@ func (graph *ResourceGraphClient) GetResources() ([]*types.GraphResource, error)
 	return resources, nil
 }
 
-func (graph *ResourceGraphClient) getResourcesByManagementGroupID(cred *azidentity.DefaultAzureCredential, resourceMap map[string]*types.GraphResource) {
+func (graph *ResourceGraphClient) getResourcesByManagementGroupID(ctx context.Context, cred *azidentity.DefaultAzureCredential, resourceMap map[string]*types.GraphResource) error {
 	queryRequest := armresourcegraph.QueryRequest{
 		Options: &armresourcegraph.QueryRequestOptions{
 			AuthorizationScopeFilter: to.Ptr(armresourcegraph.AuthorizationScopeFilterAtScopeAndBelow),
@@ -113,10 +118,10 @@ func (graph *ResourceGraphClient) getResourcesByManagementGroupID(cred *azidenti
 		ManagementGroups: graph.ManagementGroupIDs,
 	}
 
-	graph.getResources(types.ResourceGraphQueryScopeManagementGroup, queryRequest, cred, resourceMap)
+	return graph.getResources(ctx, types.ResourceGraphQueryScopeManagementGroup, queryRequest, cred, resourceMap)
 }
 
-func (graph *ResourceGraphClient) getResourcesBySubscriptionID(cred *azidentity.DefaultAzureCredential, resourceMap map[string]*types.GraphResource) {
+func (graph *ResourceGraphClient) getResourcesBySubscriptionID(ctx context.Context, cred *azidentity.DefaultAzureCredential, resourceMap map[string]*types.GraphResource) error {
 	queryRequest := armresourcegraph.QueryRequest{
 		Options: &armresourcegraph.QueryRequestOptions{
 			AuthorizationScopeFilter: to.Ptr(armresourcegraph.AuthorizationScopeFilterAtScopeAndBelow),
@@ -124,10 +129,10 @@ func (graph *ResourceGraphClient) getResourcesBySubscriptionID(cred *azidentity.
 		Subscriptions: graph.SubscriptionIDs,
 	}
 
-	graph.getResources(types.ResourceGraphQueryScopeSubscription, queryRequest, cred, resourceMap)
+	return graph.getResources(ctx, types.ResourceGraphQueryScopeSubscription, queryRequest, cred, resourceMap)
 }
 
-func (graph *ResourceGraphClient) getResources(scope types.ResourceGraphQueryScope, queryRequest armresourcegraph.QueryRequest, cred *azidentity.DefaultAzureCredential, resourceMap map[string]*types.GraphResource) {
+func (graph *ResourceGraphClient) getResources(ctx context.Context, scope types.ResourceGraphQueryScope, queryRequest armresourcegraph.QueryRequest, cred *azidentity.DefaultAzureCredential, resourceMap map[string]*types.GraphResource) error {
 	for _, query := range graph.ResourceGraphQueries {
 		if query.Scope != scope {
 			graph.Logger.Debugf("Skipping query %s for scope %s", query.Name, scope)
@@ -137,21 +142,19 @@ func (graph *ResourceGraphClient) getResources(scope types.ResourceGraphQuerySco
 		graph.Logger.Infof("Running Resource Graph Query: %s", query.Name)
 		graph.Logger.Tracef("Query: %s", query.Query)
 
-		opts := azcore.ClientOptions{Cloud: cloud.AzurePublic}
+		opts := azcore.ClientOptions{Cloud: graph.Cloud}
 		resourcesClient, err := armresourcegraph.NewClient(cred, &arm.ClientOptions{
 			ClientOptions: opts,
 		})
 		if err != nil {
-			graph.Logger.Fatal(err)
+			return fmt.Errorf("failed to create Resource Graph client: %w", err)
 		}
 
-		ctx := context.Background()
-
 		queryRequest.Query = to.Ptr(query.Query)
 
 		res, err := resourcesClient.Resources(ctx, queryRequest, nil)
 		if err != nil {
-			graph.Logger.Fatal(err)
+			return fmt.Errorf("failed to run Resource Graph query %s: %w", query.Name, err)
 		}
 
 		results := res.QueryResponse.Data.([]any)
@@ -184,12 +187,45 @@ func (graph *ResourceGraphClient) getResources(scope types.ResourceGraphQuerySco
 			}
 			graph.Logger.Tracef("Adding Resource ID: %s", resourceID)
 			resourceResult := types.GraphResource{
-				ID:       resourceID,
-				Type:     resource["type"].(string),
-				Name:     resource["name"].(string),
-				Location: resource["location"].(string),
+				ID:             resourceID,
+				Type:           resource["type"].(string),
+				Name:           resource["name"].(string),
+				Location:       resource["location"].(string),
+				SubscriptionID: ParseSubscriptionID(resourceID),
+				ResourceGroup:  ParseResourceGroupName(resourceID),
+				Tags:           map[string]string{},
+				Properties:     resource,
+			}
+			if subscriptionID, ok := resource["subscriptionId"].(string); ok && subscriptionID != "" {
+				resourceResult.SubscriptionID = subscriptionID
+			}
+			if resourceGroup, ok := resource["resourceGroup"].(string); ok && resourceGroup != "" {
+				resourceResult.ResourceGroup = resourceGroup
+			}
+			if tags, ok := resource["tags"].(map[string]any); ok {
+				for key, value := range tags {
+					resourceResult.Tags[key] = fmt.Sprint(value)
+				}
 			}
 			resourceMap[resourceID] = &resourceResult
 		}
 	}
+	return nil
+}
+
+func getCloudConfiguration(cloudConfiguration string) (cloud.Configuration, error) {
+	var cloudConfigurationFinal cloud.Configuration
+	switch cloudConfiguration {
+	case "AzurePublic":
+		cloudConfigurationFinal = cloud.AzurePublic
+	case "AzureUSGovernment":
+		cloudConfigurationFinal = cloud.AzureGovernment
+	case "AzureGovernment":
+		cloudConfigurationFinal = cloud.AzureGovernment
+	case "AzureChina":
+		cloudConfigurationFinal = cloud.AzureChina
+	default:
+		return cloud.Configuration{}, fmt.Errorf("unsupported cloud specified: %s", cloudConfiguration)
+	}
+	return cloudConfigurationFinal, nil
 }
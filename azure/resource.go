@@ -0,0 +1,78 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+type IResourceClient interface {
+	GetResource(ctx context.Context, resourceID string, apiVersion string) (map[string]any, error)
+}
+
+type ResourceClient struct {
+	Cloud  cloud.Configuration
+	Logger *logrus.Logger
+	client *arm.Client
+}
+
+func NewResourceClient(cloudConfiguration string, logger *logrus.Logger) (*ResourceClient, error) {
+	cloudConfigurationFinal, err := getCloudConfiguration(cloudConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceClient{
+		Cloud:  cloudConfigurationFinal,
+		Logger: logger,
+	}, nil
+}
+
+func (resourceClient *ResourceClient) GetResource(ctx context.Context, resourceID string, apiVersion string) (map[string]any, error) {
+	if resourceClient.client == nil {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create credential: %w", err)
+		}
+
+		client, err := arm.NewClient("terraform-state-importer", "v1.0.0", cred, &arm.ClientOptions{
+			ClientOptions: azcore.ClientOptions{Cloud: resourceClient.Cloud},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create resource manager client: %w", err)
+		}
+		resourceClient.client = client
+	}
+
+	request, err := runtime.NewRequest(ctx, http.MethodGet, runtime.JoinPaths(resourceClient.client.Endpoint(), resourceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", resourceID, err)
+	}
+	query := url.Values{}
+	query.Set("api-version", apiVersion)
+	request.Raw().URL.RawQuery = query.Encode()
+
+	resourceClient.Logger.Tracef("Getting Resource: %s, API Version: %s", resourceID, apiVersion)
+
+	response, err := resourceClient.client.Pipeline().Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource %s: %w", resourceID, err)
+	}
+	if !runtime.HasStatusCode(response, http.StatusOK) {
+		return nil, runtime.NewResponseError(response)
+	}
+
+	resource := map[string]any{}
+	if err := runtime.UnmarshalAsJSON(response, &resource); err != nil {
+		return nil, fmt.Errorf("failed to read resource %s: %w", resourceID, err)
+	}
+	return resource, nil
+}
@@ -0,0 +1,38 @@
+package azure
+
+import (
+	"strings"
+)
+
+// NormalizeResourceID lower cases a resource ID and removes any API version query and trailing or duplicate slashes,
+// so IDs from Resource Graph, the Terraform plan and user input can be compared.
+func NormalizeResourceID(resourceID string) string {
+	resourceID = strings.ToLower(strings.TrimSpace(resourceID))
+	if index := strings.Index(resourceID, "?"); index >= 0 {
+		resourceID = resourceID[:index]
+	}
+	for strings.Contains(resourceID, "//") {
+		resourceID = strings.ReplaceAll(resourceID, "//", "/")
+	}
+	return strings.TrimSuffix(resourceID, "/")
+}
+
+// ParseSubscriptionID returns the subscription ID segment of a resource ID, or an empty string if there is none.
+func ParseSubscriptionID(resourceID string) string {
+	return getResourceIDSegment(resourceID, "subscriptions")
+}
+
+// ParseResourceGroupName returns the resource group segment of a resource ID, or an empty string if there is none.
+func ParseResourceGroupName(resourceID string) string {
+	return getResourceIDSegment(resourceID, "resourcegroups")
+}
+
+func getResourceIDSegment(resourceID string, key string) string {
+	segments := strings.Split(strings.Trim(resourceID, "/"), "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if strings.EqualFold(segments[i], key) {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeResourceID(t *testing.T) {
+	tests := []struct {
+		name       string
+		resourceID string
+		expected   string
+	}{
+		{"lower cases", "/subscriptions/123/resourceGroups/RG1", "/subscriptions/123/resourcegroups/rg1"},
+		{"removes trailing slash", "/subscriptions/123/resourceGroups/rg1/", "/subscriptions/123/resourcegroups/rg1"},
+		{"removes api version", "/subscriptions/123/resourceGroups/rg1?api-version=2021-04-01", "/subscriptions/123/resourcegroups/rg1"},
+		{"removes duplicate slashes", "/subscriptions/123//resourceGroups/rg1", "/subscriptions/123/resourcegroups/rg1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, NormalizeResourceID(test.resourceID))
+		})
+	}
+}
+
+func TestParseResourceIDSegments(t *testing.T) {
+	resourceID := "/subscriptions/123/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet1"
+
+	assert.Equal(t, "123", ParseSubscriptionID(resourceID))
+	assert.Equal(t, "rg1", ParseResourceGroupName(resourceID))
+	assert.Equal(t, "", ParseResourceGroupName("/providers/Microsoft.Management/managementGroups/alz"))
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/azure/terraform-state-importer/csv"
+	"github.com/azure/terraform-state-importer/filepathparser"
+)
+
+// convertCmd represents the convert-resolutions command
+var convertCmd = &cobra.Command{
+	Use:   "convert-resolutions",
+	Short: "Convert resolved issues between the CSV, XLSX and resolutions.yaml formats",
+	Long: `The convert-resolutions command reads a resolved issues file and writes the resolutions in another
+format to the working folder, as issues.csv, issues.xlsx or resolutions.yaml.
+
+A CSV or XLSX file is converted to YAML and a YAML or JSON file is converted to CSV, unless --to is set.
+Every issue in the input must have an action, as it does when it is passed to run with --issuesCsv.
+
+Examples:
+  # Convert a resolved CSV file to resolutions.yaml for code review
+  terraform-state-importer convert-resolutions --input ./issues.csv
+
+  # Convert resolutions.yaml to an Excel workbook
+  terraform-state-importer convert-resolutions --input ./resolutions.yaml --to xlsx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configureLogging(cmd)
+
+		inputPath, _ := cmd.Flags().GetString("input")
+		if inputPath == "" {
+			log.Fatal("The --input flag is required")
+		}
+		inputPath, err := filepathparser.ParsePath(inputPath)
+		if err != nil {
+			log.Fatalf("Error getting input path: %v", err)
+		}
+		workingFolderPath, _ := cmd.Flags().GetString("workingFolderPath")
+		workingFolderPath, err = filepathparser.ParsePath(workingFolderPath)
+		if err != nil {
+			log.Fatalf("Error getting working folder path: %v", err)
+		}
+
+		inputClient, err := newIssueCsvClient(workingFolderPath, inputPath, "")
+		if err != nil {
+			log.Fatalf("Error creating issues client: %v", err)
+		}
+
+		outputFormat, _ := cmd.Flags().GetString("to")
+		outputFormat = strings.ToLower(outputFormat)
+		if outputFormat == "" {
+			outputFormat = "yaml"
+			if csv.IsResolutionsFile(inputPath) {
+				outputFormat = "csv"
+			}
+		}
+		// The output is written by format, so the input path is not passed on
+		outputClient, err := newIssueCsvClient(workingFolderPath, "", outputFormat)
+		if err != nil {
+			log.Fatalf("Error creating issues client: %v", err)
+		}
+
+		resolvedIssues, err := inputClient.Import()
+		if err != nil {
+			log.Fatalf("Error reading resolved issues: %v", err)
+		}
+		if csv.IsResolutionsFile(inputPath) && outputFormat != "yaml" {
+			issues, skippedKeys := csv.KeyByIssueID(*resolvedIssues)
+			if len(skippedKeys) > 0 {
+				log.Warnf("Patterns and issue IDs resolved with Ignore cannot be written as %s and are left out: %s", outputFormat, strings.Join(skippedKeys, ", "))
+			}
+			resolvedIssues = &issues
+		}
+		outputPath, err := outputClient.Export(*resolvedIssues)
+		if err != nil {
+			log.Fatalf("Error writing resolved issues: %v", err)
+		}
+		log.Infof("Converted %d resolved issues from %s to %s", len(*resolvedIssues), inputPath, outputPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringP("input", "i", "", "Resolved issues file to convert, a CSV, XLSX or resolutions YAML/JSON file")
+	convertCmd.Flags().StringP("workingFolderPath", "w", ".", "Working folder path to write the converted file to")
+	convertCmd.Flags().String("to", "", "Format to convert to, csv, xlsx or yaml (yaml for CSV and XLSX input, csv otherwise)")
+}
@@ -4,6 +4,15 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
 	"github.com/sirupsen/logrus"
 
 	"github.com/azure/terraform-state-importer/analyzer"
@@ -40,22 +49,15 @@ Examples:
   terraform-state-importer run --terraformModulePath ./my-module --config ./config.yaml --issuesCsv ./resolved-issues.csv
 
   # Generate text plan only
-  terraform-state-importer run --planAsTextOnly --terraformModulePath ./my-module`,
-	Run: func(cmd *cobra.Command, args []string) {
-		logVerbosity, _ := cmd.Flags().GetString("verbosity")
-		logLevel, err := logrus.ParseLevel(logVerbosity)
-		if err != nil {
-			log.Fatalf("Invalid log level: %s", logVerbosity)
-		}
-		log.SetLevel(logLevel)
-		log.SetFormatter(&logrus.TextFormatter{})
-		if viper.GetBool("structuredLogs") {
-			log.SetFormatter(&logrus.JSONFormatter{})
-		}
+  terraform-state-importer run --planAsTextOnly --terraformModulePath ./my-module
 
-		for key, value := range viper.GetViper().AllSettings() {
-			log.Debugf("Command Flag: %s = %s", key, value)
-		}
+  # Compare live Azure attributes with the planned values
+  terraform-state-importer run --compareOnly --terraformModulePath ./my-module --config ./config.yaml
+
+  # Check the resolved issues against the current run without generating import blocks
+  terraform-state-importer run --dryRun --terraformModulePath ./my-module --config ./config.yaml --issuesCsv ./resolved-issues.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configureLogging(cmd)
 
 		workingFolderPath, err := filepathparser.ParsePath(viper.GetString("workingFolderPath"))
 		if err != nil {
@@ -67,6 +69,7 @@ Examples:
 		}
 
 		planAsTextOnly, _ := cmd.Flags().GetBool("planAsTextOnly")
+		compareOnly, _ := cmd.Flags().GetBool("compareOnly")
 
 		resourceGraphQueries := []types.ResourceGraphQuery{}
 		resourceGraphQueriesRaw := viper.Get("resourceGraphQueries").([]any)
@@ -161,6 +164,130 @@ Examples:
 			}
 		}
 
+		driftIgnoreRules := []types.DriftIgnoreRule{}
+		if viper.InConfig("driftIgnoreRules") {
+			driftIgnoreRulesRaw := viper.Get("driftIgnoreRules").([]any)
+			for _, rawDriftIgnoreRule := range driftIgnoreRulesRaw {
+				driftIgnoreRuleMap := rawDriftIgnoreRule.(map[string]any)
+
+				driftIgnoreRule := types.DriftIgnoreRule{
+					AttributePath: driftIgnoreRuleMap["attributepath"].(string),
+					MatchType:     types.DriftIgnoreMatchTypeGlob,
+				}
+				if _, ok := driftIgnoreRuleMap["type"]; ok {
+					driftIgnoreRule.Type = driftIgnoreRuleMap["type"].(string)
+				}
+				if _, ok := driftIgnoreRuleMap["subtype"]; ok {
+					driftIgnoreRule.SubType = driftIgnoreRuleMap["subtype"].(string)
+				}
+				if _, ok := driftIgnoreRuleMap["matchtype"]; ok {
+					driftIgnoreRule.MatchType = types.DriftIgnoreMatchType(driftIgnoreRuleMap["matchtype"].(string))
+				}
+				if _, ok := driftIgnoreRuleMap["reason"]; ok {
+					driftIgnoreRule.Reason = driftIgnoreRuleMap["reason"].(string)
+				}
+
+				if !driftIgnoreRule.MatchType.IsValidDriftIgnoreMatchType() {
+					log.Fatalf("Invalid drift ignore rule match type %s for attribute path %s, must be Glob or Regex", driftIgnoreRule.MatchType, driftIgnoreRule.AttributePath)
+				}
+				driftIgnoreRules = append(driftIgnoreRules, driftIgnoreRule)
+			}
+		}
+
+		resourceTypeMappings := []types.ResourceTypeMapping{}
+		if viper.InConfig("resourceTypeMappings") {
+			resourceTypeMappingsRaw := viper.Get("resourceTypeMappings").([]any)
+			for _, rawResourceTypeMapping := range resourceTypeMappingsRaw {
+				resourceTypeMappingMap := rawResourceTypeMapping.(map[string]any)
+
+				subType := ""
+				if _, ok := resourceTypeMappingMap["subtype"]; ok {
+					subType = resourceTypeMappingMap["subtype"].(string)
+				}
+				resourceTypeMappings = append(resourceTypeMappings, types.ResourceTypeMapping{
+					Type:              resourceTypeMappingMap["type"].(string),
+					SubType:           subType,
+					AzureResourceType: resourceTypeMappingMap["azureresourcetype"].(string),
+				})
+			}
+		}
+
+		variableMappings := []types.VariableMapping{}
+		if viper.InConfig("variableMappings") {
+			variableMappingsRaw := viper.Get("variableMappings").([]any)
+			for _, rawVariableMapping := range variableMappingsRaw {
+				variableMappingMap := rawVariableMapping.(map[string]any)
+
+				variableMapping := types.VariableMapping{
+					Variable:      variableMappingMap["variable"].(string),
+					AttributePath: variableMappingMap["attributepath"].(string),
+				}
+				if _, ok := variableMappingMap["type"]; ok {
+					variableMapping.Type = variableMappingMap["type"].(string)
+				}
+				if _, ok := variableMappingMap["subtype"]; ok {
+					variableMapping.SubType = variableMappingMap["subtype"].(string)
+				}
+				if _, ok := variableMappingMap["addresspattern"]; ok {
+					variableMapping.AddressPattern = variableMappingMap["addresspattern"].(string)
+				}
+				variableMappings = append(variableMappings, variableMapping)
+			}
+		}
+
+		// Picking candidates by score is opt-in, so every address with more than one candidate is reviewed by default
+		candidateScoring := types.CandidateScoring{Margin: 15}
+		if viper.IsSet("candidateScoring.threshold") {
+			candidateScoring.Threshold = viper.GetInt("candidateScoring.threshold")
+		}
+		if viper.IsSet("candidateScoring.margin") {
+			candidateScoring.Margin = viper.GetInt("candidateScoring.margin")
+		}
+
+		resolutionRules := []types.ResolutionRule{}
+		if viper.InConfig("resolutionRules") {
+			resolutionRulesRaw := viper.Get("resolutionRules").([]any)
+			for _, rawResolutionRule := range resolutionRulesRaw {
+				resolutionRuleMap := rawResolutionRule.(map[string]any)
+
+				resolutionRule := types.ResolutionRule{
+					Name:       resolutionRuleMap["name"].(string),
+					ActionType: types.ActionType(resolutionRuleMap["action"].(string)),
+				}
+				if _, ok := resolutionRuleMap["type"]; ok {
+					resolutionRule.Type = resolutionRuleMap["type"].(string)
+				}
+				if _, ok := resolutionRuleMap["issuetype"]; ok {
+					resolutionRule.IssueType = types.IssueType(resolutionRuleMap["issuetype"].(string))
+				}
+				if _, ok := resolutionRuleMap["namepattern"]; ok {
+					resolutionRule.NamePattern = resolutionRuleMap["namepattern"].(string)
+				}
+				if _, ok := resolutionRuleMap["addresspattern"]; ok {
+					resolutionRule.AddressPattern = resolutionRuleMap["addresspattern"].(string)
+				}
+				if _, ok := resolutionRuleMap["idpattern"]; ok {
+					resolutionRule.IDPattern = resolutionRuleMap["idpattern"].(string)
+				}
+				if _, ok := resolutionRuleMap["candidateidpattern"]; ok {
+					resolutionRule.CandidateIDPattern = resolutionRuleMap["candidateidpattern"].(string)
+				}
+
+				if resolutionRule.IssueType != "" && !resolutionRule.IssueType.IsValidIssueType() {
+					log.Fatalf("Invalid issue type %s for resolution rule %s", resolutionRule.IssueType, resolutionRule.Name)
+				}
+				if !resolutionRule.IsValidAction() {
+					log.Fatalf("Invalid action %s for resolution rule %s, use Ignore, Use with issueType MultipleResourceIDs and a candidateIDPattern, or Destroy or Forget with issueType UnusedResourceID", resolutionRule.ActionType, resolutionRule.Name)
+				}
+				resolutionRules = append(resolutionRules, resolutionRule)
+			}
+		}
+
+		splitImportsBy := types.SplitImportsBy(viper.GetString("splitImportsBy"))
+		if !splitImportsBy.IsValidSplitImportsBy() {
+			log.Fatalf("Invalid splitImportsBy %s, must be module, subscription, resourceGroup or resourceType", splitImportsBy)
+		}
+
 		deleteCommands := []types.DeleteCommand{}
 		if viper.InConfig("deleteCommands") {
 			deleteCommandsRaw := viper.Get("deleteCommands").([]any)
@@ -178,55 +305,108 @@ Examples:
 			cloud = "AzurePublic"
 		}
 
-		resourceGraphClient := azure.NewResourceGraphClient(
+		resourceGraphClient, err := azure.NewResourceGraphClient(
 			cloud,
 			viper.GetStringSlice("managementGroupIDs"),
 			viper.GetStringSlice("subscriptionIDs"),
 			viper.GetStringSlice("ignoreResourceIDPatterns"),
 			resourceGraphQueries,
+			viper.GetDuration("graphTimeout"),
+			log,
+		)
+		if err != nil {
+			log.Fatalf("Error creating Resource Graph client: %v", err)
+		}
+
+		resourceClient, err := azure.NewResourceClient(
+			cloud,
 			log,
 		)
+		if err != nil {
+			log.Fatalf("Error creating resource client: %v", err)
+		}
 
 		jsonClient := json.NewJsonClient(
 			workingFolderPath,
 			log,
 		)
 
-		planClient := terraform.NewPlanClient(
-			terraformModulePath,
+		// In isolated mode the module is planned from a copy in the working folder, so nothing is written to the module itself
+		planModulePath := terraformModulePath
+		pluginCacheDir := ""
+		if viper.GetBool("isolatedPlan") {
+			scratchPath := getScratchPath(workingFolderPath)
+			isolatedRootPath, isolatedModulePath, err := getIsolatedModulePath(workingFolderPath, terraformModulePath)
+			if err != nil {
+				log.Fatalf("Error getting isolated module path: %v", err)
+			}
+			planModulePath = isolatedModulePath
+			pluginCacheDir = getPluginCacheDir(workingFolderPath)
+
+			isolatedCopyPath := getIsolatedCopyPath(workingFolderPath)
+			log.Infof("Copying %s to %s", isolatedRootPath, isolatedCopyPath)
+			if err := terraform.CopyModule(isolatedRootPath, isolatedCopyPath, scratchPath); err != nil {
+				log.Fatalf("Error copying module for isolated plan: %v", err)
+			}
+			if err := terraform.CheckLocalModuleSources(isolatedCopyPath); err != nil {
+				log.Fatalf("Error checking module sources for isolated plan: %v, use --isolatedRoot to copy a folder that contains them", err)
+			}
+		}
+
+		planClient, err := terraform.NewPlanClient(
+			planModulePath,
 			workingFolderPath,
 			viper.GetString("planSubscriptionID"),
 			viper.GetStringSlice("ignoreResourceTypePatterns"),
 			viper.GetBool("skipInitPlanShow"),
 			viper.GetBool("skipInitOnly"),
 			viper.GetBool("skipInitUpgrade"),
+			viper.GetBool("keepBackend"),
+			pluginCacheDir,
+			viper.GetString("stateFile"),
 			propertyMappings,
 			nameFormats,
+			driftIgnoreRules,
+			resourceTypeMappings,
+			viper.GetDuration("planTimeout"),
 			jsonClient,
 			log,
 		)
+		if err != nil {
+			log.Fatalf("Error creating plan client: %v", err)
+		}
+
+		ctx, stop := newSignalContext()
+		defer stop()
 
 		if planAsTextOnly {
-			planClient.PlanAsText()
+			if err := planClient.PlanAsText(ctx); err != nil {
+				log.Fatalf("Error generating text plan: %v", err)
+			}
 			return
 		}
 
-		issueCsvClient := csv.NewIssueCsvClient(
-			workingFolderPath,
-			viper.GetString("issuesCsv"),
-			log,
-		)
+		issueCsvClient, err := newIssueCsvClient(workingFolderPath, viper.GetString("issuesCsv"), viper.GetString("issuesFormat"))
+		if err != nil {
+			log.Fatalf("Error creating issues client: %v", err)
+		}
 
 		hclClient := hcl.NewHclClient(
-			terraformModulePath,
+			planModulePath,
 			deleteCommands,
+			viper.GetBool("importForEach"),
 			log,
 		)
 
 		mappingClient := analyzer.NewMappingClient(
 			workingFolderPath,
 			viper.GetString("issuesCsv") != "",
+			variableMappings,
+			candidateScoring,
+			resolutionRules,
+			splitImportsBy,
 			resourceGraphClient,
+			resourceClient,
 			planClient,
 			issueCsvClient,
 			jsonClient,
@@ -234,10 +414,148 @@ Examples:
 			log,
 		)
 
-		mappingClient.Map()
+		if compareOnly {
+			if _, err := mappingClient.Compare(ctx); err != nil {
+				log.Fatalf("Error comparing attributes: %v", err)
+			}
+			return
+		}
+
+		if viper.GetBool("dryRun") {
+			validationResult, err := mappingClient.ValidateResolutions(ctx)
+			if errors.Is(err, analyzer.ErrInvalidResolutions) {
+				printValidationReport(validationResult, viper.GetString("reportFormat"))
+				stop()
+				os.Exit(validateExitCodeProblems)
+			}
+			if err != nil {
+				log.Fatalf("Error validating resolutions: %v", err)
+			}
+			printValidationReport(validationResult, viper.GetString("reportFormat"))
+			return
+		}
+
+		result, err := mappingClient.Map(ctx)
+		if errors.Is(err, analyzer.ErrMappingErrors) {
+			log.Fatalf("Found %d errors during mapping: %v", len(result.Errors), result.Errors)
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Fatalf("Cancelled before mapping completed: %v", err)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Fatalf("Timed out before mapping completed, increase graphTimeout or planTimeout: %v", err)
+		}
+		if err != nil {
+			log.Fatalf("Error mapping resources: %v", err)
+		}
+
+		if planModulePath != terraformModulePath {
+			generatedFilePaths := []string{}
+			for _, outputFile := range result.OutputFiles {
+				if filepath.Dir(outputFile) == planModulePath {
+					generatedFilePaths = append(generatedFilePaths, outputFile)
+				}
+			}
+			if len(generatedFilePaths) == 0 {
+				return
+			}
+			if !viper.GetBool("writeBack") {
+				log.Infof("Generated files are in the isolated module copy %s, use --writeBack to copy them to %s", planModulePath, terraformModulePath)
+				return
+			}
+			// Files written back by an earlier run, such as a moved.tf that is no longer needed, are removed first
+			if err := hcl.NewHclClient(terraformModulePath, nil, false, log).CleanFiles(result.CleanedFiles); err != nil {
+				log.Fatalf("Error removing previously generated files from the module: %v", err)
+			}
+			writtenFilePaths, err := terraform.CopyFiles(generatedFilePaths, terraformModulePath)
+			if err != nil {
+				log.Fatalf("Error writing generated files back to the module: %v", err)
+			}
+			for _, writtenFilePath := range writtenFilePaths {
+				log.Infof("Wrote %s", writtenFilePath)
+			}
+		}
 	},
 }
 
+func configureLogging(cmd *cobra.Command) {
+	logVerbosity, _ := cmd.Flags().GetString("verbosity")
+	logLevel, err := logrus.ParseLevel(logVerbosity)
+	if err != nil {
+		log.Fatalf("Invalid log level: %s", logVerbosity)
+	}
+	log.SetLevel(logLevel)
+	log.SetFormatter(&logrus.TextFormatter{})
+	if viper.GetBool("structuredLogs") {
+		log.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	for key, value := range viper.GetViper().AllSettings() {
+		log.Debugf("Command Flag: %s = %s", key, value)
+	}
+}
+
+// newSignalContext cancels on the first interrupt so terraform can stop and the backend override file is removed,
+// a second interrupt exits immediately.
+func newSignalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+func getScratchPath(workingFolderPath string) string {
+	return filepath.Join(workingFolderPath, ".terraform-state-importer")
+}
+
+// newIssueCsvClient returns the client for the issues format, a workbook or resolutions file passed with --issuesCsv
+// is always read in its own format.
+func newIssueCsvClient(workingFolderPath string, issuesCsvPath string, issuesFormat string) (csv.IIssueCsvClient, error) {
+	if strings.EqualFold(filepath.Ext(issuesCsvPath), ".xlsx") {
+		issuesFormat = "xlsx"
+	}
+	if csv.IsResolutionsFile(issuesCsvPath) {
+		issuesFormat = "yaml"
+	}
+	switch strings.ToLower(issuesFormat) {
+	case "", "csv":
+		return csv.NewIssueCsvClient(workingFolderPath, issuesCsvPath, log), nil
+	case "xlsx":
+		return csv.NewIssueXlsxClient(workingFolderPath, issuesCsvPath, log), nil
+	case "yaml":
+		return csv.NewResolutionsClient(workingFolderPath, issuesCsvPath, log), nil
+	default:
+		return nil, fmt.Errorf("invalid issues format %s, expected csv, xlsx or yaml", issuesFormat)
+	}
+}
+
+func getIsolatedCopyPath(workingFolderPath string) string {
+	return filepath.Join(getScratchPath(workingFolderPath), "module")
+}
+
+// getIsolatedModulePath returns the folder copied by --isolatedPlan, which is the module unless --isolatedRoot is set,
+// and the path of the module inside the copy.
+func getIsolatedModulePath(workingFolderPath string, terraformModulePath string) (string, string, error) {
+	isolatedRootPath := terraformModulePath
+	if viper.GetString("isolatedRoot") != "" {
+		var err error
+		if isolatedRootPath, err = filepathparser.ParsePath(viper.GetString("isolatedRoot")); err != nil {
+			return "", "", fmt.Errorf("failed to get isolated root path: %w", err)
+		}
+	}
+	relativePath, err := filepath.Rel(isolatedRootPath, terraformModulePath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("isolated root %s does not contain the module %s", isolatedRootPath, terraformModulePath)
+	}
+	return isolatedRootPath, filepath.Join(getIsolatedCopyPath(workingFolderPath), relativePath), nil
+}
+
+func getPluginCacheDir(workingFolderPath string) string {
+	return filepath.Join(getScratchPath(workingFolderPath), "plugin-cache")
+}
+
 func init() {
 	rootCmd.AddCommand(runCmd)
 
@@ -245,8 +563,14 @@ func init() {
 	viper.BindPFlag("terraformModulePath", runCmd.PersistentFlags().Lookup("terraformModulePath"))
 	runCmd.PersistentFlags().StringP("workingFolderPath", "w", ".", "Working folder path to use")
 	viper.BindPFlag("workingFolderPath", runCmd.PersistentFlags().Lookup("workingFolderPath"))
-	runCmd.PersistentFlags().StringP("issuesCsv", "c", "", "CSV File path to use")
+	runCmd.PersistentFlags().StringP("issuesCsv", "c", "", "CSV, XLSX or resolutions YAML/JSON file path to use")
 	viper.BindPFlag("issuesCsv", runCmd.PersistentFlags().Lookup("issuesCsv"))
+	runCmd.PersistentFlags().Bool("dryRun", false, "Validate the resolved issues file against the current run without generating any files")
+	viper.BindPFlag("dryRun", runCmd.PersistentFlags().Lookup("dryRun"))
+	runCmd.PersistentFlags().String("reportFormat", "text", "Format of the validation report printed by --dryRun, text or json")
+	viper.BindPFlag("reportFormat", runCmd.PersistentFlags().Lookup("reportFormat"))
+	runCmd.PersistentFlags().String("issuesFormat", "csv", "Format to write the issues in, csv, xlsx or yaml (an .xlsx, .yaml or .json file passed with --issuesCsv is read in its own format)")
+	viper.BindPFlag("issuesFormat", runCmd.PersistentFlags().Lookup("issuesFormat"))
 	runCmd.PersistentFlags().BoolP("skipInitPlanShow", "x", false, "Skip init, plan, and show steps")
 	viper.BindPFlag("skipInitPlanShow", runCmd.PersistentFlags().Lookup("skipInitPlanShow"))
 	runCmd.PersistentFlags().BoolP("skipInitOnly", "k", false, "Skip init step")
@@ -255,6 +579,26 @@ func init() {
 	viper.BindPFlag("skipInitUpgrade", runCmd.PersistentFlags().Lookup("skipInitUpgrade"))
 	runCmd.PersistentFlags().BoolP("planAsTextOnly", "p", false, "Run the tool to generate a textual plan only")
 	viper.BindPFlag("planAsTextOnly", runCmd.PersistentFlags().Lookup("planAsTextOnly"))
+	runCmd.PersistentFlags().BoolP("compareOnly", "m", false, "Run the tool to compare live Azure attributes with the planned values only")
+	viper.BindPFlag("compareOnly", runCmd.PersistentFlags().Lookup("compareOnly"))
 	runCmd.PersistentFlags().StringP("planSubscriptionID", "s", "", "Subscription ID to use for terraform plan if not using the az cli subscription ID")
 	viper.BindPFlag("planSubscriptionID", runCmd.PersistentFlags().Lookup("planSubscriptionID"))
+	runCmd.PersistentFlags().Bool("keepBackend", false, "Plan with the module backend instead of a local backend, without taking the state lock")
+	viper.BindPFlag("keepBackend", runCmd.PersistentFlags().Lookup("keepBackend"))
+	runCmd.PersistentFlags().Bool("isolatedPlan", false, "Plan a copy of the module in the working folder instead of the module itself")
+	viper.BindPFlag("isolatedPlan", runCmd.PersistentFlags().Lookup("isolatedPlan"))
+	runCmd.PersistentFlags().String("isolatedRoot", "", "With isolatedPlan, copy this folder instead of the module, so local module sources outside the module resolve (defaults to the module path)")
+	viper.BindPFlag("isolatedRoot", runCmd.PersistentFlags().Lookup("isolatedRoot"))
+	runCmd.PersistentFlags().String("stateFile", "", "Terraform state file, or terraform show -json output, used to move resources whose address changed (the prior state of the plan is used by default)")
+	viper.BindPFlag("stateFile", runCmd.PersistentFlags().Lookup("stateFile"))
+	runCmd.PersistentFlags().Bool("importForEach", false, "Group the imports of for_each instances of the same resource into one import block with for_each (Terraform 1.7 or later)")
+	viper.BindPFlag("importForEach", runCmd.PersistentFlags().Lookup("importForEach"))
+	runCmd.PersistentFlags().String("splitImportsBy", "", "Split the import blocks into imports.<group>.tf files by module, subscription, resourceGroup or resourceType, with a manifest in imports_manifest.json")
+	viper.BindPFlag("splitImportsBy", runCmd.PersistentFlags().Lookup("splitImportsBy"))
+	runCmd.PersistentFlags().Bool("writeBack", false, "With isolatedPlan, copy the generated imports, moved, removed and destroy files back to the module")
+	viper.BindPFlag("writeBack", runCmd.PersistentFlags().Lookup("writeBack"))
+	runCmd.PersistentFlags().Duration("graphTimeout", 0, "Timeout for the Resource Graph queries, e.g. 5m (no timeout by default)")
+	viper.BindPFlag("graphTimeout", runCmd.PersistentFlags().Lookup("graphTimeout"))
+	runCmd.PersistentFlags().Duration("planTimeout", 0, "Timeout for terraform init, plan and show, e.g. 30m (no timeout by default)")
+	viper.BindPFlag("planTimeout", runCmd.PersistentFlags().Lookup("planTimeout"))
 }
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/azure/terraform-state-importer/analyzer"
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// Exit codes of the validate-resolutions command and run --dryRun
+const (
+	validateExitCodeProblems = 2
+)
+
+// validateCmd represents the validate-resolutions command
+var validateCmd = &cobra.Command{
+	Use:   "validate-resolutions",
+	Short: "Check a resolved issues file against the current run without generating any files",
+	Long: `The validate-resolutions command queries Resource Graph and plans your module as run does, then checks
+every resolution in the file passed with --issuesCsv against the issues of this run. It reports:
+
+  Malformed             a row or entry that cannot be read, or an action that is not valid for the issue
+  Unresolved            an issue without a resolution
+  UnknownIssue          a resolution that matches no issue in this run
+  IssueTypeChanged      a resolution for an issue that now has another type
+  DuplicateUse          more than one candidate chosen with Use for an issue
+  MissingCandidate      a Use resource that is no longer a candidate or no longer exists in Resource Graph
+  InvalidReplaceTarget  a Replace target that is missing or is not an unused resource
+
+No import blocks or issue files are written. It is the same as run --dryRun and accepts the flags of run.
+
+Exit codes:
+  0  every issue is resolved and every resolution is valid
+  1  the validation could not run, for example terraform plan failed
+  2  problems were found
+
+Examples:
+  # Validate a resolved CSV file
+  terraform-state-importer validate-resolutions --terraformModulePath ./my-module --config ./config.yaml --issuesCsv ./issues.csv
+
+  # Print the report as JSON
+  terraform-state-importer validate-resolutions --issuesCsv ./resolutions.yaml --reportFormat json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		viper.Set("dryRun", true)
+		runCmd.Run(cmd, args)
+	},
+}
+
+// printValidationReport prints the validation problems to stdout, as a table or as JSON.
+func printValidationReport(result *analyzer.ValidationResult, reportFormat string) {
+	if strings.EqualFold(reportFormat, "json") {
+		report, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Fatalf("Error marshalling validation report: %v", err)
+		}
+		fmt.Println(string(report))
+		return
+	}
+
+	if len(result.Problems) == 0 {
+		fmt.Printf("All %d issues are resolved and every resolution is valid\n", result.Issues)
+		return
+	}
+	fmt.Printf("Found %d problems for %d issues\n\n", len(result.Problems), result.Issues)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PROBLEM\tISSUE ID\tADDRESS\tPOSITION\tMESSAGE")
+	for _, problem := range result.Problems {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", problem.Type, problem.IssueID, problem.ResourceAddress, problem.Position, problem.Message)
+	}
+	writer.Flush()
+
+	counts := []string{}
+	for _, problemType := range []types.ResolutionProblemType{
+		types.ResolutionProblemTypeMalformed,
+		types.ResolutionProblemTypeUnresolved,
+		types.ResolutionProblemTypeUnknownIssue,
+		types.ResolutionProblemTypeIssueTypeChanged,
+		types.ResolutionProblemTypeDuplicateUse,
+		types.ResolutionProblemTypeMissingCandidate,
+		types.ResolutionProblemTypeInvalidReplaceTarget,
+	} {
+		if result.Counts[problemType] > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", result.Counts[problemType], problemType))
+		}
+	}
+	fmt.Printf("\n%s\n", strings.Join(counts, ", "))
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	// The run flags are shared rather than copied, so their viper bindings apply to this command too
+	validateCmd.Flags().AddFlagSet(runCmd.PersistentFlags())
+}
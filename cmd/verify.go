@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/azure/terraform-state-importer/analyzer"
+	"github.com/azure/terraform-state-importer/filepathparser"
+	"github.com/azure/terraform-state-importer/hcl"
+	"github.com/azure/terraform-state-importer/json"
+	"github.com/azure/terraform-state-importer/terraform"
+)
+
+// Exit codes of the verify command
+const (
+	verifyExitCodeBlockers = 2
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Plan the generated import blocks and classify the outcome of each import",
+	Long: `The verify command runs terraform plan on your module with the generated imports.tf, or the
+imports.<group>.tf files written with splitImportsBy, and reports each import as:
+
+  Clean       the resource is imported with no changes
+  Updated     the resource is imported and updated in-place
+  Replaced    the resource would be destroyed and recreated (blocker)
+  Failed      the plan does not import the resource, or terraform reported an error for it (blocker)
+  Unverified  the plan failed on other imports before this import was checked
+
+The plan is run with -json, and when it fails the errors terraform reports for an import, such
+as a resource ID that does not exist, are matched to its import block. The results are written
+to verify.json and the plan output to tfplan_verify_log.json in the working folder.
+
+Exit codes:
+  0  all imports are clean or updated
+  1  the verification could not run, for example terraform plan failed for a reason other than an import
+  2  blocking imports were found (updates are blocking with --failOnUpdate)
+
+Examples:
+  # Verify the imports generated in the module
+  terraform-state-importer verify --terraformModulePath ./my-module
+
+  # Verify the imports generated by run --isolatedPlan, failing on any update
+  terraform-state-importer verify --isolatedPlan --failOnUpdate --terraformModulePath ./my-module`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		// Flags shared with the run command are bound here, as viper keeps a single binding per key
+		for _, flagName := range []string{"terraformModulePath", "workingFolderPath", "skipInitOnly", "skipInitUpgrade", "keepBackend", "isolatedPlan", "isolatedRoot", "planSubscriptionID", "planTimeout", "failOnUpdate"} {
+			viper.BindPFlag(flagName, cmd.Flags().Lookup(flagName))
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		configureLogging(cmd)
+
+		workingFolderPath, err := filepathparser.ParsePath(viper.GetString("workingFolderPath"))
+		if err != nil {
+			log.Fatalf("Error getting working folder path: %v", err)
+		}
+		terraformModulePath, err := filepathparser.ParsePath(viper.GetString("terraformModulePath"))
+		if err != nil {
+			log.Fatalf("Error getting terraform module path: %v", err)
+		}
+
+		// The isolated copy left by run --isolatedPlan holds the generated imports.tf, so it is planned as it is
+		planModulePath := terraformModulePath
+		pluginCacheDir := ""
+		if viper.GetBool("isolatedPlan") {
+			_, isolatedModulePath, err := getIsolatedModulePath(workingFolderPath, terraformModulePath)
+			if err != nil {
+				log.Fatalf("Error getting isolated module path: %v", err)
+			}
+			planModulePath = isolatedModulePath
+			pluginCacheDir = getPluginCacheDir(workingFolderPath)
+			if _, err := os.Stat(planModulePath); err != nil {
+				log.Fatalf("Isolated module copy %s not found, run the run command with --isolatedPlan first: %v", planModulePath, err)
+			}
+		}
+
+		jsonClient := json.NewJsonClient(
+			workingFolderPath,
+			log,
+		)
+
+		planClient, err := terraform.NewPlanClient(
+			planModulePath,
+			workingFolderPath,
+			viper.GetString("planSubscriptionID"),
+			nil,
+			false,
+			viper.GetBool("skipInitOnly"),
+			viper.GetBool("skipInitUpgrade"),
+			viper.GetBool("keepBackend"),
+			pluginCacheDir,
+			"",
+			nil,
+			nil,
+			nil,
+			nil,
+			viper.GetDuration("planTimeout"),
+			jsonClient,
+			log,
+		)
+		if err != nil {
+			log.Fatalf("Error creating plan client: %v", err)
+		}
+
+		hclClient := hcl.NewHclClient(
+			planModulePath,
+			nil,
+			false,
+			log,
+		)
+
+		verifyClient := analyzer.NewVerifyClient(
+			"imports*.tf",
+			viper.GetBool("failOnUpdate"),
+			planClient,
+			jsonClient,
+			hclClient,
+			log,
+		)
+
+		ctx, stop := newSignalContext()
+		defer stop()
+
+		_, err = verifyClient.Verify(ctx)
+		if errors.Is(err, analyzer.ErrVerifyBlockers) {
+			log.Errorf("Verification failed: %v", err)
+			stop()
+			os.Exit(verifyExitCodeBlockers)
+		}
+		if err != nil {
+			log.Fatalf("Error verifying imports: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringP("terraformModulePath", "t", ".", "Terraform module path to use")
+	verifyCmd.Flags().StringP("workingFolderPath", "w", ".", "Working folder path to use")
+	verifyCmd.Flags().BoolP("skipInitOnly", "k", false, "Skip init step")
+	verifyCmd.Flags().BoolP("skipInitUpgrade", "u", false, "Skip -upgrade flag on terraform init")
+	verifyCmd.Flags().StringP("planSubscriptionID", "s", "", "Subscription ID to use for terraform plan if not using the az cli subscription ID")
+	verifyCmd.Flags().Bool("keepBackend", false, "Plan with the module backend instead of a local backend, without taking the state lock")
+	verifyCmd.Flags().Bool("isolatedPlan", false, "Plan the isolated module copy created by run --isolatedPlan")
+	verifyCmd.Flags().String("isolatedRoot", "", "The isolatedRoot passed to run --isolatedPlan (defaults to the module path)")
+	verifyCmd.Flags().Duration("planTimeout", 0, "Timeout for terraform init, plan and show, e.g. 30m (no timeout by default)")
+	verifyCmd.Flags().Bool("failOnUpdate", false, "Treat imports that would be updated in-place as blocking")
+}
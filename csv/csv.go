@@ -3,16 +3,19 @@ package csv
 import (
 	csvwriter "encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/azure/terraform-state-importer/types"
 	"github.com/sirupsen/logrus"
 )
 
 type IIssueCsvClient interface {
-	Export(issues map[string]types.Issue)
+	Export(issues map[string]types.Issue) (string, error)
 	Import() (*map[string]types.Issue, error)
 }
 
@@ -20,9 +23,33 @@ type IssueCsvClient struct {
 	WorkingFolderPath string
 	IssueCsvPath      string
 	IssueCsv          *IssueCsv
-	Logger            *logrus.Logger
+	// ExtraHeader holds the columns added by reviewers to the imported CSV file, in order, so they are written back
+	ExtraHeader []string
+	Logger      *logrus.Logger
 }
 
+// legacyHeader is the header written before candidate scores were added, it is still accepted on import.
+var legacyHeader = []string{"Issue ID", "Issue Type", "Resource Address", "Resource Name", "Resource Type", "Resource Sub Type", "Resource Location", "Mapped Resource ID", "Action", "Action ID"}
+
+// optionalHeader columns are informational, files written by older versions may have only some of them.
+var optionalHeader = []string{"Match Score", "Match Reasons", "Resolved By", "Stale Reason"}
+
+// Column names read on import
+const (
+	columnIssueID          = "Issue ID"
+	columnIssueType        = "Issue Type"
+	columnResourceAddress  = "Resource Address"
+	columnResourceName     = "Resource Name"
+	columnResourceType     = "Resource Type"
+	columnResourceSubType  = "Resource Sub Type"
+	columnResourceLocation = "Resource Location"
+	columnMappedResourceID = "Mapped Resource ID"
+	columnAction           = "Action"
+	columnActionID         = "Action ID"
+	columnResolvedBy       = "Resolved By"
+	columnStaleReason      = "Stale Reason"
+)
+
 type IssueCsv struct {
 	Header []string
 	Rows   []*IssueCsvRow
@@ -32,7 +59,7 @@ func NewIssueCsvClient(workingFolderPath string, issueCsvPath string, logger *lo
 	return &IssueCsvClient{
 		WorkingFolderPath: workingFolderPath,
 		IssueCsvPath:      issueCsvPath,
-		IssueCsv:          &IssueCsv{Header: []string{"Issue ID", "Issue Type", "Resource Address", "Resource Name", "Resource Type", "Resource Sub Type", "Resource Location", "Mapped Resource ID", "Action", "Action ID"}},
+		IssueCsv:          &IssueCsv{Header: append(append([]string{}, legacyHeader...), optionalHeader...)},
 		Logger:            logger,
 	}
 }
@@ -52,10 +79,58 @@ type IssueCsvRow struct {
 	MappedResourceID string
 	Action           types.ActionType
 	ActionID         string
+	MatchScore       *int
+	MatchReasons     string
+	ResolvedBy       string
+	StaleReason      string
+	ExtraColumns     map[string]string
+}
+
+// Values returns the row in header order followed by the extra columns.
+func (row *IssueCsvRow) Values(extraHeader []string) []string {
+	matchScore := ""
+	if row.MatchScore != nil {
+		matchScore = strconv.Itoa(*row.MatchScore)
+	}
+	values := []string{
+		row.IssueID,
+		string(row.IssueType),
+		row.ResourceAddress,
+		row.ResourceName,
+		row.ResourceType,
+		row.ResourceSubType,
+		row.ResourceLocation,
+		row.MappedResourceID,
+		string(row.Action),
+		row.ActionID,
+		matchScore,
+		row.MatchReasons,
+		row.ResolvedBy,
+		row.StaleReason,
+	}
+	for _, columnName := range extraHeader {
+		values = append(values, row.ExtraColumns[columnName])
+	}
+	return values
+}
+
+func (csvClient *IssueCsvClient) Export(issues map[string]types.Issue) (string, error) {
+	for _, row := range getIssueCsvRows(issues) {
+		csvClient.IssueCsv.AddRow(row)
+	}
+
+	return csvClient.writeCsv(getExtraHeader(csvClient.ExtraHeader, issues))
 }
 
-func (csvClient *IssueCsvClient) Export(issues map[string]types.Issue) {
+// getIssueCsvRows returns the rows for the issues sorted by issue type, a multiple resource ID issue has a row for
+// each candidate.
+func getIssueCsvRows(issues map[string]types.Issue) []*IssueCsvRow {
+	rows := []*IssueCsvRow{}
 	for id, issue := range issues {
+		// Stale rows share the ID of the current issue, so they are keyed differently
+		if issue.IssueID != "" {
+			id = issue.IssueID
+		}
 		resourceAddress := issue.ResourceAddress
 		resourceName := issue.ResourceName
 		resourceType := issue.ResourceType
@@ -63,6 +138,11 @@ func (csvClient *IssueCsvClient) Export(issues map[string]types.Issue) {
 		resourceLocation := issue.ResourceLocation
 
 		if issue.IssueType == types.IssueTypeMultipleResourceIDs {
+			candidateScores := map[string]types.CandidateScore{}
+			for _, candidateScore := range issue.CandidateScores {
+				candidateScores[candidateScore.ResourceID] = candidateScore
+			}
+
 			for _, mappedResource := range issue.MappedResourceIDs {
 				csvRow := IssueCsvRow{
 					IssueID:          id,
@@ -73,10 +153,17 @@ func (csvClient *IssueCsvClient) Export(issues map[string]types.Issue) {
 					ResourceSubType:  resourceSubType,
 					ResourceLocation: resourceLocation,
 					MappedResourceID: mappedResource,
-					Action:           types.ActionTypeNone,
-					ActionID:         "",
+					Action:           issue.Resolution.ActionType,
+					ActionID:         issue.Resolution.ActionID,
+					ResolvedBy:       issue.ResolvedBy,
+					StaleReason:      issue.StaleReason,
+					ExtraColumns:     issue.ExtraColumns,
 				}
-				csvClient.IssueCsv.AddRow(&csvRow)
+				if candidateScore, ok := candidateScores[mappedResource]; ok {
+					csvRow.MatchScore = &candidateScore.Score
+					csvRow.MatchReasons = strings.Join(candidateScore.Reasons, ", ")
+				}
+				rows = append(rows, &csvRow)
 			}
 		} else {
 			csvRow := IssueCsvRow{
@@ -88,48 +175,62 @@ func (csvClient *IssueCsvClient) Export(issues map[string]types.Issue) {
 				ResourceSubType:  resourceSubType,
 				ResourceLocation: resourceLocation,
 				MappedResourceID: "",
-				Action:           types.ActionTypeNone,
-				ActionID:         "",
+				Action:           issue.Resolution.ActionType,
+				ActionID:         issue.Resolution.ActionID,
+				ResolvedBy:       issue.ResolvedBy,
+				StaleReason:      issue.StaleReason,
+				ExtraColumns:     issue.ExtraColumns,
 			}
-			csvClient.IssueCsv.AddRow(&csvRow)
+			rows = append(rows, &csvRow)
 		}
 	}
 
-	sort.Sort(ByIssueTypeAddressResourceTypeAndMappedId(csvClient.IssueCsv.Rows))
+	sort.Sort(ByIssueTypeAddressResourceTypeAndMappedId(rows))
+	return rows
+}
+
+// getExtraHeader returns the reviewer columns to write, in the order of the imported file followed by any other
+// columns found on the issues.
+func getExtraHeader(importedExtraHeader []string, issues map[string]types.Issue) []string {
+	extraHeader := append([]string{}, importedExtraHeader...)
+	knownColumns := map[string]bool{}
+	for _, columnName := range append(append(append([]string{}, legacyHeader...), optionalHeader...), extraHeader...) {
+		knownColumns[columnName] = true
+	}
 
-	csvClient.writeCsv()
+	otherColumns := []string{}
+	for _, issue := range issues {
+		for columnName := range issue.ExtraColumns {
+			if !knownColumns[columnName] {
+				knownColumns[columnName] = true
+				otherColumns = append(otherColumns, columnName)
+			}
+		}
+	}
+	sort.Strings(otherColumns)
+	return append(extraHeader, otherColumns...)
 }
 
-func (csvClient *IssueCsvClient) writeCsv() {
-	csvData := [][]string{csvClient.IssueCsv.Header}
+func (csvClient *IssueCsvClient) writeCsv(extraHeader []string) (string, error) {
+	csvData := [][]string{append(append([]string{}, csvClient.IssueCsv.Header...), extraHeader...)}
 	for _, issue := range csvClient.IssueCsv.Rows {
-		csvData = append(csvData, []string{
-			issue.IssueID,
-			string(issue.IssueType),
-			issue.ResourceAddress,
-			issue.ResourceName,
-			issue.ResourceType,
-			issue.ResourceSubType,
-			issue.ResourceLocation,
-			issue.MappedResourceID,
-			string(issue.Action),
-			issue.ActionID,
-		})
+		csvData = append(csvData, issue.Values(extraHeader))
 	}
 
 	csvFilePath := filepath.Join(csvClient.WorkingFolderPath, "issues.csv")
 	csvFile, err := os.Create(csvFilePath)
 	if err != nil {
-		csvClient.Logger.Fatalf("Failed to create file: %v", err)
+		return "", fmt.Errorf("failed to create file: %w", err)
 	}
 	defer csvFile.Close()
 	csvWriter := csvwriter.NewWriter(csvFile)
 	defer csvWriter.Flush()
 	err = csvWriter.WriteAll(csvData)
 	if err != nil {
-		csvClient.Logger.Fatalf("Failed to write CSV file: %v", err)
+		return "", fmt.Errorf("failed to write CSV file: %w", err)
 	}
 	csvClient.Logger.Infof("Issues written to %s", csvFilePath)
+	return csvFilePath, nil
 }
 
 func (csvClient *IssueCsvClient) Import() (*map[string]types.Issue, error) {
@@ -140,65 +241,139 @@ func (csvClient *IssueCsvClient) Import() (*map[string]types.Issue, error) {
 	defer csvFile.Close()
 
 	csvReader := csvwriter.NewReader(csvFile)
-	records, err := csvReader.ReadAll()
+	// Row lengths are checked with the other row errors, so they can all be reported at once
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV file: %w", err)
 	}
 
-	if len(records) < 1 {
-		return nil, fmt.Errorf("CSV file is empty")
+	columns, extraHeader, err := parseHeader(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
 	}
+	csvClient.ExtraHeader = extraHeader
 
-	header := records[0]
-	if !validateHeader(header) {
-		return nil, fmt.Errorf("invalid CSV header")
+	records := []csvRecord{}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+		line, _ := csvReader.FieldPos(0)
+		if isEmptyRecord(record) {
+			continue
+		}
+		records = append(records, csvRecord{Position: fmt.Sprintf("line %d", line), Columns: columns, Values: record})
 	}
 
+	issues, err := importRecords(records, extraHeader, csvClient.Logger)
+	if err != nil {
+		return issues, fmt.Errorf("invalid CSV file %s: %w", csvClient.IssueCsvPath, err)
+	}
+	return issues, nil
+}
+
+// importRecords validates the rows read from an issues file and returns the resolved issues keyed by issue ID. All
+// the malformed rows are reported in an ImportError, which is returned with the issues from the valid rows.
+func importRecords(records []csvRecord, extraHeader []string, logger *logrus.Logger) (*map[string]types.Issue, error) {
 	issues := make(map[string]types.Issue)
+	extraColumns := make(map[string]map[string]string)
+	importError := &ImportError{}
+	addRowError := func(record csvRecord, problemType types.ResolutionProblemType, format string, args ...any) {
+		importError.Problems = append(importError.Problems, types.ResolutionProblem{
+			Type:            problemType,
+			IssueID:         record.Get(columnIssueID),
+			ResourceAddress: record.Get(columnResourceAddress),
+			Position:        record.Position,
+			Message:         fmt.Sprintf(format, args...),
+		})
+	}
 
 	// Get all the issue keys, so we can use them for validation
-	for _, record := range records[1:] {
-		issueID := record[0]
+	for _, record := range records {
+		issueID := record.Get(columnIssueID)
 		if _, ok := issues[issueID]; !ok {
 			issues[issueID] = types.Issue{}
 		}
 	}
 
-	for _, record := range records[1:] {
-		if len(record) != len(header) {
-			csvClient.Logger.Fatalf("Malformed row in CSV file: %v", record)
+	for _, record := range records {
+		if len(record.Values) != len(record.Columns) {
+			addRowError(record, types.ResolutionProblemTypeMalformed, "malformed row, found %d columns but the header has %d", len(record.Values), len(record.Columns))
+			continue
+		}
+
+		if staleReason := record.Get(columnStaleReason); staleReason != "" {
+			logger.Debugf("Skipping stale row for Issue ID: %s, %s", record.Get(columnIssueID), staleReason)
+			continue
+		}
+
+		issue := types.Issue{
+			IssueID:           record.Get(columnIssueID),
+			IssueType:         types.IssueType(record.Get(columnIssueType)),
+			ResourceAddress:   record.Get(columnResourceAddress),
+			ResourceName:      record.Get(columnResourceName),
+			ResourceType:      record.Get(columnResourceType),
+			ResourceSubType:   record.Get(columnResourceSubType),
+			ResourceLocation:  record.Get(columnResourceLocation),
+			MappedResourceIDs: []string{record.Get(columnMappedResourceID)},
+			ResolvedBy:        record.Get(columnResolvedBy),
+		}
+
+		// Reviewer columns are kept for the issue, multiple resource ID issues span several rows so they are merged
+		for _, columnName := range extraHeader {
+			value := record.Get(columnName)
+			if value == "" {
+				continue
+			}
+			if _, ok := extraColumns[issue.IssueID]; !ok {
+				extraColumns[issue.IssueID] = map[string]string{}
+			}
+			if _, ok := extraColumns[issue.IssueID][columnName]; !ok {
+				extraColumns[issue.IssueID][columnName] = value
+			}
 		}
 
-		issueAction := types.ActionType(record[8])
+		issueAction := types.ActionType(record.Get(columnAction))
 
 		if !issueAction.IsValidActionType() || issueAction == types.ActionTypeNone {
-			csvClient.Logger.Fatalf("Action is missing or malformed for Issue ID: %s, Action: %s", record[0], record[8])
+			problemType := types.ResolutionProblemTypeMalformed
+			if issueAction == types.ActionTypeNone {
+				problemType = types.ResolutionProblemTypeUnresolved
+			}
+			addRowError(record, problemType, "action is missing or malformed for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+			continue
 		}
 
-		issue := types.Issue{
-			IssueID:           record[0],
-			IssueType:         types.IssueType(record[1]),
-			ResourceAddress:   record[2],
-			ResourceName:      record[3],
-			ResourceType:      record[4],
-			ResourceSubType:   record[5],
-			ResourceLocation:  record[7],
-			MappedResourceIDs: []string{record[7]},
+		// Short IDs written by earlier versions can collide, which would apply one resolution to another resource
+		if existingIssue, ok := issues[issue.IssueID]; ok && existingIssue.IssueID != "" && existingIssue.ResourceAddress != issue.ResourceAddress {
+			addRowError(record, types.ResolutionProblemTypeMalformed, "issue ID %s is used for both %s and %s, run the tool again without the CSV file to generate new issue IDs", issue.IssueID, existingIssue.ResourceAddress, issue.ResourceAddress)
+			continue
 		}
 
 		switch issue.IssueType {
 		case types.IssueTypeMultipleResourceIDs:
 			if issueAction != types.ActionTypeIgnore && issueAction != types.ActionTypeUse {
-				csvClient.Logger.Fatalf("Action for MultiResourceIDs must be Use or Ignore for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				addRowError(record, types.ResolutionProblemTypeMalformed, "action for MultiResourceIDs must be Use or Ignore for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				continue
 			}
 			if issueAction == types.ActionTypeIgnore {
-				csvClient.Logger.Debugf("Ignoring Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				logger.Debugf("Ignoring Issue ID: %s, Action: %s", issue.IssueID, issueAction)
 				continue
 			}
 			if issueAction == types.ActionTypeUse {
 				if issue, ok := issues[issue.IssueID]; ok {
 					if issue.IssueID != "" {
-						csvClient.Logger.Fatalf("Duplicate Use Action found for Issue ID %s", issue.IssueID)
+						addRowError(record, types.ResolutionProblemTypeDuplicateUse, "duplicate Use Action found for Issue ID %s", issue.IssueID)
+						continue
 					}
 				}
 				issue.Resolution = types.IssueResolution{
@@ -208,11 +383,12 @@ func (csvClient *IssueCsvClient) Import() (*map[string]types.Issue, error) {
 			}
 		case types.IssueTypeNoResourceID:
 			if issueAction != types.ActionTypeIgnore && issueAction != types.ActionTypeReplace {
-				csvClient.Logger.Fatalf("Action for NoResourceID must be Ignore or Replace for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				addRowError(record, types.ResolutionProblemTypeMalformed, "action for NoResourceID must be Ignore or Replace for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				continue
 			}
 
 			if issueAction == types.ActionTypeIgnore {
-				csvClient.Logger.Debugf("Ignoring Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				logger.Debugf("Ignoring Issue ID: %s, Action: %s", issue.IssueID, issueAction)
 				issue.Resolution = types.IssueResolution{
 					ActionType: issueAction,
 					ActionID:   "",
@@ -220,14 +396,16 @@ func (csvClient *IssueCsvClient) Import() (*map[string]types.Issue, error) {
 			}
 
 			if issueAction == types.ActionTypeReplace {
-				actionID := record[9]
+				actionID := record.Get(columnActionID)
 
 				if actionID == "" {
-					csvClient.Logger.Fatalf("Action ID is missing for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+					addRowError(record, types.ResolutionProblemTypeInvalidReplaceTarget, "action ID is missing for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+					continue
 				}
 
 				if _, ok := issues[actionID]; !ok {
-					csvClient.Logger.Fatalf("Action ID %s not found in CSV file for Issue ID: %s", actionID, issue.IssueID)
+					addRowError(record, types.ResolutionProblemTypeInvalidReplaceTarget, "action ID %s not found in CSV file for Issue ID: %s", actionID, issue.IssueID)
+					continue
 				}
 
 				issue.Resolution = types.IssueResolution{
@@ -236,46 +414,128 @@ func (csvClient *IssueCsvClient) Import() (*map[string]types.Issue, error) {
 				}
 			}
 		case types.IssueTypeUnusedResourceID:
-			if issueAction != types.ActionTypeIgnore && issueAction != types.ActionTypeReplace && issueAction != types.ActionTypeDestroy {
-				csvClient.Logger.Fatalf("Action for UnusedResourceID must be Ignore, Replace, or Destroy for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+			if issueAction != types.ActionTypeIgnore && issueAction != types.ActionTypeReplace && issueAction != types.ActionTypeDestroy && issueAction != types.ActionTypeForget {
+				addRowError(record, types.ResolutionProblemTypeMalformed, "action for UnusedResourceID must be Ignore, Replace, Destroy, or Forget for Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				continue
 			}
 			if issueAction == types.ActionTypeIgnore {
-				csvClient.Logger.Debugf("Ignoring Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				logger.Debugf("Ignoring Issue ID: %s, Action: %s", issue.IssueID, issueAction)
 				issue.Resolution = types.IssueResolution{
 					ActionType: issueAction,
 					ActionID:   "",
 				}
 			}
 			if issueAction == types.ActionTypeReplace || issueAction == types.ActionTypeDestroy {
-				csvClient.Logger.Debugf("Destroying via Replace or Destroy Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				logger.Debugf("Destroying via Replace or Destroy Issue ID: %s, Action: %s", issue.IssueID, issueAction)
 				issue.Resolution = types.IssueResolution{
 					ActionType: issueAction,
 					ActionID:   "",
 				}
 			}
+			if issueAction == types.ActionTypeForget {
+				// The action ID is the address in state, which is looked up by resource ID when it is not set
+				logger.Debugf("Forgetting Issue ID: %s, Action: %s", issue.IssueID, issueAction)
+				issue.Resolution = types.IssueResolution{
+					ActionType: issueAction,
+					ActionID:   record.Get(columnActionID),
+				}
+			}
 		default:
-			csvClient.Logger.Fatalf("Invalid Issue Type: %s for Issue ID: %s", issue.IssueType, issue.IssueID)
+			addRowError(record, types.ResolutionProblemTypeMalformed, "invalid Issue Type: %s for Issue ID: %s", issue.IssueType, issue.IssueID)
+			continue
 		}
 
 		issues[issue.IssueID] = issue
 	}
 
+	if len(importError.Problems) > 0 {
+		return &issues, importError
+	}
+
+	for issueID, columns := range extraColumns {
+		issue := issues[issueID]
+		issue.ExtraColumns = columns
+		issues[issueID] = issue
+	}
+
 	return &issues, nil
 }
 
-func validateHeader(header []string) bool {
-	expectedHeader := []string{"Issue ID", "Issue Type", "Resource Address", "Resource Name", "Resource Type", "Resource Sub Type", "Resource Location", "Mapped Resource ID", "Action", "Action ID"}
-	if len(header) != len(expectedHeader) {
-		return false
+// ImportError lists every problem found in an issues file, so they can all be fixed in one go.
+type ImportError struct {
+	Problems []types.ResolutionProblem
+}
+
+func (importError *ImportError) Error() string {
+	lines := []string{}
+	for _, problem := range importError.Problems {
+		lines = append(lines, fmt.Sprintf("%s: %s", problem.Position, problem.Message))
+	}
+	return fmt.Sprintf("found %d problems:\n%s", len(importError.Problems), strings.Join(lines, "\n"))
+}
+
+// csvRecord is a row of an issues file, read by column name as reviewers may reorder the columns.
+type csvRecord struct {
+	Position string
+	Columns  map[string]int
+	Values   []string
+}
+
+func (record csvRecord) Get(columnName string) string {
+	index, ok := record.Columns[columnName]
+	if !ok || index >= len(record.Values) {
+		return ""
 	}
-	for i, h := range header {
-		if h != expectedHeader[i] {
+	return record.Values[index]
+}
+
+func isEmptyRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
 			return false
 		}
 	}
 	return true
 }
 
+// parseHeader maps each column name to its index, the columns of the legacy header are required and any columns
+// not written by this tool are returned in order as extra columns.
+func parseHeader(header []string) (map[string]int, []string, error) {
+	knownColumns := map[string]bool{}
+	for _, columnName := range append(append([]string{}, legacyHeader...), optionalHeader...) {
+		knownColumns[columnName] = true
+	}
+
+	columns := map[string]int{}
+	extraHeader := []string{}
+	for i, columnName := range header {
+		// Excel adds a byte order mark to CSV files saved as UTF-8
+		columnName = strings.TrimSpace(strings.TrimPrefix(columnName, "\ufeff"))
+		if columnName == "" {
+			return nil, nil, fmt.Errorf("column %d has no name", i+1)
+		}
+		if _, ok := columns[columnName]; ok {
+			return nil, nil, fmt.Errorf("column %s is duplicated", columnName)
+		}
+		columns[columnName] = i
+		if !knownColumns[columnName] {
+			extraHeader = append(extraHeader, columnName)
+		}
+	}
+
+	missingColumns := []string{}
+	for _, columnName := range legacyHeader {
+		if _, ok := columns[columnName]; !ok {
+			missingColumns = append(missingColumns, columnName)
+		}
+	}
+	if len(missingColumns) > 0 {
+		return nil, nil, fmt.Errorf("missing columns %s", strings.Join(missingColumns, ", "))
+	}
+
+	return columns, extraHeader, nil
+}
+
 type ByIssueTypeAddressResourceTypeAndMappedId []*IssueCsvRow
 
 func (o ByIssueTypeAddressResourceTypeAndMappedId) Len() int      { return len(o) }
@@ -297,5 +557,9 @@ func (o ByIssueTypeAddressResourceTypeAndMappedId) Less(i, j int) bool {
 		return o[i].ResourceAddress < o[j].ResourceAddress
 	}
 
+	if o[i].MatchScore != nil && o[j].MatchScore != nil && *o[i].MatchScore != *o[j].MatchScore {
+		return *o[i].MatchScore > *o[j].MatchScore
+	}
+
 	return o[i].MappedResourceID < o[j].MappedResourceID
 }
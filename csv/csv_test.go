@@ -0,0 +1,169 @@
+package csv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestCsv(t *testing.T, lines ...string) string {
+	csvFilePath := filepath.Join(t.TempDir(), "issues.csv")
+	assert.NoError(t, os.WriteFile(csvFilePath, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	return csvFilePath
+}
+
+func Test_parseHeader(t *testing.T) {
+	header := append(append([]string{"Notes"}, legacyHeader...), "Match Score", "Owner")
+	header[1] = "\ufeff" + header[1]
+
+	columns, extraHeader, err := parseHeader(header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Notes", "Owner"}, extraHeader)
+	assert.Equal(t, 1, columns[columnIssueID])
+	assert.Equal(t, 7, columns[columnResourceLocation])
+	assert.Equal(t, 11, columns["Match Score"])
+	assert.Equal(t, 12, columns["Owner"])
+}
+
+func Test_parseHeader_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []string
+		expected string
+	}{
+		{"missing column", []string{"Issue ID", "Issue Type", "Resource Address", "Resource Name", "Resource Type", "Resource Sub Type", "Resource Location", "Mapped Resource ID", "Action"}, "missing columns Action ID"},
+		{"missing columns", []string{"Issue ID", "Issue Type", "Resource Address"}, "missing columns Resource Name, Resource Type, Resource Sub Type, Resource Location, Mapped Resource ID, Action, Action ID"},
+		{"duplicated column", append(append([]string{}, legacyHeader...), "Action"), "column Action is duplicated"},
+		{"unnamed column", append(append([]string{}, legacyHeader...), " "), "column 11 has no name"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := parseHeader(test.header)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestIssueCsvClient_Import_ReorderedColumns(t *testing.T) {
+	subnetIssueID := types.GetIssueID(testSubnetAddress)
+	orphanIssueID := types.GetIssueID(testOrphanSubnetID)
+	csvFilePath := writeTestCsv(t,
+		"Action,Action ID,Resource Location,Issue ID,Issue Type,Resource Address,Resource Name,Resource Type,Resource Sub Type,Mapped Resource ID",
+		"Replace,"+orphanIssueID+",uksouth,"+subnetIssueID+",NoResourceID,"+testSubnetAddress+",new,azurerm_subnet,,",
+		"Destroy,,westeurope,"+orphanIssueID+",UnusedResourceID,"+testOrphanSubnetID+",old,microsoft.network/virtualnetworks/subnets,,",
+	)
+
+	issues, err := NewIssueCsvClient(".", csvFilePath, logrus.New()).Import()
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.Issue{
+		IssueID:           subnetIssueID,
+		IssueType:         types.IssueTypeNoResourceID,
+		ResourceAddress:   testSubnetAddress,
+		ResourceName:      "new",
+		ResourceType:      "azurerm_subnet",
+		ResourceLocation:  "uksouth",
+		MappedResourceIDs: []string{""},
+		Resolution:        types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: orphanIssueID},
+	}, (*issues)[subnetIssueID])
+	assert.Equal(t, "westeurope", (*issues)[orphanIssueID].ResourceLocation)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeDestroy}, (*issues)[orphanIssueID].Resolution)
+}
+
+func TestIssueCsvClient_Import_LegacyHeader(t *testing.T) {
+	orphanIssueID := types.GetIssueID(testOrphanSubnetID)
+	csvFilePath := writeTestCsv(t,
+		strings.Join(legacyHeader, ","),
+		orphanIssueID+",UnusedResourceID,"+testOrphanSubnetID+",old,microsoft.network/virtualnetworks/subnets,,uksouth,,Ignore,",
+	)
+
+	issues, err := NewIssueCsvClient(".", csvFilePath, logrus.New()).Import()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "uksouth", (*issues)[orphanIssueID].ResourceLocation)
+	assert.Equal(t, "", (*issues)[orphanIssueID].ResourceSubType)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeIgnore}, (*issues)[orphanIssueID].Resolution)
+}
+
+func TestIssueCsvClient_ExportAndImport_ExtraColumns(t *testing.T) {
+	vnetIssueID := types.GetIssueID(testVnetAddress)
+	orphanIssueID := types.GetIssueID(testOrphanSubnetID)
+	csvFilePath := writeTestCsv(t,
+		"Notes,"+strings.Join(legacyHeader, ",")+",Owner",
+		"keep the hub,"+vnetIssueID+",MultipleResourceIDs,"+testVnetAddress+",vnet,azurerm_virtual_network,,uksouth,"+testVnetHubID+",Use,,network team",
+		","+vnetIssueID+",MultipleResourceIDs,"+testVnetAddress+",vnet,azurerm_virtual_network,,uksouth,"+testVnetSpokeID+",Ignore,,",
+		","+orphanIssueID+",UnusedResourceID,"+testOrphanSubnetID+",old,microsoft.network/virtualnetworks/subnets,,uksouth,,Ignore,,platform team",
+	)
+	importClient := NewIssueCsvClient(".", csvFilePath, logrus.New())
+
+	issues, err := importClient.Import()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Notes", "Owner"}, importClient.ExtraHeader)
+	assert.Equal(t, map[string]string{"Notes": "keep the hub", "Owner": "network team"}, (*issues)[vnetIssueID].ExtraColumns)
+	assert.Equal(t, map[string]string{"Owner": "platform team"}, (*issues)[orphanIssueID].ExtraColumns)
+
+	// Reviewer columns are written back after the known columns, in the order they were read
+	exportClient := NewIssueCsvClient(t.TempDir(), "", logrus.New())
+	exportClient.ExtraHeader = importClient.ExtraHeader
+	exportedFilePath, err := exportClient.Export(*issues)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(exportedFilePath)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, strings.Join(append(append(append([]string{}, legacyHeader...), optionalHeader...), "Notes", "Owner"), ","), lines[0])
+	assert.Contains(t, lines, vnetIssueID+",MultipleResourceIDs,"+testVnetAddress+",vnet,azurerm_virtual_network,,uksouth,"+testVnetHubID+",Use,,,,,,keep the hub,network team")
+
+	reimportClient := NewIssueCsvClient(".", exportedFilePath, logrus.New())
+	reimportedIssues, err := reimportClient.Import()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Notes", "Owner"}, reimportClient.ExtraHeader)
+	assert.Equal(t, (*issues)[vnetIssueID].ExtraColumns, (*reimportedIssues)[vnetIssueID].ExtraColumns)
+	assert.Equal(t, (*issues)[orphanIssueID].ExtraColumns, (*reimportedIssues)[orphanIssueID].ExtraColumns)
+}
+
+func TestIssueCsvClient_Import_MissingColumn(t *testing.T) {
+	csvFilePath := writeTestCsv(t,
+		"Issue ID,Issue Type,Resource Address,Resource Name,Resource Type,Resource Sub Type,Mapped Resource ID,Action,Action ID",
+	)
+
+	_, err := NewIssueCsvClient(".", csvFilePath, logrus.New()).Import()
+
+	assert.EqualError(t, err, "invalid CSV header: missing columns Resource Location")
+}
+
+func TestIssueCsvClient_Import_MalformedRows(t *testing.T) {
+	subnetIssueID := types.GetIssueID(testSubnetAddress)
+	orphanIssueID := types.GetIssueID(testOrphanSubnetID)
+	csvFilePath := writeTestCsv(t,
+		strings.Join(legacyHeader, ","),
+		orphanIssueID+",UnusedResourceID,"+testOrphanSubnetID+",old,microsoft.network/virtualnetworks/subnets,,uksouth,,Destroy,",
+		subnetIssueID+",NoResourceID,"+testSubnetAddress+",new,azurerm_subnet,,uksouth,,Replace",
+		"",
+		subnetIssueID+",NoResourceID,"+testSubnetAddress+",new,azurerm_subnet,,uksouth,,Destroy,",
+		"i-0000000000000000,Unknown,azurerm_subnet.other,other,azurerm_subnet,,uksouth,,Ignore,",
+	)
+
+	issues, err := NewIssueCsvClient(".", csvFilePath, logrus.New()).Import()
+
+	var importError *ImportError
+	assert.True(t, errors.As(err, &importError))
+	positions := []string{}
+	for _, problem := range importError.Problems {
+		positions = append(positions, problem.Position)
+	}
+	assert.Equal(t, []string{"line 3", "line 5", "line 6"}, positions)
+	assert.Equal(t, types.ResolutionProblemTypeMalformed, importError.Problems[0].Type)
+	assert.Equal(t, subnetIssueID, importError.Problems[0].IssueID)
+	assert.Contains(t, err.Error(), "found 3 problems:\nline 3: malformed row, found 9 columns but the header has 10\nline 5: action for NoResourceID must be Ignore or Replace")
+	assert.Contains(t, err.Error(), "line 6: invalid Issue Type: Unknown for Issue ID: i-0000000000000000")
+	// The valid rows are still returned, so they can be checked alongside the problems
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeDestroy}, (*issues)[orphanIssueID].Resolution)
+}
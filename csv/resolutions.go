@@ -0,0 +1,359 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// commentColumnName is the CSV column that holds the comment of a resolution.
+const commentColumnName = "Comment"
+
+// NaturalKeyPrefix keys the resolutions that are for a Terraform address, address pattern or Azure resource ID rather
+// than an issue ID, they take precedence over issue IDs when the resolutions are looked up.
+const NaturalKeyPrefix = "key:"
+
+// GetNaturalKey returns the key of a resolution for a Terraform address, address pattern or Azure resource ID. Azure
+// resource IDs are normalized, as they are case insensitive.
+func GetNaturalKey(resourceAddress string) string {
+	if strings.HasPrefix(resourceAddress, "/") {
+		resourceAddress = azure.NormalizeResourceID(resourceAddress)
+	}
+	return NaturalKeyPrefix + resourceAddress
+}
+
+// IsAddressPattern reports whether a resolution key is a pattern, where * matches any characters.
+func IsAddressPattern(key string) bool {
+	return strings.Contains(key, "*")
+}
+
+var issueIDPattern = regexp.MustCompile(`^i-[0-9a-f]+$`)
+
+// ResolutionFile is the resolutions.yaml format, resolutions are keyed by issue ID, Terraform address or Azure
+// resource ID.
+type ResolutionFile struct {
+	Resolutions map[string]Resolution `yaml:"resolutions" json:"resolutions"`
+}
+
+// Resolution resolves an issue. The target is the Azure resource ID to Use, or the Azure resource ID that replaces a
+// Terraform address.
+type Resolution struct {
+	Action  types.ActionType `yaml:"action" json:"action"`
+	Target  string           `yaml:"target,omitempty" json:"target,omitempty"`
+	Comment string           `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+type ResolutionsClient struct {
+	WorkingFolderPath   string
+	ResolutionsFilePath string
+	Logger              *logrus.Logger
+}
+
+func NewResolutionsClient(workingFolderPath string, resolutionsFilePath string, logger *logrus.Logger) *ResolutionsClient {
+	return &ResolutionsClient{
+		WorkingFolderPath:   workingFolderPath,
+		ResolutionsFilePath: resolutionsFilePath,
+		Logger:              logger,
+	}
+}
+
+// IsResolutionsFile reports whether the file is a YAML or JSON resolutions file, based on its extension.
+func IsResolutionsFile(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// Export writes resolutions.yaml with an entry for each issue keyed by its address, the issue type, ID and
+// candidates are written as comments so reviewers only need to fill in the action and target.
+func (resolutionsClient *ResolutionsClient) Export(issues map[string]types.Issue) (string, error) {
+	sortedIssues := []types.Issue{}
+	for _, issue := range issues {
+		// Stale resolutions have the same address as the current issue, they are reported when the issues are mapped
+		if issue.IssueID == "" || issue.StaleReason != "" {
+			continue
+		}
+		sortedIssues = append(sortedIssues, issue)
+	}
+	sort.Slice(sortedIssues, func(i, j int) bool {
+		if sortedIssues[i].IssueType != sortedIssues[j].IssueType {
+			return sortedIssues[i].IssueType < sortedIssues[j].IssueType
+		}
+		return sortedIssues[i].ResourceAddress < sortedIssues[j].ResourceAddress
+	})
+
+	resolutionsNode := &yaml.Node{Kind: yaml.MappingNode}
+	for _, issue := range sortedIssues {
+		resolution := Resolution{
+			Action:  issue.Resolution.ActionType,
+			Comment: issue.ExtraColumns[commentColumnName],
+		}
+		switch issue.Resolution.ActionType {
+		case types.ActionTypeUse:
+			if len(issue.MappedResourceIDs) > 0 {
+				resolution.Target = issue.MappedResourceIDs[0]
+			}
+		case types.ActionTypeReplace:
+			if issue.Resolution.ActionID != "" {
+				resolution.Target = issues[issue.Resolution.ActionID].ResourceAddress
+			}
+		case types.ActionTypeForget:
+			resolution.Target = issue.Resolution.ActionID
+		}
+
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: issue.ResourceAddress, HeadComment: getResolutionComment(issue)}
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(&resolution); err != nil {
+			return "", fmt.Errorf("failed to encode resolution for %s: %w", issue.ResourceAddress, err)
+		}
+		resolutionsNode.Content = append(resolutionsNode.Content, keyNode, valueNode)
+	}
+
+	document := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "resolutions"},
+		resolutionsNode,
+	}}
+	content := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return "", fmt.Errorf("failed to marshal resolutions: %w", err)
+	}
+
+	resolutionsFilePath := filepath.Join(resolutionsClient.WorkingFolderPath, "resolutions.yaml")
+	if err := os.WriteFile(resolutionsFilePath, content.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	resolutionsClient.Logger.Infof("Issues written to %s", resolutionsFilePath)
+	return resolutionsFilePath, nil
+}
+
+func getResolutionComment(issue types.Issue) string {
+	actions := []string{}
+	for _, actionType := range issue.IssueType.ValidActionTypes() {
+		// Ignore drops a candidate in a CSV file, an address with several candidates cannot be ignored as a whole
+		if issue.IssueType == types.IssueTypeMultipleResourceIDs && actionType == types.ActionTypeIgnore {
+			continue
+		}
+		actions = append(actions, string(actionType))
+	}
+	lines := []string{fmt.Sprintf("%s %s, actions: %s", issue.IssueType, issue.IssueID, strings.Join(actions, ", "))}
+
+	if issue.IssueType == types.IssueTypeMultipleResourceIDs {
+		scores := map[string]int{}
+		for _, candidateScore := range issue.CandidateScores {
+			scores[candidateScore.ResourceID] = candidateScore.Score
+		}
+		for _, mappedResourceID := range issue.MappedResourceIDs {
+			if score, ok := scores[mappedResourceID]; ok {
+				lines = append(lines, fmt.Sprintf("candidate: %s (score %d)", mappedResourceID, score))
+			} else {
+				lines = append(lines, fmt.Sprintf("candidate: %s", mappedResourceID))
+			}
+		}
+	}
+	if issue.ResolvedBy != "" {
+		lines = append(lines, fmt.Sprintf("resolved by: %s", issue.ResolvedBy))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (resolutionsClient *ResolutionsClient) Import() (*map[string]types.Issue, error) {
+	content, err := os.ReadFile(resolutionsClient.ResolutionsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	resolutionFile := ResolutionFile{}
+	if strings.EqualFold(filepath.Ext(resolutionsClient.ResolutionsFilePath), ".json") {
+		err = json.Unmarshal(content, &resolutionFile)
+	} else {
+		err = yaml.Unmarshal(content, &resolutionFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", resolutionsClient.ResolutionsFilePath, err)
+	}
+
+	issues, err := importResolutions(resolutionFile.Resolutions, resolutionsClient.Logger)
+	if err != nil {
+		return issues, fmt.Errorf("invalid resolutions file %s: %w", resolutionsClient.ResolutionsFilePath, err)
+	}
+	return issues, nil
+}
+
+// importResolutions returns the resolved issues keyed by issue ID, or by natural key for the resolutions keyed by
+// address or resource ID. The issue type is inferred from the key and action where it can be, an Azure resource ID
+// can only be an UnusedResourceID issue for example.
+func importResolutions(resolutions map[string]Resolution, logger *logrus.Logger) (*map[string]types.Issue, error) {
+	keys := []string{}
+	for key := range resolutions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	issues := make(map[string]types.Issue)
+	replaceKeys := []string{}
+	replacingIssues := map[string]types.Issue{}
+	importError := &ImportError{}
+	addResolutionError := func(key string, problemType types.ResolutionProblemType, format string, args ...any) {
+		importError.Problems = append(importError.Problems, types.ResolutionProblem{
+			Type:            problemType,
+			ResourceAddress: key,
+			Position:        fmt.Sprintf("resolution %s", key),
+			Message:         fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, key := range keys {
+		resolution := resolutions[key]
+		issue := types.Issue{
+			IssueID:         key,
+			ResourceAddress: key,
+			Resolution:      types.IssueResolution{ActionType: resolution.Action},
+		}
+		if issueIDPattern.MatchString(key) {
+			issue.ResourceAddress = ""
+		} else {
+			issue.IssueID = GetNaturalKey(key)
+		}
+		if resolution.Comment != "" {
+			issue.ExtraColumns = map[string]string{commentColumnName: resolution.Comment}
+		}
+
+		isResourceID := strings.HasPrefix(key, "/")
+		// A pattern resolves many issues, so it cannot pair with a single resource
+		if IsAddressPattern(key) && resolution.Action != types.ActionTypeIgnore && resolution.Action != types.ActionTypeDestroy && resolution.Action != types.ActionTypeForget {
+			addResolutionError(key, types.ResolutionProblemTypeMalformed, "action %s is not valid for a pattern, use Ignore, Destroy or Forget", resolution.Action)
+			continue
+		}
+		switch resolution.Action {
+		case types.ActionTypeUse:
+			issue.IssueType = types.IssueTypeMultipleResourceIDs
+			if resolution.Target == "" {
+				addResolutionError(key, types.ResolutionProblemTypeMalformed, "target is missing, set it to the Azure resource ID to use")
+				continue
+			}
+			issue.MappedResourceIDs = []string{resolution.Target}
+		case types.ActionTypeIgnore:
+			// Ignore on a multiple resource ID issue only drops a candidate, so an address can only be ignored when it
+			// has no resource ID
+			if isResourceID {
+				issue.IssueType = types.IssueTypeUnusedResourceID
+			} else if issue.ResourceAddress != "" {
+				issue.IssueType = types.IssueTypeNoResourceID
+			}
+		case types.ActionTypeReplace:
+			if isResourceID || (issue.ResourceAddress == "" && resolution.Target == "") {
+				issue.IssueType = types.IssueTypeUnusedResourceID
+				break
+			}
+			issue.IssueType = types.IssueTypeNoResourceID
+			if !strings.HasPrefix(resolution.Target, "/") {
+				addResolutionError(key, types.ResolutionProblemTypeInvalidReplaceTarget, "target %q must be the Azure resource ID that replaces the resource", resolution.Target)
+				continue
+			}
+			issue.Resolution.ActionID = GetNaturalKey(resolution.Target)
+		case types.ActionTypeDestroy:
+			issue.IssueType = types.IssueTypeUnusedResourceID
+		case types.ActionTypeForget:
+			issue.IssueType = types.IssueTypeUnusedResourceID
+			if resolution.Target != "" && strings.HasPrefix(resolution.Target, "/") {
+				addResolutionError(key, types.ResolutionProblemTypeMalformed, "target %q must be the Terraform address of the resource in state", resolution.Target)
+				continue
+			}
+			issue.Resolution.ActionID = resolution.Target
+		default:
+			problemType := types.ResolutionProblemTypeMalformed
+			if resolution.Action == types.ActionTypeNone {
+				problemType = types.ResolutionProblemTypeUnresolved
+			}
+			addResolutionError(key, problemType, "action is missing or malformed, Action: %s", resolution.Action)
+			continue
+		}
+
+		if issue.IssueType == types.IssueTypeUnusedResourceID && !isResourceID && issue.ResourceAddress != "" {
+			addResolutionError(key, types.ResolutionProblemTypeMalformed, "action %s is only valid for an Azure resource ID", resolution.Action)
+			continue
+		}
+		if existingIssue, ok := issues[issue.IssueID]; ok && existingIssue.Resolution.ActionType != issue.Resolution.ActionType {
+			addResolutionError(key, types.ResolutionProblemTypeMalformed, "issue %s is resolved with both %s and %s", issue.IssueID, existingIssue.Resolution.ActionType, issue.Resolution.ActionType)
+			continue
+		}
+		issues[issue.IssueID] = issue
+		if issue.IssueType == types.IssueTypeNoResourceID && issue.Resolution.ActionType == types.ActionTypeReplace {
+			replaceKeys = append(replaceKeys, key)
+			replacingIssues[key] = issue
+		}
+	}
+
+	// The replacing resource is paired with the Replace action, so it does not need its own entry
+	for _, key := range replaceKeys {
+		issue := replacingIssues[key]
+		target := resolutions[key].Target
+		if targetIssue, ok := issues[issue.Resolution.ActionID]; ok {
+			if targetIssue.Resolution.ActionType != types.ActionTypeReplace {
+				addResolutionError(key, types.ResolutionProblemTypeInvalidReplaceTarget, "target %s is resolved with %s, it must be Replace to replace a resource", target, targetIssue.Resolution.ActionType)
+			}
+			continue
+		}
+		logger.Debugf("Adding Replace resolution for %s paired with %s", target, key)
+		issues[issue.Resolution.ActionID] = types.Issue{
+			IssueID:         issue.Resolution.ActionID,
+			IssueType:       types.IssueTypeUnusedResourceID,
+			ResourceAddress: target,
+			Resolution:      types.IssueResolution{ActionType: types.ActionTypeReplace},
+		}
+	}
+
+	if len(importError.Problems) > 0 {
+		return &issues, importError
+	}
+	return &issues, nil
+}
+
+// KeyByIssueID keys resolutions by issue ID, so resolutions keyed by address or resource ID can be written to a CSV
+// file or workbook. Resource IDs are hashed once normalized, as the case typed by the reviewer may not match Resource
+// Graph. Patterns have no issue ID and an Ignore keyed by issue ID has no issue type, so their keys are returned
+// instead.
+func KeyByIssueID(resolvedIssues map[string]types.Issue) (map[string]types.Issue, []string) {
+	issueIDs := map[string]string{}
+	for key := range resolvedIssues {
+		issueIDs[key] = key
+		if strings.HasPrefix(key, NaturalKeyPrefix) {
+			issueIDs[key] = types.GetIssueID(strings.TrimPrefix(key, NaturalKeyPrefix))
+		}
+	}
+
+	issues := map[string]types.Issue{}
+	skippedKeys := []string{}
+	for key, issue := range resolvedIssues {
+		if strings.HasPrefix(key, NaturalKeyPrefix) && IsAddressPattern(key) {
+			skippedKeys = append(skippedKeys, issue.ResourceAddress)
+			continue
+		}
+		if issue.IssueType == "" {
+			skippedKeys = append(skippedKeys, key)
+			continue
+		}
+		issue.IssueID = issueIDs[key]
+		if issueID, ok := issueIDs[issue.Resolution.ActionID]; ok {
+			issue.Resolution.ActionID = issueID
+		}
+		issues[issue.IssueID] = issue
+	}
+	sort.Strings(skippedKeys)
+	return issues, skippedKeys
+}
@@ -0,0 +1,183 @@
+package csv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/azure"
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_importResolutions_IssueTypes(t *testing.T) {
+	issueID := types.GetIssueID(testSubnetAddress)
+	resolutions := map[string]Resolution{
+		testVnetAddress:    {Action: types.ActionTypeUse, Target: testVnetHubID},
+		testSubnetAddress:  {Action: types.ActionTypeIgnore},
+		testOrphanSubnetID: {Action: types.ActionTypeIgnore},
+		issueID:            {Action: types.ActionTypeIgnore},
+		"module.*":         {Action: types.ActionTypeIgnore},
+		testVnetSpokeID:    {Action: types.ActionTypeDestroy},
+	}
+
+	issues, err := importResolutions(resolutions, logrus.New())
+
+	assert.NoError(t, err)
+	issueTypes := map[string]types.IssueType{}
+	for key, issue := range *issues {
+		issueTypes[key] = issue.IssueType
+	}
+	assert.Equal(t, map[string]types.IssueType{
+		GetNaturalKey(testVnetAddress):    types.IssueTypeMultipleResourceIDs,
+		GetNaturalKey(testSubnetAddress):  types.IssueTypeNoResourceID,
+		GetNaturalKey(testOrphanSubnetID): types.IssueTypeUnusedResourceID,
+		issueID:                           "",
+		GetNaturalKey("module.*"):         types.IssueTypeNoResourceID,
+		GetNaturalKey(testVnetSpokeID):    types.IssueTypeUnusedResourceID,
+	}, issueTypes)
+}
+
+func Test_importResolutions_Invalid(t *testing.T) {
+	resolutions := map[string]Resolution{
+		testVnetAddress:    {Action: types.ActionTypeUse},
+		testSubnetAddress:  {Action: types.ActionTypeReplace, Target: "azurerm_subnet.other"},
+		testOrphanSubnetID: {Action: types.ActionTypeForget, Target: testVnetHubID},
+		"module.*":         {Action: types.ActionTypeUse, Target: testVnetHubID},
+		"azurerm_subnet.a": {Action: types.ActionTypeDestroy},
+		"azurerm_subnet.b": {},
+	}
+
+	_, err := importResolutions(resolutions, logrus.New())
+
+	var importError *ImportError
+	assert.True(t, errors.As(err, &importError))
+	problems := map[string]types.ResolutionProblemType{}
+	for _, problem := range importError.Problems {
+		problems[problem.ResourceAddress] = problem.Type
+	}
+	assert.Equal(t, map[string]types.ResolutionProblemType{
+		testVnetAddress:    types.ResolutionProblemTypeMalformed,
+		testSubnetAddress:  types.ResolutionProblemTypeInvalidReplaceTarget,
+		testOrphanSubnetID: types.ResolutionProblemTypeMalformed,
+		"module.*":         types.ResolutionProblemTypeMalformed,
+		"azurerm_subnet.a": types.ResolutionProblemTypeMalformed,
+		"azurerm_subnet.b": types.ResolutionProblemTypeUnresolved,
+	}, problems)
+}
+
+func TestKeyByIssueID(t *testing.T) {
+	upperCaseTargetID := strings.ToUpper(testOrphanSubnetID)
+	issueID := types.GetIssueID(testVnetAddress)
+	resolutions := map[string]Resolution{
+		testSubnetAddress: {Action: types.ActionTypeReplace, Target: upperCaseTargetID},
+		testVnetSpokeID:   {Action: types.ActionTypeDestroy},
+		issueID:           {Action: types.ActionTypeIgnore},
+		"module.*":        {Action: types.ActionTypeIgnore},
+	}
+	resolvedIssues, err := importResolutions(resolutions, logrus.New())
+	assert.NoError(t, err)
+
+	issues, skippedKeys := KeyByIssueID(*resolvedIssues)
+
+	// Resource IDs are hashed once normalized, so they match the issue ID of the resource however they were typed
+	subnetIssueID := types.GetIssueID(testSubnetAddress)
+	targetIssueID := types.GetIssueID(azure.NormalizeResourceID(testOrphanSubnetID))
+	spokeIssueID := types.GetIssueID(azure.NormalizeResourceID(testVnetSpokeID))
+	assert.Equal(t, []string{issueID, "module.*"}, skippedKeys)
+	assert.Len(t, issues, 3)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: targetIssueID}, issues[subnetIssueID].Resolution)
+	assert.Equal(t, targetIssueID, issues[targetIssueID].IssueID)
+	assert.Equal(t, upperCaseTargetID, issues[targetIssueID].ResourceAddress)
+	assert.Equal(t, types.ActionTypeDestroy, issues[spokeIssueID].Resolution.ActionType)
+}
+
+func TestResolutionsClient_ImportAndConvertToCsv(t *testing.T) {
+	workingFolderPath := t.TempDir()
+	resolutionsFilePath := filepath.Join(workingFolderPath, "resolutions.yaml")
+	content := `resolutions:
+  ` + testVnetAddress + `:
+    action: Use
+    target: ` + testVnetHubID + `
+  ` + testSubnetAddress + `:
+    action: Ignore
+    comment: created by the pipeline
+  module.network.azurerm_subnet.new:
+    action: Replace
+    target: ` + strings.ToUpper(testOrphanSubnetID) + `
+  ` + testVnetSpokeID + `:
+    action: Destroy
+`
+	assert.NoError(t, os.WriteFile(resolutionsFilePath, []byte(content), 0644))
+
+	resolvedIssues, err := NewResolutionsClient(workingFolderPath, resolutionsFilePath, logrus.New()).Import()
+	assert.NoError(t, err)
+	issues, skippedKeys := KeyByIssueID(*resolvedIssues)
+	assert.Empty(t, skippedKeys)
+	csvFilePath, err := NewIssueCsvClient(workingFolderPath, "", logrus.New()).Export(issues)
+	assert.NoError(t, err)
+
+	csvClient := NewIssueCsvClient(workingFolderPath, csvFilePath, logrus.New())
+	importedIssues, err := csvClient.Import()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{commentColumnName}, csvClient.ExtraHeader)
+	targetIssueID := types.GetIssueID(azure.NormalizeResourceID(testOrphanSubnetID))
+	spokeIssueID := types.GetIssueID(azure.NormalizeResourceID(testVnetSpokeID))
+	expected := map[string]types.IssueResolution{
+		types.GetIssueID(testVnetAddress):                     {ActionType: types.ActionTypeUse},
+		types.GetIssueID(testSubnetAddress):                   {ActionType: types.ActionTypeIgnore},
+		types.GetIssueID("module.network.azurerm_subnet.new"): {ActionType: types.ActionTypeReplace, ActionID: targetIssueID},
+		targetIssueID: {ActionType: types.ActionTypeReplace},
+		spokeIssueID:  {ActionType: types.ActionTypeDestroy},
+	}
+	actual := map[string]types.IssueResolution{}
+	for issueID, issue := range *importedIssues {
+		actual[issueID] = issue.Resolution
+	}
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, types.IssueTypeNoResourceID, (*importedIssues)[types.GetIssueID(testSubnetAddress)].IssueType)
+	assert.Equal(t, map[string]string{commentColumnName: "created by the pipeline"}, (*importedIssues)[types.GetIssueID(testSubnetAddress)].ExtraColumns)
+	assert.Equal(t, []string{testVnetHubID}, (*importedIssues)[types.GetIssueID(testVnetAddress)].MappedResourceIDs)
+}
+
+func TestResolutionsClient_ExportAndImport(t *testing.T) {
+	workingFolderPath := t.TempDir()
+	issues := getTestIssues()
+	vnetIssueID := types.GetIssueID(testVnetAddress)
+	subnetIssueID := types.GetIssueID(testSubnetAddress)
+	orphanIssueID := types.GetIssueID(testOrphanSubnetID)
+	vnetIssue := issues[vnetIssueID]
+	vnetIssue.Resolution = types.IssueResolution{ActionType: types.ActionTypeUse}
+	vnetIssue.MappedResourceIDs = []string{testVnetHubID}
+	vnetIssue.ExtraColumns = map[string]string{commentColumnName: "hub wins"}
+	issues[vnetIssueID] = vnetIssue
+	subnetIssue := issues[subnetIssueID]
+	subnetIssue.Resolution = types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: orphanIssueID}
+	issues[subnetIssueID] = subnetIssue
+	orphanIssue := issues[orphanIssueID]
+	orphanIssue.Resolution = types.IssueResolution{ActionType: types.ActionTypeReplace}
+	issues[orphanIssueID] = orphanIssue
+
+	resolutionsFilePath, err := NewResolutionsClient(workingFolderPath, "", logrus.New()).Export(issues)
+
+	assert.NoError(t, err)
+	content, err := os.ReadFile(resolutionsFilePath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "# MultipleResourceIDs "+vnetIssueID+", actions: Use\n")
+	assert.Contains(t, string(content), "# NoResourceID "+subnetIssueID+", actions: Ignore, Replace\n")
+	assert.Contains(t, string(content), "  "+testSubnetAddress+":\n    action: Replace\n    target: "+testOrphanSubnetID+"\n")
+	assert.Contains(t, string(content), "    comment: hub wins\n")
+
+	importedIssues, err := NewResolutionsClient(workingFolderPath, resolutionsFilePath, logrus.New()).Import()
+
+	assert.NoError(t, err)
+	assert.Len(t, *importedIssues, 3)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeUse}, (*importedIssues)[GetNaturalKey(testVnetAddress)].Resolution)
+	assert.Equal(t, []string{testVnetHubID}, (*importedIssues)[GetNaturalKey(testVnetAddress)].MappedResourceIDs)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: GetNaturalKey(testOrphanSubnetID)}, (*importedIssues)[GetNaturalKey(testSubnetAddress)].Resolution)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeReplace}, (*importedIssues)[GetNaturalKey(testOrphanSubnetID)].Resolution)
+}
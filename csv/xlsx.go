@@ -0,0 +1,274 @@
+package csv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
+)
+
+// issueIDsSheetName is the lookup sheet listing the issues that a Replace action can pair with.
+const issueIDsSheetName = "Issue IDs"
+
+// xlsxIssueTypes are the issue types written to the workbook, each on its own sheet named after the type.
+var xlsxIssueTypes = []types.IssueType{types.IssueTypeMultipleResourceIDs, types.IssueTypeNoResourceID, types.IssueTypeUnusedResourceID}
+
+var issueIDsHeader = []string{"Issue ID", "Resource Address", "Resource Name", "Resource Type", "Resource Location"}
+
+type IssueXlsxClient struct {
+	WorkingFolderPath string
+	IssueXlsxPath     string
+	Header            []string
+	// ExtraHeader holds the columns added by reviewers to the imported workbook, in order, so they are written back
+	ExtraHeader []string
+	Logger      *logrus.Logger
+}
+
+func NewIssueXlsxClient(workingFolderPath string, issueXlsxPath string, logger *logrus.Logger) *IssueXlsxClient {
+	return &IssueXlsxClient{
+		WorkingFolderPath: workingFolderPath,
+		IssueXlsxPath:     issueXlsxPath,
+		Header:            append(append([]string{}, legacyHeader...), optionalHeader...),
+		Logger:            logger,
+	}
+}
+
+func (xlsxClient *IssueXlsxClient) Export(issues map[string]types.Issue) (string, error) {
+	extraHeader := getExtraHeader(xlsxClient.ExtraHeader, issues)
+	header := append(append([]string{}, xlsxClient.Header...), extraHeader...)
+
+	rowsByIssueType := map[types.IssueType][]*IssueCsvRow{}
+	replaceTargets := []*IssueCsvRow{}
+	for _, row := range getIssueCsvRows(issues) {
+		rowsByIssueType[row.IssueType] = append(rowsByIssueType[row.IssueType], row)
+		// A NoResourceID issue is replaced by the resource of an UnusedResourceID issue
+		if row.IssueType == types.IssueTypeUnusedResourceID && row.StaleReason == "" {
+			replaceTargets = append(replaceTargets, row)
+		}
+	}
+
+	workbook := excelize.NewFile()
+	defer workbook.Close()
+	defaultSheetName := workbook.GetSheetName(0)
+
+	headerStyle, err := workbook.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return "", fmt.Errorf("failed to create header style: %w", err)
+	}
+
+	sheetNames := []string{}
+	for _, issueType := range xlsxIssueTypes {
+		rows := rowsByIssueType[issueType]
+		if len(rows) == 0 {
+			continue
+		}
+		if err := xlsxClient.writeIssueSheet(workbook, issueType, header, extraHeader, rows, len(replaceTargets), headerStyle); err != nil {
+			return "", fmt.Errorf("failed to write sheet %s: %w", issueType, err)
+		}
+		sheetNames = append(sheetNames, string(issueType))
+	}
+
+	if err := writeIssueIDsSheet(workbook, replaceTargets, headerStyle); err != nil {
+		return "", fmt.Errorf("failed to write sheet %s: %w", issueIDsSheetName, err)
+	}
+
+	if err := workbook.DeleteSheet(defaultSheetName); err != nil {
+		return "", fmt.Errorf("failed to delete sheet %s: %w", defaultSheetName, err)
+	}
+	if len(sheetNames) > 0 {
+		index, err := workbook.GetSheetIndex(sheetNames[0])
+		if err != nil {
+			return "", fmt.Errorf("failed to get sheet %s: %w", sheetNames[0], err)
+		}
+		workbook.SetActiveSheet(index)
+	}
+
+	xlsxFilePath := filepath.Join(xlsxClient.WorkingFolderPath, "issues.xlsx")
+	if err := workbook.SaveAs(xlsxFilePath); err != nil {
+		return "", fmt.Errorf("failed to write XLSX file: %w", err)
+	}
+	xlsxClient.Logger.Infof("Issues written to %s", xlsxFilePath)
+	return xlsxFilePath, nil
+}
+
+// writeIssueSheet writes the rows of an issue type to their own sheet, with a dropdown of the actions valid for the
+// issue type and, for NoResourceID issues, a dropdown of the issue IDs a Replace action can pair with.
+func (xlsxClient *IssueXlsxClient) writeIssueSheet(workbook *excelize.File, issueType types.IssueType, header []string, extraHeader []string, rows []*IssueCsvRow, replaceTargetCount int, headerStyle int) error {
+	sheetName := string(issueType)
+	if _, err := workbook.NewSheet(sheetName); err != nil {
+		return err
+	}
+
+	lastRow := len(rows) + 1
+	if err := writeSheetHeader(workbook, sheetName, header, lastRow, headerStyle); err != nil {
+		return err
+	}
+
+	matchScoreIndex := indexOf(header, "Match Score")
+	for i, row := range rows {
+		values := []interface{}{}
+		for _, value := range row.Values(extraHeader) {
+			values = append(values, value)
+		}
+		// Scores are written as numbers, so they sort and filter as numbers
+		if row.MatchScore != nil {
+			values[matchScoreIndex] = *row.MatchScore
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := workbook.SetSheetRow(sheetName, cell, &values); err != nil {
+			return err
+		}
+	}
+
+	actionColumn, err := excelize.ColumnNumberToName(indexOf(header, columnAction) + 1)
+	if err != nil {
+		return err
+	}
+	actions := []string{}
+	for _, actionType := range issueType.ValidActionTypes() {
+		actions = append(actions, string(actionType))
+	}
+	actionValidation := excelize.NewDataValidation(true)
+	actionValidation.Sqref = fmt.Sprintf("%s2:%s%d", actionColumn, actionColumn, lastRow)
+	if err := actionValidation.SetDropList(actions); err != nil {
+		return err
+	}
+	actionValidation.SetError(excelize.DataValidationErrorStyleStop, "Invalid action", fmt.Sprintf("The action for %s issues must be one of %s", issueType, strings.Join(actions, ", ")))
+	if err := workbook.AddDataValidation(sheetName, actionValidation); err != nil {
+		return err
+	}
+
+	if issueType != types.IssueTypeNoResourceID || replaceTargetCount == 0 {
+		return nil
+	}
+	actionIDColumn, err := excelize.ColumnNumberToName(indexOf(header, columnActionID) + 1)
+	if err != nil {
+		return err
+	}
+	actionIDValidation := excelize.NewDataValidation(true)
+	actionIDValidation.Sqref = fmt.Sprintf("%s2:%s%d", actionIDColumn, actionIDColumn, lastRow)
+	actionIDValidation.SetSqrefDropList(fmt.Sprintf("'%s'!$A$2:$A$%d", issueIDsSheetName, replaceTargetCount+1))
+	actionIDValidation.SetError(excelize.DataValidationErrorStyleStop, "Invalid action ID", fmt.Sprintf("The action ID must be an UnusedResourceID issue from the %s sheet", issueIDsSheetName))
+	return workbook.AddDataValidation(sheetName, actionIDValidation)
+}
+
+// writeIssueIDsSheet writes the lookup sheet used by the action ID dropdown.
+func writeIssueIDsSheet(workbook *excelize.File, replaceTargets []*IssueCsvRow, headerStyle int) error {
+	if _, err := workbook.NewSheet(issueIDsSheetName); err != nil {
+		return err
+	}
+	if err := writeSheetHeader(workbook, issueIDsSheetName, issueIDsHeader, len(replaceTargets)+1, headerStyle); err != nil {
+		return err
+	}
+	for i, row := range replaceTargets {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		values := []string{row.IssueID, row.ResourceAddress, row.ResourceName, row.ResourceType, row.ResourceLocation}
+		if err := workbook.SetSheetRow(issueIDsSheetName, cell, &values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSheetHeader writes a bold header row that is frozen and filterable.
+func writeSheetHeader(workbook *excelize.File, sheetName string, header []string, lastRow int, headerStyle int) error {
+	if err := workbook.SetSheetRow(sheetName, "A1", &header); err != nil {
+		return err
+	}
+	lastColumn, err := excelize.ColumnNumberToName(len(header))
+	if err != nil {
+		return err
+	}
+	if err := workbook.SetCellStyle(sheetName, "A1", lastColumn+"1", headerStyle); err != nil {
+		return err
+	}
+	if err := workbook.SetPanes(sheetName, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	return workbook.AutoFilter(sheetName, fmt.Sprintf("A1:%s%d", lastColumn, lastRow), nil)
+}
+
+func (xlsxClient *IssueXlsxClient) Import() (*map[string]types.Issue, error) {
+	workbook, err := excelize.OpenFile(xlsxClient.IssueXlsxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer workbook.Close()
+
+	records := []csvRecord{}
+	extraHeader := []string{}
+	issueSheetCount := 0
+	for _, sheetName := range workbook.GetSheetList() {
+		if indexOf(issueTypeNames(), sheetName) < 0 {
+			xlsxClient.Logger.Debugf("Skipping sheet %s as it is not an issue type", sheetName)
+			continue
+		}
+		issueSheetCount++
+
+		sheetRows, err := workbook.GetRows(sheetName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sheet %s: %w", sheetName, err)
+		}
+		if len(sheetRows) == 0 {
+			continue
+		}
+
+		header := sheetRows[0]
+		columns, sheetExtraHeader, err := parseHeader(header)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header in sheet %s: %w", sheetName, err)
+		}
+		for _, columnName := range sheetExtraHeader {
+			if indexOf(extraHeader, columnName) < 0 {
+				extraHeader = append(extraHeader, columnName)
+			}
+		}
+
+		for i, values := range sheetRows[1:] {
+			if isEmptyRecord(values) {
+				continue
+			}
+			// Trailing empty cells are not stored in the workbook
+			for len(values) < len(header) {
+				values = append(values, "")
+			}
+			records = append(records, csvRecord{Position: fmt.Sprintf("sheet %s row %d", sheetName, i+2), Columns: columns, Values: values})
+		}
+	}
+	if issueSheetCount == 0 {
+		return nil, fmt.Errorf("XLSX file %s has no issue sheets, expected sheets named %s", xlsxClient.IssueXlsxPath, strings.Join(issueTypeNames(), ", "))
+	}
+	xlsxClient.ExtraHeader = extraHeader
+
+	issues, err := importRecords(records, extraHeader, xlsxClient.Logger)
+	if err != nil {
+		return issues, fmt.Errorf("invalid XLSX file %s: %w", xlsxClient.IssueXlsxPath, err)
+	}
+	return issues, nil
+}
+
+func issueTypeNames() []string {
+	names := []string{}
+	for _, issueType := range xlsxIssueTypes {
+		names = append(names, string(issueType))
+	}
+	return names
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
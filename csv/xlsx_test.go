@@ -0,0 +1,164 @@
+package csv
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	testVnetAddress    = "module.network.azurerm_virtual_network.this"
+	testSubnetAddress  = "module.network.azurerm_subnet.this"
+	testVnetHubID      = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet-hub"
+	testVnetSpokeID    = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet-spoke"
+	testOrphanSubnetID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet-hub/subnets/old"
+)
+
+func getTestIssues() map[string]types.Issue {
+	vnetIssueID := types.GetIssueID(testVnetAddress)
+	subnetIssueID := types.GetIssueID(testSubnetAddress)
+	orphanIssueID := types.GetIssueID(testOrphanSubnetID)
+	return map[string]types.Issue{
+		vnetIssueID: {
+			IssueID:           vnetIssueID,
+			IssueType:         types.IssueTypeMultipleResourceIDs,
+			ResourceAddress:   testVnetAddress,
+			ResourceName:      "vnet",
+			ResourceType:      "azurerm_virtual_network",
+			ResourceLocation:  "uksouth",
+			MappedResourceIDs: []string{testVnetHubID, testVnetSpokeID},
+			CandidateScores:   []types.CandidateScore{{ResourceID: testVnetHubID, Score: 90, Reasons: []string{"name"}}, {ResourceID: testVnetSpokeID, Score: 40}},
+			ExtraColumns:      map[string]string{"Owner": "network team"},
+		},
+		subnetIssueID: {
+			IssueID:          subnetIssueID,
+			IssueType:        types.IssueTypeNoResourceID,
+			ResourceAddress:  testSubnetAddress,
+			ResourceName:     "new",
+			ResourceType:     "azurerm_subnet",
+			ResourceLocation: "uksouth",
+		},
+		orphanIssueID: {
+			IssueID:          orphanIssueID,
+			IssueType:        types.IssueTypeUnusedResourceID,
+			ResourceAddress:  testOrphanSubnetID,
+			ResourceName:     "old",
+			ResourceType:     "microsoft.network/virtualnetworks/subnets",
+			ResourceLocation: "uksouth",
+		},
+	}
+}
+
+func TestIssueXlsxClient_ExportAndImport(t *testing.T) {
+	workingFolderPath := t.TempDir()
+	issues := getTestIssues()
+	vnetIssueID := types.GetIssueID(testVnetAddress)
+	subnetIssueID := types.GetIssueID(testSubnetAddress)
+	orphanIssueID := types.GetIssueID(testOrphanSubnetID)
+
+	xlsxFilePath, err := NewIssueXlsxClient(workingFolderPath, "", logrus.New()).Export(issues)
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(workingFolderPath, "issues.xlsx"), xlsxFilePath)
+
+	workbook, err := excelize.OpenFile(xlsxFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"MultipleResourceIDs", "NoResourceID", "UnusedResourceID", "Issue IDs"}, workbook.GetSheetList())
+	assert.Equal(t, "MultipleResourceIDs", workbook.GetSheetName(workbook.GetActiveSheetIndex()))
+
+	// Each issue sheet has a bold header that is frozen and filterable, with the extra columns after the known ones
+	filterRanges := map[string]string{}
+	for _, definedName := range workbook.GetDefinedName() {
+		if definedName.Name == "_xlnm._FilterDatabase" {
+			filterRanges[definedName.Scope] = definedName.RefersTo
+		}
+	}
+	expectedFilterRanges := map[string]string{
+		"MultipleResourceIDs": "'MultipleResourceIDs'!$A$1:$O$3",
+		"NoResourceID":        "'NoResourceID'!$A$1:$O$2",
+		"UnusedResourceID":    "'UnusedResourceID'!$A$1:$O$2",
+		"Issue IDs":           "'Issue IDs'!$A$1:$E$2",
+	}
+	assert.Equal(t, expectedFilterRanges, filterRanges)
+	for sheetName := range expectedFilterRanges {
+		panes, err := workbook.GetPanes(sheetName)
+		assert.NoError(t, err)
+		assert.True(t, panes.Freeze, sheetName)
+		assert.Equal(t, 1, panes.YSplit, sheetName)
+		assert.Equal(t, "A2", panes.TopLeftCell, sheetName)
+		styleID, err := workbook.GetCellStyle(sheetName, "A1")
+		assert.NoError(t, err)
+		style, err := workbook.GetStyle(styleID)
+		assert.NoError(t, err)
+		assert.True(t, style.Font != nil && style.Font.Bold, sheetName)
+	}
+
+	header, err := workbook.GetRows("MultipleResourceIDs")
+	assert.NoError(t, err)
+	assert.Equal(t, append(append(append([]string{}, legacyHeader...), optionalHeader...), "Owner"), header[0])
+	// Candidates are sorted by score and the score is written as a number, which is stored without a cell type
+	assert.Equal(t, testVnetHubID, header[1][7])
+	assert.Equal(t, "90", header[1][10])
+	matchScoreType, err := workbook.GetCellType("MultipleResourceIDs", "K2")
+	assert.NoError(t, err)
+	assert.Equal(t, excelize.CellTypeUnset, matchScoreType)
+
+	issueIDs, err := workbook.GetRows("Issue IDs")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{issueIDsHeader, {orphanIssueID, testOrphanSubnetID, "old", "microsoft.network/virtualnetworks/subnets", "uksouth"}}, issueIDs)
+
+	// The action dropdowns only offer the actions valid for the sheet, the action ID dropdown lists the Issue IDs sheet
+	expectedValidations := map[string][]string{
+		"MultipleResourceIDs": {"I2:I3", `"Use,Ignore"`},
+		"NoResourceID":        {"I2:I2", `"Ignore,Replace"`, "J2:J2", "'Issue IDs'!$A$2:$A$2"},
+		"UnusedResourceID":    {"I2:I2", `"Ignore,Replace,Destroy,Forget"`},
+	}
+	for sheetName, expected := range expectedValidations {
+		dataValidations, err := workbook.GetDataValidations(sheetName)
+		assert.NoError(t, err)
+		actual := []string{}
+		for _, dataValidation := range dataValidations {
+			assert.Equal(t, "list", dataValidation.Type)
+			assert.True(t, dataValidation.ShowErrorMessage)
+			actual = append(actual, dataValidation.Sqref, dataValidation.Formula1)
+		}
+		assert.Equal(t, expected, actual, sheetName)
+	}
+
+	// Resolve every issue as a reviewer would
+	assert.NoError(t, workbook.SetCellValue("MultipleResourceIDs", "I2", "Use"))
+	assert.NoError(t, workbook.SetCellValue("MultipleResourceIDs", "I3", "Ignore"))
+	assert.NoError(t, workbook.SetCellValue("NoResourceID", "I2", "Replace"))
+	assert.NoError(t, workbook.SetCellValue("NoResourceID", "J2", orphanIssueID))
+	assert.NoError(t, workbook.SetCellValue("UnusedResourceID", "I2", "Replace"))
+	assert.NoError(t, workbook.Save())
+	assert.NoError(t, workbook.Close())
+
+	xlsxClient := NewIssueXlsxClient(workingFolderPath, xlsxFilePath, logrus.New())
+	importedIssues, err := xlsxClient.Import()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Owner"}, xlsxClient.ExtraHeader)
+	assert.Len(t, *importedIssues, 3)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeUse}, (*importedIssues)[vnetIssueID].Resolution)
+	assert.Equal(t, []string{testVnetHubID}, (*importedIssues)[vnetIssueID].MappedResourceIDs)
+	assert.Equal(t, map[string]string{"Owner": "network team"}, (*importedIssues)[vnetIssueID].ExtraColumns)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeReplace, ActionID: orphanIssueID}, (*importedIssues)[subnetIssueID].Resolution)
+	assert.Equal(t, "uksouth", (*importedIssues)[subnetIssueID].ResourceLocation)
+	assert.Equal(t, types.IssueResolution{ActionType: types.ActionTypeReplace}, (*importedIssues)[orphanIssueID].Resolution)
+}
+
+func TestIssueXlsxClient_Import_NoIssueSheets(t *testing.T) {
+	xlsxFilePath := filepath.Join(t.TempDir(), "issues.xlsx")
+	workbook := excelize.NewFile()
+	assert.NoError(t, workbook.SaveAs(xlsxFilePath))
+	assert.NoError(t, workbook.Close())
+
+	_, err := NewIssueXlsxClient(".", xlsxFilePath, logrus.New()).Import()
+
+	assert.ErrorContains(t, err, "has no issue sheets")
+}
@@ -0,0 +1,94 @@
+package hcl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// forEachKeyRegex matches the for_each key at the end of an address, e.g. ["hub"] in azurerm_virtual_network.this["hub"]
+var forEachKeyRegex = regexp.MustCompile(`\[("(?:[^"\\]|\\.)*")\]$`)
+
+// groupImportBlocks groups the import blocks of the for_each instances of the same resource, keeping the order of the
+// first import block of each group. Resources with a single instance, or with count indexes, are kept on their own.
+func groupImportBlocks(importBlocks []types.ImportBlock) [][]types.ImportBlock {
+	importBlockGroups := [][]types.ImportBlock{}
+	groupIndexes := map[string]int{}
+	for _, importBlock := range importBlocks {
+		addressTemplate, _, ok := splitForEachKey(importBlock.To)
+		if !ok {
+			importBlockGroups = append(importBlockGroups, []types.ImportBlock{importBlock})
+			continue
+		}
+		if index, exists := groupIndexes[addressTemplate]; exists {
+			importBlockGroups[index] = append(importBlockGroups[index], importBlock)
+			continue
+		}
+		groupIndexes[addressTemplate] = len(importBlockGroups)
+		importBlockGroups = append(importBlockGroups, []types.ImportBlock{importBlock})
+	}
+	return importBlockGroups
+}
+
+// splitForEachKey splits an address into the address without its for_each key and the key.
+func splitForEachKey(address string) (string, string, bool) {
+	match := forEachKeyRegex.FindStringSubmatchIndex(address)
+	if match == nil {
+		return address, "", false
+	}
+	forEachKey, err := strconv.Unquote(address[match[2]:match[3]])
+	if err != nil {
+		return address, "", false
+	}
+	return address[:match[0]], forEachKey, true
+}
+
+// readForEachImportBlocks expands an import block with a literal for_each map into an import block for each key, so
+// they can be verified one by one. The to address must be indexed by each.key and the id must be each.value.
+func readForEachImportBlocks(block *hclsyntax.Block, content []byte) ([]types.ImportBlock, error) {
+	forEach, diagnostics := block.Body.Attributes["for_each"].Expr.Value(nil)
+	if diagnostics.HasErrors() || !forEach.IsWhollyKnown() || forEach.IsNull() || !(forEach.Type().IsMapType() || forEach.Type().IsObjectType()) {
+		return nil, fmt.Errorf("for_each at line %d must be a literal map of addresses to resource IDs", block.Range().Start.Line)
+	}
+
+	to, ok := block.Body.Attributes["to"]
+	if !ok {
+		return nil, fmt.Errorf("import block at line %d has no to address", block.Range().Start.Line)
+	}
+	addressTemplate := string(to.Expr.Range().SliceBytes(content))
+	if !strings.HasSuffix(addressTemplate, "[each.key]") {
+		return nil, fmt.Errorf("to address %s at line %d must be indexed by each.key", addressTemplate, block.Range().Start.Line)
+	}
+	if id, ok := block.Body.Attributes["id"]; !ok || string(id.Expr.Range().SliceBytes(content)) != "each.value" {
+		return nil, fmt.Errorf("id at line %d must be each.value", block.Range().Start.Line)
+	}
+
+	forEachKeys := []string{}
+	forEachValues := map[string]cty.Value{}
+	for iterator := forEach.ElementIterator(); iterator.Next(); {
+		key, value := iterator.Element()
+		forEachKeys = append(forEachKeys, key.AsString())
+		forEachValues[key.AsString()] = value
+	}
+	sort.Strings(forEachKeys)
+
+	importBlocks := []types.ImportBlock{}
+	for _, forEachKey := range forEachKeys {
+		value := forEachValues[forEachKey]
+		if value.IsNull() || value.Type() != cty.String {
+			return nil, fmt.Errorf("for_each value for key %q at line %d must be a resource ID", forEachKey, block.Range().Start.Line)
+		}
+		importBlocks = append(importBlocks, types.ImportBlock{
+			To: fmt.Sprintf("%s[%s]", strings.TrimSuffix(addressTemplate, "[each.key]"), strconv.Quote(forEachKey)),
+			ID: value.AsString(),
+		})
+	}
+	return importBlocks, nil
+}
@@ -0,0 +1,128 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+func Test_splitForEachKey(t *testing.T) {
+	tests := []struct {
+		address         string
+		addressTemplate string
+		forEachKey      string
+		ok              bool
+	}{
+		{`azurerm_virtual_network.this["hub"]`, "azurerm_virtual_network.this", "hub", true},
+		{`module.hub["a"].azurerm_subnet.this["x.y"]`, `module.hub["a"].azurerm_subnet.this`, "x.y", true},
+		{`azurerm_subnet.this["say \"hi\""]`, "azurerm_subnet.this", `say "hi"`, true},
+		{`azurerm_subnet.this["a\\b"]`, "azurerm_subnet.this", `a\b`, true},
+		{"azurerm_subnet.this[0]", "azurerm_subnet.this[0]", "", false},
+		{`module.hub["a"].azurerm_subnet.this`, `module.hub["a"].azurerm_subnet.this`, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			addressTemplate, forEachKey, ok := splitForEachKey(test.address)
+			assert.Equal(t, test.addressTemplate, addressTemplate)
+			assert.Equal(t, test.forEachKey, forEachKey)
+			assert.Equal(t, test.ok, ok)
+		})
+	}
+}
+
+func Test_groupImportBlocks(t *testing.T) {
+	importBlocks := []types.ImportBlock{
+		{To: `azurerm_subnet.this["say \"hi\""]`, ID: "subnet-hi"},
+		{To: "azurerm_resource_group.this", ID: "rg"},
+		{To: "azurerm_public_ip.this[0]", ID: "pip-0"},
+		{To: `azurerm_virtual_network.this["hub"]`, ID: "vnet-hub"},
+		{To: `azurerm_subnet.this["a"]`, ID: "subnet-a"},
+		{To: "azurerm_public_ip.this[1]", ID: "pip-1"},
+		{To: `module.spoke["b"].azurerm_subnet.this["a"]`, ID: "spoke-subnet-a"},
+	}
+
+	importBlockGroups := groupImportBlocks(importBlocks)
+
+	assert.Equal(t, [][]types.ImportBlock{
+		{importBlocks[0], importBlocks[4]},
+		{importBlocks[1]},
+		{importBlocks[2]},
+		{importBlocks[3]},
+		{importBlocks[5]},
+		{importBlocks[6]},
+	}, importBlockGroups)
+}
+
+func TestHclClient_WriteImportBlocks_ImportForEach(t *testing.T) {
+	modulePath := t.TempDir()
+	hclClient := NewHclClient(modulePath, nil, true, logrus.New())
+	importBlocks := []types.ImportBlock{
+		{To: `module.hub["a"].azurerm_subnet.this["say \"hi\""]`, ID: "/subscriptions/sub/subnets/hi"},
+		{To: "azurerm_public_ip.this[0]", ID: "/subscriptions/sub/publicIPAddresses/pip-0"},
+		{To: `module.hub["a"].azurerm_subnet.this["firewall"]`, ID: "/subscriptions/sub/subnets/firewall"},
+		{To: `azurerm_virtual_network.this["hub"]`, ID: "/subscriptions/sub/virtualNetworks/hub"},
+	}
+
+	_, err := hclClient.WriteImportBlocks(importBlocks, "imports.tf")
+
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(modulePath, "imports.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, `import {
+  for_each = {
+    firewall     = "/subscriptions/sub/subnets/firewall"
+    "say \"hi\"" = "/subscriptions/sub/subnets/hi"
+  }
+  to = module.hub["a"].azurerm_subnet.this[each.key]
+  id = each.value
+}
+
+import {
+  id = "/subscriptions/sub/publicIPAddresses/pip-0"
+  to = azurerm_public_ip.this[0]
+}
+
+import {
+  id = "/subscriptions/sub/virtualNetworks/hub"
+  to = azurerm_virtual_network.this["hub"]
+}
+
+`, string(content))
+
+	// Verify reads the for_each block back as an import block for each key, sorted by key
+	readImportBlocks, err := hclClient.ReadImportBlocks("imports*.tf")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []types.ImportBlock{importBlocks[2], importBlocks[0], importBlocks[1], importBlocks[3]}, readImportBlocks)
+}
+
+func Test_readForEachImportBlocks_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"variable for_each", "import {\n  for_each = var.subnets\n  to = azurerm_subnet.this[each.key]\n  id = each.value\n}\n", "for_each at line 1 must be a literal map of addresses to resource IDs"},
+		{"list for_each", "import {\n  for_each = [\"a\"]\n  to = azurerm_subnet.this[each.key]\n  id = each.value\n}\n", "for_each at line 1 must be a literal map of addresses to resource IDs"},
+		{"to not indexed by key", "import {\n  for_each = { a = \"id-a\" }\n  to = azurerm_subnet.this[each.value]\n  id = each.value\n}\n", "to address azurerm_subnet.this[each.value] at line 1 must be indexed by each.key"},
+		{"id not value", "import {\n  for_each = { a = \"id-a\" }\n  to = azurerm_subnet.this[each.key]\n  id = \"id-a\"\n}\n", "id at line 1 must be each.value"},
+		{"value not an ID", "import {\n  for_each = { a = 1 }\n  to = azurerm_subnet.this[each.key]\n  id = each.value\n}\n", `for_each value for key "a" at line 1 must be a resource ID`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, diagnostics := hclsyntax.ParseConfig([]byte(test.content), "imports.tf", hcl.InitialPos)
+			assert.False(t, diagnostics.HasErrors())
+
+			_, err := readForEachImportBlocks(file.Body.(*hclsyntax.Body).Blocks[0], []byte(test.content))
+
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package hcl
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// FormatValue renders a JSON compatible value as an HCL expression, e.g. for use as a module variable value.
+func FormatValue(value any) (string, error) {
+	if value == nil {
+		return "null", nil
+	}
+
+	ctyValue, err := ToCtyValue(value)
+	if err != nil {
+		return "", err
+	}
+	return string(hclwrite.TokensForValue(ctyValue).Bytes()), nil
+}
+
+// ToCtyValue converts a JSON compatible value into a cty value.
+func ToCtyValue(value any) (cty.Value, error) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ctyType, err := ctyjson.ImpliedType(jsonValue)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(jsonValue, ctyType)
+}
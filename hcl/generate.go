@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/sirupsen/logrus"
 	"github.com/zclconf/go-cty/cty"
@@ -15,25 +16,37 @@ import (
 )
 
 type IHclClient interface {
-	WriteImportBlocks(resources []types.ImportBlock, fileName string)
-	WriteDestroyBlocks(resources []types.DestroyBlock, fileName string)
-	CleanFiles(filesToRemove []string)
+	WriteImportBlocks(resources []types.ImportBlock, fileName string) (string, error)
+	WriteDestroyBlocks(resources []types.DestroyBlock, fileName string) (string, error)
+	WriteMovedBlocks(movedBlocks []types.MovedBlock, fileName string) (string, error)
+	WriteRemovedBlocks(removedBlocks []types.RemovedBlock, fileName string) (string, error)
+	WriteVariableValues(variableValues []types.VariableValue, filePath string) error
+	CleanFiles(filesToRemove []string) error
+	SetFilesAside(fileNames []string) (func(), error)
+	ReadImportBlocks(fileName string) ([]types.ImportBlock, error)
 }
 
 type HclClient struct {
 	TerraformModulePath string
 	DeleteCommands      []types.DeleteCommand
-	Logger              *logrus.Logger
+	// ImportForEach groups the imports of the for_each instances of a resource into one import block
+	ImportForEach bool
+	Logger        *logrus.Logger
 }
 
-func NewHclClient(terraformModulePath string, deleteCommands []types.DeleteCommand, logger *logrus.Logger) *HclClient {
+func NewHclClient(terraformModulePath string, deleteCommands []types.DeleteCommand, importForEach bool, logger *logrus.Logger) *HclClient {
 	return &HclClient{
 		TerraformModulePath: terraformModulePath,
 		DeleteCommands:      deleteCommands,
+		ImportForEach:       importForEach,
 		Logger:              logger,
 	}
 }
 
+// setAsideFileSuffix is added to the generated files that are set aside while the module is planned. Terraform only
+// reads .tf files, so the renamed files are not planned.
+const setAsideFileSuffix = ".terraform-state-importer.bak"
+
 const defaultDeleteCommand = `$resourceID = (az resource show --ids %s | ConvertFrom-Json | Select-Object -ExpandProperty id)
 if ($resourceID -ne $null) {
 	Write-Host "Deleting resource..."
@@ -42,29 +55,96 @@ if ($resourceID -ne $null) {
 	Write-Host "Resource not found, skipping deletion."
 }`
 
-func (hclClient *HclClient) WriteImportBlocks(importBlocks []types.ImportBlock, fileName string) {
+func (hclClient *HclClient) WriteImportBlocks(importBlocks []types.ImportBlock, fileName string) (string, error) {
 	hclFilePath := filepath.Join(hclClient.TerraformModulePath, fileName)
 	hclFile := hclwrite.NewEmptyFile()
 
-	for _, importBlock := range importBlocks {
+	importBlockGroups := [][]types.ImportBlock{}
+	if hclClient.ImportForEach {
+		importBlockGroups = groupImportBlocks(importBlocks)
+	} else {
+		for _, importBlock := range importBlocks {
+			importBlockGroups = append(importBlockGroups, []types.ImportBlock{importBlock})
+		}
+	}
+
+	for _, importBlockGroup := range importBlockGroups {
 		resourceBlock := hclFile.Body().AppendNewBlock("import", nil)
-		resourceBlock.Body().SetAttributeValue("id", cty.StringVal(importBlock.ID))
-		traversal := hcl.Traversal{
-			hcl.TraverseRoot{Name: importBlock.To},
+		if len(importBlockGroup) == 1 {
+			resourceBlock.Body().SetAttributeValue("id", cty.StringVal(importBlockGroup[0].ID))
+			traversal := hcl.Traversal{
+				hcl.TraverseRoot{Name: importBlockGroup[0].To},
+			}
+			resourceBlock.Body().SetAttributeTraversal("to", traversal)
+			hclFile.Body().AppendNewline()
+			continue
+		}
+
+		forEach := map[string]cty.Value{}
+		for _, importBlock := range importBlockGroup {
+			_, forEachKey, _ := splitForEachKey(importBlock.To)
+			forEach[forEachKey] = cty.StringVal(importBlock.ID)
 		}
-		resourceBlock.Body().SetAttributeTraversal("to", traversal)
+		addressTemplate, _, _ := splitForEachKey(importBlockGroup[0].To)
+		resourceBlock.Body().SetAttributeValue("for_each", cty.MapVal(forEach))
+		resourceBlock.Body().SetAttributeTraversal("to", hcl.Traversal{hcl.TraverseRoot{Name: addressTemplate + "[each.key]"}})
+		resourceBlock.Body().SetAttributeTraversal("id", hcl.Traversal{hcl.TraverseRoot{Name: "each.value"}})
 		hclFile.Body().AppendNewline()
 	}
 
 	err := os.WriteFile(hclFilePath, hclFile.Bytes(), 0644)
 	if err != nil {
-		hclClient.Logger.Fatal("Error writing file: ", err)
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
 	hclClient.Logger.Infof("HCL imports file %s written to: %s", fileName, hclFilePath)
+	return hclFilePath, nil
+}
+
+// WriteMovedBlocks writes a moved block for each resource that is already in state under another address.
+func (hclClient *HclClient) WriteMovedBlocks(movedBlocks []types.MovedBlock, fileName string) (string, error) {
+	hclFilePath := filepath.Join(hclClient.TerraformModulePath, fileName)
+	hclFile := hclwrite.NewEmptyFile()
+
+	for _, movedBlock := range movedBlocks {
+		resourceBlock := hclFile.Body().AppendNewBlock("moved", nil)
+		resourceBlock.Body().SetAttributeTraversal("from", hcl.Traversal{hcl.TraverseRoot{Name: movedBlock.From}})
+		resourceBlock.Body().SetAttributeTraversal("to", hcl.Traversal{hcl.TraverseRoot{Name: movedBlock.To}})
+		hclFile.Body().AppendNewline()
+	}
+
+	err := os.WriteFile(hclFilePath, hclFile.Bytes(), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	hclClient.Logger.Infof("HCL moved file %s written to: %s", fileName, hclFilePath)
+	return hclFilePath, nil
+}
+
+// WriteRemovedBlocks writes a removed block for each resource that leaves the state without being destroyed.
+func (hclClient *HclClient) WriteRemovedBlocks(removedBlocks []types.RemovedBlock, fileName string) (string, error) {
+	hclFilePath := filepath.Join(hclClient.TerraformModulePath, fileName)
+	hclFile := hclwrite.NewEmptyFile()
+
+	for _, removedBlock := range removedBlocks {
+		resourceBlock := hclFile.Body().AppendNewBlock("removed", nil)
+		resourceBlock.Body().SetAttributeTraversal("from", hcl.Traversal{hcl.TraverseRoot{Name: removedBlock.From}})
+		lifecycleBlock := resourceBlock.Body().AppendNewBlock("lifecycle", nil)
+		lifecycleBlock.Body().SetAttributeValue("destroy", cty.False)
+		hclFile.Body().AppendNewline()
+	}
+
+	err := os.WriteFile(hclFilePath, hclFile.Bytes(), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	hclClient.Logger.Infof("HCL removed file %s written to: %s", fileName, hclFilePath)
+	return hclFilePath, nil
 }
 
-func (hclClient *HclClient) WriteDestroyBlocks(destroyBlocks []types.DestroyBlock, fileName string) {
+func (hclClient *HclClient) WriteDestroyBlocks(destroyBlocks []types.DestroyBlock, fileName string) (string, error) {
 	hclFilePath := filepath.Join(hclClient.TerraformModulePath, fileName)
 	hclFile := hclwrite.NewEmptyFile()
 
@@ -89,20 +169,249 @@ func (hclClient *HclClient) WriteDestroyBlocks(destroyBlocks []types.DestroyBloc
 
 	err := os.WriteFile(hclFilePath, hclFile.Bytes(), 0644)
 	if err != nil {
-		hclClient.Logger.Fatal("Error writing file: ", err)
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
 	hclClient.Logger.Infof("HCL imports file %s written to: %s", fileName, hclFilePath)
+	return hclFilePath, nil
+}
+
+func (hclClient *HclClient) WriteVariableValues(variableValues []types.VariableValue, filePath string) error {
+	hclFile := hclwrite.NewEmptyFile()
+
+	if err := checkVariablePaths(variableValues); err != nil {
+		return err
+	}
+
+	// Dotted variable names such as hub.address_space are nested into an object under the top level variable
+	variableNames := []string{}
+	variables := map[string]map[string]any{}
+	conflicts := map[string][]types.VariableValue{}
+	for _, variableValue := range variableValues {
+		path := strings.Split(variableValue.Variable, ".")
+		variableName := path[0]
+		if _, exists := variables[variableName]; !exists {
+			variableNames = append(variableNames, variableName)
+			variables[variableName] = map[string]any{}
+		}
+
+		values := variables[variableName]
+		if len(path) == 1 {
+			values[""] = variableValue.Value
+		} else {
+			for _, key := range path[1 : len(path)-1] {
+				if _, ok := values[key].(map[string]any); !ok {
+					values[key] = map[string]any{}
+				}
+				values = values[key].(map[string]any)
+			}
+			values[path[len(path)-1]] = variableValue.Value
+		}
+
+		if variableValue.Conflicting {
+			conflicts[variableName] = append(conflicts[variableName], variableValue)
+		}
+	}
+
+	for _, variableName := range variableNames {
+		for _, conflict := range conflicts[variableName] {
+			comments := []string{fmt.Sprintf("# Conflicting values found in Azure for %s, using the most common value:", conflict.Variable)}
+			for _, source := range conflict.Sources {
+				sourceValue, err := FormatValue(source.Value)
+				if err != nil {
+					sourceValue = fmt.Sprintf("%v", source.Value)
+				}
+				comments = append(comments, fmt.Sprintf("#   %s: %s", source.ResourceAddress, sourceValue))
+			}
+			hclFile.Body().AppendUnstructuredTokens(hclwrite.Tokens{
+				{Type: hclsyntax.TokenComment, Bytes: []byte(strings.Join(comments, "\n") + "\n")},
+			})
+		}
+
+		var value any = variables[variableName]
+		if topLevelValue, ok := variables[variableName][""]; ok {
+			value = topLevelValue
+		}
+
+		ctyValue, err := ToCtyValue(value)
+		if err != nil {
+			return fmt.Errorf("failed to convert value for variable %s: %w", variableName, err)
+		}
+		hclFile.Body().SetAttributeValue(variableName, ctyValue)
+		hclFile.Body().AppendNewline()
+	}
+
+	err := os.WriteFile(filePath, hclwrite.Format(hclFile.Bytes()), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	hclClient.Logger.Infof("Suggested variable values written to: %s", filePath)
+	return nil
+}
+
+// checkVariablePaths fails when a variable is set both as a whole and through dotted names below it, such as hub and
+// hub.address_space, as one would replace the other.
+func checkVariablePaths(variableValues []types.VariableValue) error {
+	for _, variableValue := range variableValues {
+		for _, nestedVariableValue := range variableValues {
+			if strings.HasPrefix(nestedVariableValue.Variable, variableValue.Variable+".") {
+				return fmt.Errorf("variable mappings %s and %s conflict, %s is set as a whole and has values nested below it", variableValue.Variable, nestedVariableValue.Variable, variableValue.Variable)
+			}
+		}
+	}
+	return nil
+}
+
+// CleanFiles removes previously generated files from the module. File names can be patterns, such as imports.*.tf.
+func (hclClient *HclClient) CleanFiles(filesToRemove []string) error {
+	filePaths, err := hclClient.getMatchingFiles(filesToRemove)
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range filePaths {
+		hclClient.Logger.Debugf("File %s already exists, it will be deleted", filePath)
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete existing file %s: %w", filePath, err)
+		}
+	}
+	return nil
+}
+
+// SetFilesAside renames previously generated files in the module to backup files, so the module can be planned without
+// them, and returns a function that restores them. File names can be patterns, as for CleanFiles.
+func (hclClient *HclClient) SetFilesAside(fileNames []string) (func(), error) {
+	filePaths, err := hclClient.getMatchingFiles(fileNames)
+	if err != nil {
+		return nil, err
+	}
+	for _, filePath := range filePaths {
+		backupFilePath := filePath + setAsideFileSuffix
+		if _, err := os.Stat(backupFilePath); err == nil {
+			return nil, fmt.Errorf("backup file %s already exists from a previous run, restore it to %s or remove it", backupFilePath, filePath)
+		}
+	}
+
+	setAsideFilePaths := []string{}
+	restoreFiles := func() {
+		for _, filePath := range setAsideFilePaths {
+			backupFilePath := filePath + setAsideFileSuffix
+			hclClient.Logger.Debugf("Restoring file %s from %s", filePath, backupFilePath)
+			if err := os.Rename(backupFilePath, filePath); err != nil {
+				hclClient.Logger.Errorf("Failed to restore file %s, rename %s to %s before running terraform in the module: %v", filePath, backupFilePath, filePath, err)
+			}
+		}
+	}
+	for _, filePath := range filePaths {
+		hclClient.Logger.Debugf("Setting aside file %s until the plan is done", filePath)
+		if err := os.Rename(filePath, filePath+setAsideFileSuffix); err != nil {
+			restoreFiles()
+			return nil, fmt.Errorf("failed to set aside file %s: %w", filePath, err)
+		}
+		setAsideFilePaths = append(setAsideFilePaths, filePath)
+	}
+	return restoreFiles, nil
+}
+
+// getMatchingFiles returns the paths of the files in the module matching any of the file names or patterns.
+func (hclClient *HclClient) getMatchingFiles(fileNames []string) ([]string, error) {
+	entries, err := os.ReadDir(hclClient.TerraformModulePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read module folder %s: %w", hclClient.TerraformModulePath, err)
+	}
+
+	filePaths := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, fileName := range fileNames {
+			matched, err := filepath.Match(fileName, entry.Name())
+			if err != nil {
+				return nil, fmt.Errorf("invalid file pattern %s: %w", fileName, err)
+			}
+			if matched {
+				filePaths = append(filePaths, filepath.Join(hclClient.TerraformModulePath, entry.Name()))
+				break
+			}
+		}
+	}
+	return filePaths, nil
+}
+
+// ReadImportBlocks reads the import blocks from the files in the module matching a file name or pattern, such as
+// imports*.tf for imports split across files, keeping the to address as written.
+func (hclClient *HclClient) ReadImportBlocks(fileName string) ([]types.ImportBlock, error) {
+	entries, err := os.ReadDir(hclClient.TerraformModulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	importBlocks := []types.ImportBlock{}
+	matchedFiles := 0
+	for _, entry := range entries {
+		matched, err := filepath.Match(fileName, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %s: %w", fileName, err)
+		}
+		if !matched || entry.IsDir() {
+			continue
+		}
+		matchedFiles++
+		fileImportBlocks, err := readImportBlocksFromFile(filepath.Join(hclClient.TerraformModulePath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		importBlocks = append(importBlocks, fileImportBlocks...)
+	}
+	if matchedFiles == 0 {
+		return nil, fmt.Errorf("failed to read file: no file matching %s in %s", fileName, hclClient.TerraformModulePath)
+	}
+	return importBlocks, nil
 }
 
-func (hclClient *HclClient) CleanFiles(filesToRemove []string) {
-	for _, fileName := range filesToRemove {
-		filePath := filepath.Join(hclClient.TerraformModulePath, fileName)
-		if _, err := os.Stat(filePath); err == nil {
-			hclClient.Logger.Debugf("File %s already exists, it will be deleted", filePath)
-			if err := os.Remove(filePath); err != nil {
-				hclClient.Logger.Fatalf("Error deleting existing imports file: %v", err)
+func readImportBlocksFromFile(hclFilePath string) ([]types.ImportBlock, error) {
+	content, err := os.ReadFile(hclFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	file, diagnostics := hclsyntax.ParseConfig(content, hclFilePath, hcl.InitialPos)
+	if diagnostics.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %s", hclFilePath, diagnostics.Error())
+	}
+
+	importBlocks := []types.ImportBlock{}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "import" {
+			continue
+		}
+		if _, ok := block.Body.Attributes["for_each"]; ok {
+			forEachImportBlocks, err := readForEachImportBlocks(block, content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read import block in %s: %w", hclFilePath, err)
+			}
+			importBlocks = append(importBlocks, forEachImportBlocks...)
+			continue
+		}
+
+		importBlock := types.ImportBlock{}
+		if attribute, ok := block.Body.Attributes["to"]; ok {
+			importBlock.To = string(attribute.Expr.Range().SliceBytes(content))
+		}
+		if attribute, ok := block.Body.Attributes["id"]; ok {
+			value, diagnostics := attribute.Expr.Value(nil)
+			if !diagnostics.HasErrors() && value.Type() == cty.String && value.IsKnown() && !value.IsNull() {
+				importBlock.ID = value.AsString()
+			} else {
+				importBlock.ID = string(attribute.Expr.Range().SliceBytes(content))
 			}
 		}
+		importBlocks = append(importBlocks, importBlock)
 	}
+	return importBlocks, nil
 }
@@ -0,0 +1,102 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+func getModuleFileNames(t *testing.T, modulePath string) []string {
+	entries, err := os.ReadDir(modulePath)
+	assert.NoError(t, err)
+	fileNames := []string{}
+	for _, entry := range entries {
+		fileNames = append(fileNames, entry.Name())
+	}
+	sort.Strings(fileNames)
+	return fileNames
+}
+
+func TestHclClient_SetFilesAside(t *testing.T) {
+	modulePath := t.TempDir()
+	for _, fileName := range []string{"main.tf", "imports.tf", "imports.network.tf", "moved.tf"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(modulePath, fileName), []byte("# "+fileName+"\n"), 0644))
+	}
+	hclClient := NewHclClient(modulePath, nil, false, logrus.New())
+
+	restoreFiles, err := hclClient.SetFilesAside([]string{"imports.tf", "imports.*.tf", "destroy.tf"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"imports.network.tf" + setAsideFileSuffix,
+		"imports.tf" + setAsideFileSuffix,
+		"main.tf",
+		"moved.tf",
+	}, getModuleFileNames(t, modulePath))
+
+	restoreFiles()
+
+	assert.Equal(t, []string{"imports.network.tf", "imports.tf", "main.tf", "moved.tf"}, getModuleFileNames(t, modulePath))
+	content, err := os.ReadFile(filepath.Join(modulePath, "imports.network.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# imports.network.tf\n", string(content))
+}
+
+func TestHclClient_SetFilesAside_BackupExists(t *testing.T) {
+	modulePath := t.TempDir()
+	for _, fileName := range []string{"imports.tf", "moved.tf", "moved.tf" + setAsideFileSuffix} {
+		assert.NoError(t, os.WriteFile(filepath.Join(modulePath, fileName), []byte{}, 0644))
+	}
+	hclClient := NewHclClient(modulePath, nil, false, logrus.New())
+
+	_, err := hclClient.SetFilesAside([]string{"imports.tf", "moved.tf"})
+
+	assert.ErrorContains(t, err, "moved.tf"+setAsideFileSuffix+" already exists from a previous run")
+	// Nothing is renamed when a backup is in the way
+	assert.Equal(t, []string{"imports.tf", "moved.tf", "moved.tf" + setAsideFileSuffix}, getModuleFileNames(t, modulePath))
+}
+
+func TestHclClient_WriteVariableValues_NestedVariables(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "terraform.tfvars")
+	hclClient := NewHclClient(t.TempDir(), nil, false, logrus.New())
+
+	err := hclClient.WriteVariableValues([]types.VariableValue{
+		{Variable: "hub.address_space", Value: []any{"10.0.0.0/16"}},
+		{Variable: "hub.firewall.sku", Value: "Premium"},
+		{Variable: "location", Value: "uksouth"},
+	}, filePath)
+
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `address_space = ["10.0.0.0/16"]`)
+	assert.Contains(t, string(content), `sku = "Premium"`)
+	assert.Contains(t, string(content), `location = "uksouth"`)
+}
+
+func TestHclClient_WriteVariableValues_ConflictingPaths(t *testing.T) {
+	tests := []struct {
+		name           string
+		variableValues []types.VariableValue
+		expected       string
+	}{
+		{"top level and nested", []types.VariableValue{{Variable: "hub.address_space", Value: "10.0.0.0/16"}, {Variable: "hub", Value: map[string]any{}}}, "variable mappings hub and hub.address_space conflict, hub is set as a whole and has values nested below it"},
+		{"nested levels", []types.VariableValue{{Variable: "hub.firewall", Value: "fw"}, {Variable: "hub.firewall.sku", Value: "Premium"}}, "variable mappings hub.firewall and hub.firewall.sku conflict, hub.firewall is set as a whole and has values nested below it"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "terraform.tfvars")
+
+			err := NewHclClient(t.TempDir(), nil, false, logrus.New()).WriteVariableValues(test.variableValues, filePath)
+
+			assert.EqualError(t, err, test.expected)
+			assert.NoFileExists(t, filePath)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package json
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -9,8 +10,8 @@ import (
 )
 
 type IJsonClient interface {
-	Export(resources any, fileName string)
-	Import(fileName string) map[string]any
+	Export(resources any, fileName string) (string, error)
+	Import(fileName string) (map[string]any, error)
 }
 
 type JsonClient struct {
@@ -25,30 +26,31 @@ func NewJsonClient(workingFolderPath string, logger *logrus.Logger) *JsonClient
 	}
 }
 
-func (jsonClient *JsonClient) Export(resources any, fileName string) {
+func (jsonClient *JsonClient) Export(resources any, fileName string) (string, error) {
 	jsonResources, err := json.Marshal(resources)
 	if err != nil {
-		jsonClient.Logger.Fatal("Error during Marshal(): ", err)
+		return "", fmt.Errorf("failed to marshal %s: %w", fileName, err)
 	}
 	jsonFilePath := filepath.Join(jsonClient.WorkingFolderPath, fileName)
 	err = os.WriteFile(jsonFilePath, jsonResources, 0644)
 	if err != nil {
-		jsonClient.Logger.Fatal("Error writing file: ", err)
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
+	return jsonFilePath, nil
 }
 
-func (jsonClient *JsonClient) Import(fileName string) map[string]any {
+func (jsonClient *JsonClient) Import(fileName string) (map[string]any, error) {
 	jsonFilePath := filepath.Join(jsonClient.WorkingFolderPath, fileName)
 
 	content, err := os.ReadFile(jsonFilePath)
 	if err != nil {
-		jsonClient.Logger.Fatal("Error when opening file: ", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
 	var payload map[string]any
 	err = json.Unmarshal(content, &payload)
 	if err != nil {
-		jsonClient.Logger.Fatal("Error during Unmarshal(): ", err)
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", jsonFilePath, err)
 	}
-	return payload
+	return payload, nil
 }
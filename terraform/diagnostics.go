@@ -0,0 +1,102 @@
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// PlanError is returned when terraform plan fails, with the error diagnostics it reported.
+type PlanError struct {
+	Diagnostics []types.PlanDiagnostic
+	Err         error
+}
+
+func (planError *PlanError) Error() string {
+	return planError.Err.Error()
+}
+
+func (planError *PlanError) Unwrap() error {
+	return planError.Err
+}
+
+// planLogLine is a line of the machine readable output of terraform plan -json.
+type planLogLine struct {
+	Level      string `json:"@level"`
+	Message    string `json:"@message"`
+	Type       string `json:"type"`
+	Diagnostic *struct {
+		Severity string `json:"severity"`
+		Summary  string `json:"summary"`
+		Detail   string `json:"detail"`
+		Address  string `json:"address"`
+	} `json:"diagnostic"`
+}
+
+// planLogWriter writes the output of terraform plan -json to a log file, and logs the message of each line so the
+// progress of the plan can still be followed.
+type planLogWriter struct {
+	file    io.Writer
+	logger  *logrus.Logger
+	pending []byte
+}
+
+func (writer *planLogWriter) Write(p []byte) (int, error) {
+	if _, err := writer.file.Write(p); err != nil {
+		return 0, err
+	}
+	writer.pending = append(writer.pending, p...)
+	for {
+		index := bytes.IndexByte(writer.pending, '\n')
+		if index < 0 {
+			break
+		}
+		writer.logLine(writer.pending[:index])
+		writer.pending = writer.pending[index+1:]
+	}
+	return len(p), nil
+}
+
+func (writer *planLogWriter) logLine(line []byte) {
+	logLine := planLogLine{}
+	if err := json.Unmarshal(line, &logLine); err != nil {
+		writer.logger.Info(string(line))
+		return
+	}
+	switch logLine.Level {
+	case "error":
+		writer.logger.Error(logLine.Message)
+	case "warn":
+		writer.logger.Warn(logLine.Message)
+	default:
+		writer.logger.Info(logLine.Message)
+	}
+}
+
+// readPlanDiagnostics reads the error diagnostics from the output of terraform plan -json. Lines that are not JSON,
+// such as a crash message, are skipped.
+func readPlanDiagnostics(reader io.Reader) ([]types.PlanDiagnostic, error) {
+	diagnostics := []types.PlanDiagnostic{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		logLine := planLogLine{}
+		if err := json.Unmarshal(scanner.Bytes(), &logLine); err != nil {
+			continue
+		}
+		if logLine.Type != "diagnostic" || logLine.Diagnostic == nil || logLine.Diagnostic.Severity != "error" {
+			continue
+		}
+		diagnostics = append(diagnostics, types.PlanDiagnostic{
+			Summary: logLine.Diagnostic.Summary,
+			Detail:  logLine.Diagnostic.Detail,
+			Address: logLine.Diagnostic.Address,
+		})
+	}
+	return diagnostics, scanner.Err()
+}
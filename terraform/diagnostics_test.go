@@ -0,0 +1,89 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// failedPlanLog is the output of terraform plan -json for a module with an import of a resource that does not exist, an
+// import ID of the wrong resource type and an import to an address that is not in the configuration.
+const failedPlanLog = `{"@level":"info","@message":"Terraform 1.9.8","@module":"terraform.ui","@timestamp":"2024-11-05T10:12:01.118402Z","terraform":"1.9.8","type":"version","ui":"1.2"}
+{"@level":"info","@message":"azurerm_resource_group.rg: Refreshing state... [id=/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-hub]","@module":"terraform.ui","@timestamp":"2024-11-05T10:12:04.204117Z","hook":{"resource":{"addr":"azurerm_resource_group.rg","module":"","resource":"azurerm_resource_group.rg","implied_provider":"azurerm","resource_type":"azurerm_resource_group","resource_name":"rg","resource_key":null},"id_key":"id","id_value":"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-hub"},"type":"refresh_start"}
+{"@level":"warn","@message":"Warning: Argument is deprecated","@module":"terraform.ui","@timestamp":"2024-11-05T10:12:05.512960Z","diagnostic":{"severity":"warning","summary":"Argument is deprecated","detail":"The property enforce_private_link_endpoint_network_policies will be removed in v4.0 of the AzureRM Provider.","address":"azurerm_subnet.this","range":{"filename":"main.tf","start":{"line":21,"column":3,"byte":402},"end":{"line":21,"column":54,"byte":453}}},"type":"diagnostic"}
+{"@level":"error","@message":"Error: Cannot import non-existent remote object","@module":"terraform.ui","@timestamp":"2024-11-05T10:12:06.730441Z","diagnostic":{"severity":"error","summary":"Cannot import non-existent remote object","detail":"While attempting to import an existing object to \"azurerm_resource_group.missing\", the provider detected that no object exists with the given id. Only pre-existing objects can be imported; check that the id is correct and that it is associated with the provider's configured region or endpoint, or use \"terraform apply\" to create a new remote object for this resource.","address":"azurerm_resource_group.missing","range":{"filename":"imports.tf","start":{"line":6,"column":1,"byte":115},"end":{"line":6,"column":7,"byte":121}}},"type":"diagnostic"}
+{"@level":"error","@message":"Error: parsing \"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/vnet-hub\": parsing segment \"staticSubnets\": parsing the Subnet ID: the segment at position 8 didn't match","@module":"terraform.ui","@timestamp":"2024-11-05T10:12:06.731052Z","diagnostic":{"severity":"error","summary":"parsing \"/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/vnet-hub\": parsing segment \"staticSubnets\": parsing the Subnet ID: the segment at position 8 didn't match","detail":"","address":"module.hub[\"a\"].azurerm_subnet.this[\"firewall\"]","range":{"filename":"imports.tf","start":{"line":11,"column":1,"byte":248},"end":{"line":11,"column":7,"byte":254}}},"type":"diagnostic"}
+{"@level":"error","@message":"Error: Configuration for import target does not exist","@module":"terraform.ui","@timestamp":"2024-11-05T10:12:06.731388Z","diagnostic":{"severity":"error","summary":"Configuration for import target does not exist","detail":"The configuration for the given import azurerm_public_ip.removed does not exist. All target instances must have an associated configuration to be imported.","range":{"filename":"imports.tf","start":{"line":17,"column":8,"byte":402},"end":{"line":17,"column":33,"byte":427}},"snippet":{"context":"import","code":"  to = azurerm_public_ip.removed","start_line":17,"highlight_start_offset":7,"highlight_end_offset":32,"values":[]}},"type":"diagnostic"}
+`
+
+var failedPlanDiagnostics = []types.PlanDiagnostic{
+	{
+		Summary: "Cannot import non-existent remote object",
+		Detail:  `While attempting to import an existing object to "azurerm_resource_group.missing", the provider detected that no object exists with the given id. Only pre-existing objects can be imported; check that the id is correct and that it is associated with the provider's configured region or endpoint, or use "terraform apply" to create a new remote object for this resource.`,
+		Address: "azurerm_resource_group.missing",
+	},
+	{
+		Summary: `parsing "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg-hub/providers/Microsoft.Network/virtualNetworks/vnet-hub": parsing segment "staticSubnets": parsing the Subnet ID: the segment at position 8 didn't match`,
+		Address: `module.hub["a"].azurerm_subnet.this["firewall"]`,
+	},
+	{
+		Summary: "Configuration for import target does not exist",
+		Detail:  "The configuration for the given import azurerm_public_ip.removed does not exist. All target instances must have an associated configuration to be imported.",
+	},
+}
+
+func Test_readPlanDiagnostics(t *testing.T) {
+	diagnostics, err := readPlanDiagnostics(strings.NewReader(failedPlanLog + "panic: not JSON\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, failedPlanDiagnostics, diagnostics)
+}
+
+func Test_planLogWriter(t *testing.T) {
+	file := &bytes.Buffer{}
+	logOutput := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(logOutput)
+	writer := &planLogWriter{file: file, logger: logger}
+
+	// Lines are logged once they are complete, however the output is split
+	for _, chunk := range []string{failedPlanLog[:100], failedPlanLog[100:1000], failedPlanLog[1000:]} {
+		_, err := writer.Write([]byte(chunk))
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, failedPlanLog, file.String())
+	assert.Equal(t, 6, strings.Count(logOutput.String(), "\n"))
+	assert.Contains(t, logOutput.String(), `level=info msg="Terraform 1.9.8"`)
+	assert.Contains(t, logOutput.String(), `level=warning msg="Warning: Argument is deprecated"`)
+	assert.Contains(t, logOutput.String(), `level=error msg="Error: Configuration for import target does not exist"`)
+}
+
+func TestPlanClient_PlanAndGetImportChanges_ReturnsPlanDiagnostics(t *testing.T) {
+	binPath := t.TempDir()
+	workingFolderPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(binPath, "failed-plan.log"), []byte(failedPlanLog), 0644))
+	script := "#!/bin/sh\ncat \"$(dirname \"$0\")/failed-plan.log\"\nexit 1\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(binPath, "terraform"), []byte(script), 0755))
+	t.Setenv("PATH", binPath+string(os.PathListSeparator)+os.Getenv("PATH"))
+	planClient := &PlanClient{TerraformModulePath: t.TempDir(), WorkingFolderPath: workingFolderPath, SkipInitOnly: true, SubscriptionID: "sub", Logger: logrus.New()}
+
+	_, err := planClient.PlanAndGetImportChanges(context.Background())
+
+	planError := &PlanError{}
+	assert.True(t, errors.As(err, &planError))
+	assert.ErrorContains(t, err, "terraform plan failed")
+	assert.Equal(t, failedPlanDiagnostics, planError.Diagnostics)
+	content, err := os.ReadFile(filepath.Join(workingFolderPath, "tfplan_verify_log.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, failedPlanLog, string(content))
+}
@@ -0,0 +1,203 @@
+package terraform
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// defaultDriftIgnoreRules apply to every resource type. A rule in the configuration with the same attribute path
+// replaces the default rule, so it can be narrowed to some types.
+var defaultDriftIgnoreRules = []types.DriftIgnoreRule{
+	{AttributePath: "replace_triggers_external_values", MatchType: types.DriftIgnoreMatchTypeGlob, Reason: "azapi replacement trigger, not an Azure property"},
+	{AttributePath: "retry", MatchType: types.DriftIgnoreMatchTypeGlob, Reason: "retry settings, not an Azure property"},
+	{AttributePath: "timeouts", MatchType: types.DriftIgnoreMatchTypeGlob, Reason: "timeouts, not an Azure property"},
+	{AttributePath: "output", MatchType: types.DriftIgnoreMatchTypeGlob, Reason: "azapi computed output"},
+}
+
+var (
+	planResourceHeaderRegex = regexp.MustCompile(`resource "([^"]+)" "`)
+	planSubTypeRegex        = regexp.MustCompile(`^ {8}type\s+= "([^"@]+)@`)
+)
+
+type planAttributeLine struct {
+	Depth    int
+	Path     string
+	IsChange bool
+	Opens    bool
+}
+
+type suppressedDrift struct {
+	Address       string
+	AttributePath string
+	Reason        string
+}
+
+// driftIgnoreMatcher is a drift ignore rule with its patterns compiled, so they are compiled once rather than for
+// every line of the plan.
+type driftIgnoreMatcher struct {
+	Rule          types.DriftIgnoreRule
+	Type          func(string) bool
+	SubType       func(string) bool
+	AttributePath func(string) bool
+}
+
+// getDriftIgnoreRules adds the default rules to the rules from the configuration, leaving out the defaults that a
+// configured rule replaces.
+func getDriftIgnoreRules(driftIgnoreRules []types.DriftIgnoreRule) []types.DriftIgnoreRule {
+	defaultRules := []types.DriftIgnoreRule{}
+	for _, defaultRule := range defaultDriftIgnoreRules {
+		replaced := slices.ContainsFunc(driftIgnoreRules, func(driftIgnoreRule types.DriftIgnoreRule) bool {
+			return driftIgnoreRule.AttributePath == defaultRule.AttributePath
+		})
+		if !replaced {
+			defaultRules = append(defaultRules, defaultRule)
+		}
+	}
+	return slices.Concat(defaultRules, driftIgnoreRules)
+}
+
+// newDriftIgnoreMatchers compiles the patterns of the drift ignore rules, failing on the first rule with an invalid one.
+func newDriftIgnoreMatchers(driftIgnoreRules []types.DriftIgnoreRule) ([]driftIgnoreMatcher, error) {
+	driftIgnoreMatchers := []driftIgnoreMatcher{}
+	for _, rule := range driftIgnoreRules {
+		matcher := driftIgnoreMatcher{Rule: rule}
+		patterns := []struct {
+			name    string
+			pattern string
+			matches *func(string) bool
+		}{
+			{"type", rule.Type, &matcher.Type},
+			{"subType", rule.SubType, &matcher.SubType},
+			{"attributePath", rule.AttributePath, &matcher.AttributePath},
+		}
+		for _, pattern := range patterns {
+			matches, err := compileDriftIgnorePattern(rule.MatchType, pattern.pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s pattern %q in the drift ignore rule for attribute path %s: %w", pattern.name, pattern.pattern, rule.AttributePath, err)
+			}
+			*pattern.matches = matches
+		}
+		driftIgnoreMatchers = append(driftIgnoreMatchers, matcher)
+	}
+	return driftIgnoreMatchers, nil
+}
+
+func (planClient *PlanClient) matchDriftIgnoreRule(resourceType string, subType string, attributePath string) (types.DriftIgnoreRule, bool) {
+	for _, matcher := range planClient.driftIgnoreMatchers {
+		if !matcher.Type(resourceType) || !matcher.SubType(subType) {
+			continue
+		}
+
+		// A rule for a parent attribute also covers every nested attribute below it
+		segments := strings.Split(attributePath, ".")
+		for i := len(segments); i > 0; i-- {
+			if matcher.AttributePath(strings.Join(segments[:i], ".")) {
+				return matcher.Rule, true
+			}
+		}
+	}
+	return types.DriftIgnoreRule{}, false
+}
+
+// compileDriftIgnorePattern returns a function matching a value against the pattern. An empty pattern matches every
+// value, and glob patterns are case insensitive.
+func compileDriftIgnorePattern(matchType types.DriftIgnoreMatchType, pattern string) (func(string) bool, error) {
+	if pattern == "" {
+		return func(string) bool { return true }, nil
+	}
+
+	if matchType == types.DriftIgnoreMatchTypeRegex {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return regex.MatchString, nil
+	}
+
+	globPattern := strings.ToLower(pattern)
+	if _, err := path.Match(globPattern, ""); err != nil {
+		return nil, err
+	}
+	return func(value string) bool {
+		matched, _ := path.Match(globPattern, strings.ToLower(value))
+		return matched
+	}, nil
+}
+
+// parsePlanAttributeLines works out the attribute path of each line in a text plan resource block
+// from its indentation, so nested changes such as body.properties.sku can be matched by rules.
+func parsePlanAttributeLines(resourceBuffer []string) []*planAttributeLine {
+	attributeLines := make([]*planAttributeLine, len(resourceBuffer))
+	pathStack := []string{}
+
+	for i, line := range resourceBuffer {
+		trimmed := strings.TrimLeft(line, " ")
+		nameColumn := len(line) - len(trimmed)
+		isChange := false
+
+		for _, sign := range []string{"-/+ ", "~ ", "+ ", "- "} {
+			if strings.HasPrefix(trimmed, sign) {
+				isChange = true
+				trimmed = trimmed[len(sign):]
+				nameColumn += len(sign)
+				break
+			}
+		}
+
+		if nameColumn < 8 || trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "}") || strings.HasPrefix(trimmed, "]") || strings.HasPrefix(trimmed, ")") {
+			continue
+		}
+
+		depth := (nameColumn - 8) / 4
+		if depth > len(pathStack) {
+			depth = len(pathStack)
+		}
+
+		attributeLine := &planAttributeLine{
+			Depth:    depth,
+			IsChange: isChange,
+			Opens:    strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "[") || strings.HasSuffix(trimmed, "("),
+		}
+
+		name := ""
+		if index := strings.Index(trimmed, " = "); index > 0 {
+			name = trimmed[:index]
+		} else if strings.HasSuffix(trimmed, " {") {
+			name = strings.TrimSuffix(trimmed, " {")
+		}
+		name = strings.Trim(strings.TrimSpace(name), `"`)
+
+		if name == "" {
+			// List items and heredoc content belong to the attribute that contains them
+			attributeLine.Path = strings.Join(pathStack[:depth], ".")
+		} else {
+			pathStack = append(pathStack[:depth], name)
+			attributeLine.Path = strings.Join(pathStack, ".")
+		}
+
+		attributeLines[i] = attributeLine
+	}
+
+	return attributeLines
+}
+
+// hasNestedChanges reports whether the opening line at index has any changed lines nested within it.
+func hasNestedChanges(attributeLines []*planAttributeLine, index int) bool {
+	for _, attributeLine := range attributeLines[index+1:] {
+		if attributeLine == nil {
+			continue
+		}
+		if attributeLine.Depth <= attributeLines[index].Depth {
+			return false
+		}
+		if attributeLine.IsChange {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package terraform
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const terraformIgnoreFileName = ".terraformignore"
+
+// defaultIgnoreRules are always applied before the rules in the .terraformignore file.
+var defaultIgnoreRules = []string{".git/", ".terraform/"}
+
+// localModuleSourcePattern matches the module sources that are local paths.
+var localModuleSourcePattern = regexp.MustCompile(`(?m)^\s*source\s*=\s*"(\.\.?/[^"]*)"`)
+
+// initFileNames are written to the copy by terraform init and kept when the module is copied again.
+var initFileNames = []string{".terraform", ".terraform.lock.hcl"}
+
+type ignoreRule struct {
+	pattern   *regexp.Regexp
+	negate    bool
+	directory bool
+}
+
+// CopyModule copies a Terraform module to a scratch directory so it can be planned without changing the original,
+// skipping the files matched by its .terraformignore file and the excluded paths. Any previous copy at the destination is
+// replaced, apart from what terraform init left in it, so the copy can be planned again with --skipInitOnly.
+func CopyModule(modulePath string, destinationPath string, excludedPaths ...string) error {
+	modulePath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return fmt.Errorf("failed to get module path: %w", err)
+	}
+	destinationPath, err = filepath.Abs(destinationPath)
+	if err != nil {
+		return fmt.Errorf("failed to get isolated module path: %w", err)
+	}
+
+	excludedPaths = append(excludedPaths, destinationPath)
+	for i, excludedPath := range excludedPaths {
+		if excludedPaths[i], err = filepath.Abs(excludedPath); err != nil {
+			return fmt.Errorf("failed to get excluded path: %w", err)
+		}
+	}
+
+	ignoreRules, err := readIgnoreRules(modulePath)
+	if err != nil {
+		return err
+	}
+
+	if err := clearCopy(destinationPath); err != nil {
+		return fmt.Errorf("failed to remove previous isolated module copy: %w", err)
+	}
+
+	return filepath.Walk(modulePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(modulePath, path)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			return os.MkdirAll(destinationPath, info.Mode().Perm())
+		}
+
+		if slices.Contains(excludedPaths, path) || isIgnored(ignoreRules, filepath.ToSlash(relativePath), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		targetPath := filepath.Join(destinationPath, relativePath)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read link %s: %w", path, err)
+			}
+			return os.Symlink(linkTarget, targetPath)
+		case info.IsDir():
+			return os.MkdirAll(targetPath, info.Mode().Perm())
+		default:
+			return copyFile(path, targetPath, info.Mode().Perm())
+		}
+	})
+}
+
+// CheckLocalModuleSources returns an error for a local module source in the copy that points outside of it, as the
+// module it points to was not copied and would fail to resolve when the copy is planned.
+func CheckLocalModuleSources(copyPath string) error {
+	copyPath, err := filepath.Abs(copyPath)
+	if err != nil {
+		return fmt.Errorf("failed to get isolated module path: %w", err)
+	}
+	return filepath.WalkDir(copyPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".terraform" {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".tf" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for _, match := range localModuleSourcePattern.FindAllStringSubmatch(string(content), -1) {
+			sourcePath := filepath.Join(filepath.Dir(path), filepath.FromSlash(match[1]))
+			relativePath, err := filepath.Rel(copyPath, sourcePath)
+			if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+				filePath, _ := filepath.Rel(copyPath, path)
+				return fmt.Errorf("module source %q in %s is outside the copied folder", match[1], filepath.ToSlash(filePath))
+			}
+		}
+		return nil
+	})
+}
+
+// clearCopy removes a previous copy of a module, keeping the .terraform folders and dependency lock files written by
+// terraform init. A lock file in the module itself is copied over the kept one.
+func clearCopy(path string) error {
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if slices.Contains(initFileNames, entry.Name()) {
+			continue
+		}
+		entryPath := filepath.Join(path, entry.Name())
+		if !entry.IsDir() {
+			if err := os.Remove(entryPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := clearCopy(entryPath); err != nil {
+			return err
+		}
+		remaining, err := os.ReadDir(entryPath)
+		if err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			if err := os.Remove(entryPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CopyFiles copies files into a directory, keeping their names.
+func CopyFiles(filePaths []string, destinationPath string) ([]string, error) {
+	copiedFilePaths := []string{}
+	for _, filePath := range filePaths {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return copiedFilePaths, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		targetPath := filepath.Join(destinationPath, filepath.Base(filePath))
+		if err := copyFile(filePath, targetPath, info.Mode().Perm()); err != nil {
+			return copiedFilePaths, err
+		}
+		copiedFilePaths = append(copiedFilePaths, targetPath)
+	}
+	return copiedFilePaths, nil
+}
+
+func copyFile(sourcePath string, targetPath string, mode os.FileMode) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", sourcePath, err)
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", targetPath, err)
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return fmt.Errorf("failed to copy %s: %w", sourcePath, err)
+	}
+	if err := target.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", targetPath, err)
+	}
+	return nil
+}
+
+func readIgnoreRules(modulePath string) ([]ignoreRule, error) {
+	lines := slices.Clone(defaultIgnoreRules)
+
+	file, err := os.Open(filepath.Join(modulePath, terraformIgnoreFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", terraformIgnoreFileName, err)
+	}
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", terraformIgnoreFileName, err)
+		}
+	}
+
+	ignoreRules := []ignoreRule{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ignoreRule, err := parseIgnoreRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in %s: %w", line, terraformIgnoreFileName, err)
+		}
+		ignoreRules = append(ignoreRules, ignoreRule)
+	}
+	return ignoreRules, nil
+}
+
+// parseIgnoreRule converts a .gitignore style pattern to a regular expression over slash separated relative paths.
+// Patterns without a slash match at any depth, a leading slash anchors to the module root and a trailing slash matches directories only.
+func parseIgnoreRule(line string) (ignoreRule, error) {
+	rule := ignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.directory = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expression := strings.Builder{}
+	expression.WriteString("^")
+	if !anchored {
+		expression.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			expression.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case line[i] == '*':
+			expression.WriteString("[^/]*")
+		case line[i] == '?':
+			expression.WriteString("[^/]")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(line[i])))
+		}
+	}
+	expression.WriteString("$")
+
+	pattern, err := regexp.Compile(expression.String())
+	if err != nil {
+		return rule, err
+	}
+	rule.pattern = pattern
+	return rule, nil
+}
+
+// isIgnored applies the rules in order, so a later negated rule can include a path again.
+func isIgnored(ignoreRules []ignoreRule, relativePath string, isDirectory bool) bool {
+	ignored := false
+	for _, rule := range ignoreRules {
+		if rule.directory && !isDirectory {
+			continue
+		}
+		if rule.pattern.MatchString(relativePath) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
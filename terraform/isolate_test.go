@@ -0,0 +1,144 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestCopyModule_RespectsTerraformIgnore(t *testing.T) {
+	modulePath := t.TempDir()
+	writeTestFile(t, filepath.Join(modulePath, "main.tf"), "# main")
+	writeTestFile(t, filepath.Join(modulePath, "modules", "vnet", "main.tf"), "# vnet")
+	writeTestFile(t, filepath.Join(modulePath, ".terraform", "providers", "provider"), "binary")
+	writeTestFile(t, filepath.Join(modulePath, ".git", "HEAD"), "ref")
+	writeTestFile(t, filepath.Join(modulePath, "docs", "readme.md"), "# docs")
+	writeTestFile(t, filepath.Join(modulePath, "secret.tfvars"), "secret")
+	writeTestFile(t, filepath.Join(modulePath, "keep.tfvars"), "keep")
+	writeTestFile(t, filepath.Join(modulePath, ".terraformignore"), "# comments are skipped\ndocs/\n*.tfvars\n!keep.tfvars\n")
+
+	scratchPath := filepath.Join(modulePath, ".terraform-state-importer")
+	destinationPath := filepath.Join(scratchPath, "module")
+	writeTestFile(t, filepath.Join(scratchPath, "plugin-cache", "provider"), "binary")
+
+	err := CopyModule(modulePath, destinationPath, scratchPath)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(destinationPath, "main.tf"))
+	assert.FileExists(t, filepath.Join(destinationPath, "modules", "vnet", "main.tf"))
+	assert.FileExists(t, filepath.Join(destinationPath, "keep.tfvars"))
+	assert.NoFileExists(t, filepath.Join(destinationPath, "secret.tfvars"))
+	assert.NoDirExists(t, filepath.Join(destinationPath, "docs"))
+	assert.NoDirExists(t, filepath.Join(destinationPath, ".terraform"))
+	assert.NoDirExists(t, filepath.Join(destinationPath, ".git"))
+	assert.NoDirExists(t, filepath.Join(destinationPath, ".terraform-state-importer"))
+}
+
+func TestCopyModule_ReplacesPreviousCopy(t *testing.T) {
+	modulePath := t.TempDir()
+	destinationPath := filepath.Join(t.TempDir(), "module")
+	writeTestFile(t, filepath.Join(modulePath, "main.tf"), "# main")
+	writeTestFile(t, filepath.Join(destinationPath, "imports.tf"), "# stale")
+
+	err := CopyModule(modulePath, destinationPath)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(destinationPath, "main.tf"))
+	assert.NoFileExists(t, filepath.Join(destinationPath, "imports.tf"))
+}
+
+func TestCopyModule_KeepsInitFiles(t *testing.T) {
+	modulePath := t.TempDir()
+	destinationPath := filepath.Join(t.TempDir(), "module")
+	writeTestFile(t, filepath.Join(modulePath, "main.tf"), "# main")
+	writeTestFile(t, filepath.Join(destinationPath, ".terraform", "providers", "provider"), "binary")
+	writeTestFile(t, filepath.Join(destinationPath, ".terraform.lock.hcl"), "# lock")
+	writeTestFile(t, filepath.Join(destinationPath, "modules", "vnet", ".terraform", "modules.json"), "{}")
+	writeTestFile(t, filepath.Join(destinationPath, "modules", "old", "main.tf"), "# stale")
+
+	err := CopyModule(modulePath, destinationPath)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(destinationPath, "main.tf"))
+	assert.FileExists(t, filepath.Join(destinationPath, ".terraform", "providers", "provider"))
+	assert.FileExists(t, filepath.Join(destinationPath, ".terraform.lock.hcl"))
+	assert.FileExists(t, filepath.Join(destinationPath, "modules", "vnet", ".terraform", "modules.json"))
+	assert.NoDirExists(t, filepath.Join(destinationPath, "modules", "old"))
+}
+
+func TestCheckLocalModuleSources(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{"Inside", "module \"vnet\" {\n  source = \"./modules/vnet\"\n}\n", ""},
+		{"Sibling inside", "module \"vnet\" {\n  source = \"../shared/vnet\"\n}\n", ""},
+		{"Registry", "module \"vnet\" {\n  source = \"Azure/avm-res-network-virtualnetwork/azurerm\"\n}\n", ""},
+		{"Outside", "module \"vnet\" {\n  source = \"../../modules/vnet\"\n}\n", `module source "../../modules/vnet" in stacks/main.tf is outside the copied folder`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			copyPath := t.TempDir()
+			writeTestFile(t, filepath.Join(copyPath, "stacks", "main.tf"), test.content)
+			writeTestFile(t, filepath.Join(copyPath, "stacks", ".terraform", "modules", "x", "main.tf"), "module \"x\" {\n  source = \"../../../../x\"\n}\n")
+
+			err := CheckLocalModuleSources(copyPath)
+
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	sourcePath := t.TempDir()
+	destinationPath := t.TempDir()
+	writeTestFile(t, filepath.Join(sourcePath, "imports.tf"), "import {}")
+
+	copiedFilePaths, err := CopyFiles([]string{filepath.Join(sourcePath, "imports.tf")}, destinationPath)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(destinationPath, "imports.tf")}, copiedFilePaths)
+	content, _ := os.ReadFile(filepath.Join(destinationPath, "imports.tf"))
+	assert.Equal(t, "import {}", string(content))
+}
+
+func Test_isIgnored(t *testing.T) {
+	rules := []ignoreRule{}
+	for _, line := range []string{"/build", "**/cache/*.bin", "*.log", "logs/", "!important.log"} {
+		rule, err := parseIgnoreRule(line)
+		assert.NoError(t, err)
+		rules = append(rules, rule)
+	}
+
+	tests := []struct {
+		path        string
+		isDirectory bool
+		expected    bool
+	}{
+		{"build", true, true},
+		{"modules/build", true, false},
+		{"a/b/cache/x.bin", false, true},
+		{"cache/x.bin", false, true},
+		{"nested/debug.log", false, true},
+		{"important.log", false, false},
+		{"logs", true, true},
+		{"logs", false, false},
+		{"main.tf", false, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, isIgnored(rules, test.path, test.isDirectory), test.path)
+	}
+}
@@ -0,0 +1,130 @@
+package terraform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// parentReferenceAttributes are the attributes that place a resource under its parent, in order of preference.
+var parentReferenceAttributes = []string{"parent_id", "resource_group_name", "scope", "virtual_network_name"}
+
+var instanceKeyRegex = regexp.MustCompile(`\["(?:[^"\\]|\\.)*"\]|\[\d+\]`)
+
+var referenceIndexRegex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// getParentReferences reads the plan configuration and returns the resource referenced as the parent of each
+// configured resource, keyed by the configuration address without instance keys, e.g. module.hub.azapi_resource.vnet.
+func getParentReferences(plan map[string]any) map[string]string {
+	parentReferences := map[string]string{}
+	configuration, ok := plan["configuration"].(map[string]any)
+	if !ok {
+		return parentReferences
+	}
+	if rootModule, ok := configuration["root_module"].(map[string]any); ok {
+		readModuleParentReferences(rootModule, "", parentReferences)
+	}
+	return parentReferences
+}
+
+func readModuleParentReferences(module map[string]any, modulePath string, parentReferences map[string]string) {
+	resources, _ := module["resources"].([]any)
+	for _, rawResource := range resources {
+		resource, ok := rawResource.(map[string]any)
+		if !ok || resource["mode"] != "managed" {
+			continue
+		}
+		expressions, _ := resource["expressions"].(map[string]any)
+		for _, attribute := range parentReferenceAttributes {
+			expression, _ := expressions[attribute].(map[string]any)
+			references, _ := expression["references"].([]any)
+			if reference := getResourceReference(references); reference != "" {
+				parentReferences[joinAddress(modulePath, resource["address"].(string))] = joinAddress(modulePath, reference)
+				break
+			}
+		}
+	}
+
+	moduleCalls, _ := module["module_calls"].(map[string]any)
+	for name, rawModuleCall := range moduleCalls {
+		moduleCall, _ := rawModuleCall.(map[string]any)
+		if childModule, ok := moduleCall["module"].(map[string]any); ok {
+			readModuleParentReferences(childModule, joinAddress(modulePath, "module."+name), parentReferences)
+		}
+	}
+}
+
+// getResourceReference returns the managed resource from a list of references such as
+// ["azapi_resource.rg.id", "azapi_resource.rg"], ignoring variables, locals, data sources and modules.
+func getResourceReference(references []any) string {
+	for _, rawReference := range references {
+		reference, _ := rawReference.(string)
+		segments := strings.Split(referenceIndexRegex.ReplaceAllString(reference, ""), ".")
+		if len(segments) < 2 {
+			continue
+		}
+		switch segments[0] {
+		case "var", "local", "data", "module", "each", "count", "path", "terraform", "self":
+			continue
+		}
+		return segments[0] + "." + segments[1]
+	}
+	return ""
+}
+
+// getParentAddress resolves the configured parent reference of a resource instance to the address of a parent instance.
+// The parent must be in the same module instance, and when the parent has several instances the one with the same key is used.
+func getParentAddress(address string, parentReferences map[string]string, addresses map[string][]string) string {
+	parentReference, ok := parentReferences[GetConfigurationAddress(address)]
+	if !ok {
+		return ""
+	}
+
+	// Resource types and names never contain dots, so dropping the last two segments leaves the module instance
+	resourceAddress := trimInstanceKey(address)
+	modulePath := ""
+	if index := strings.LastIndex(resourceAddress[:strings.LastIndex(resourceAddress, ".")], "."); index >= 0 {
+		modulePath = resourceAddress[:index]
+	}
+
+	parentAddresses := []string{}
+	for _, parentAddress := range addresses[parentReference] {
+		if modulePath == "" || strings.HasPrefix(parentAddress, modulePath+".") {
+			parentAddresses = append(parentAddresses, parentAddress)
+		}
+	}
+	if len(parentAddresses) == 1 {
+		return parentAddresses[0]
+	}
+
+	instanceKey := strings.TrimPrefix(address, resourceAddress)
+	for _, parentAddress := range parentAddresses {
+		if instanceKey != "" && strings.TrimPrefix(parentAddress, trimInstanceKey(parentAddress)) == instanceKey {
+			return parentAddress
+		}
+	}
+	return ""
+}
+
+// trimInstanceKey removes the trailing count or for_each key from an address.
+func trimInstanceKey(address string) string {
+	if !strings.HasSuffix(address, "]") {
+		return address
+	}
+	locations := instanceKeyRegex.FindAllStringIndex(address, -1)
+	if len(locations) > 0 && locations[len(locations)-1][1] == len(address) {
+		return address[:locations[len(locations)-1][0]]
+	}
+	return address
+}
+
+// GetConfigurationAddress removes all instance keys from an address, giving the address of the resource in the configuration.
+func GetConfigurationAddress(address string) string {
+	return instanceKeyRegex.ReplaceAllString(address, "")
+}
+
+func joinAddress(modulePath string, address string) string {
+	if modulePath == "" {
+		return address
+	}
+	return modulePath + "." + address
+}
@@ -0,0 +1,67 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getParentReferences(t *testing.T) {
+	plan := map[string]any{
+		"configuration": map[string]any{
+			"root_module": map[string]any{
+				"resources": []any{
+					map[string]any{"address": "azurerm_resource_group.this", "mode": "managed", "expressions": map[string]any{}},
+				},
+				"module_calls": map[string]any{
+					"hub": map[string]any{
+						"module": map[string]any{
+							"resources": []any{
+								map[string]any{
+									"address": "azapi_resource.vnet", "mode": "managed",
+									"expressions": map[string]any{"parent_id": map[string]any{"references": []any{"var.parent_id"}}},
+								},
+								map[string]any{
+									"address": "azapi_resource.subnet", "mode": "managed",
+									"expressions": map[string]any{"parent_id": map[string]any{"references": []any{"azapi_resource.vnet[each.key].id", "azapi_resource.vnet"}}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	parentReferences := getParentReferences(plan)
+
+	assert.Equal(t, map[string]string{"module.hub.azapi_resource.subnet": "module.hub.azapi_resource.vnet"}, parentReferences)
+}
+
+func Test_getParentAddress(t *testing.T) {
+	parentReferences := map[string]string{
+		"module.hub.azapi_resource.subnet": "module.hub.azapi_resource.vnet",
+		"azurerm_subnet.this":              "azurerm_virtual_network.this",
+	}
+	addresses := map[string][]string{
+		"module.hub.azapi_resource.vnet": {`module.hub["a.b"].azapi_resource.vnet`, `module.hub["c"].azapi_resource.vnet["x"]`, `module.hub["c"].azapi_resource.vnet["y"]`},
+		"azurerm_virtual_network.this":   {"azurerm_virtual_network.this"},
+	}
+
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{`module.hub["a.b"].azapi_resource.subnet["s1"]`, `module.hub["a.b"].azapi_resource.vnet`},
+		{`module.hub["c"].azapi_resource.subnet["y"]`, `module.hub["c"].azapi_resource.vnet["y"]`},
+		{`module.hub["c"].azapi_resource.subnet["z"]`, ""},
+		{"azurerm_subnet.this[0]", "azurerm_virtual_network.this"},
+		{"azurerm_route.this", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			assert.Equal(t, test.expected, getParentAddress(test.address, parentReferences, addresses))
+		})
+	}
+}
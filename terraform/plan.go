@@ -2,12 +2,15 @@ package terraform
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -16,8 +19,10 @@ import (
 )
 
 type IPlanClient interface {
-	PlanAndGetResources() []*types.PlanResource
-	PlanAsText()
+	PlanAndGetResources(ctx context.Context) ([]*types.PlanResource, error)
+	PlanAsText(ctx context.Context) error
+	PlanAndGetImportChanges(ctx context.Context) ([]*types.ImportChange, error)
+	GetStateResources() ([]*types.StateResource, error)
 }
 
 type PlanClient struct {
@@ -28,14 +33,23 @@ type PlanClient struct {
 	SkipInitPlanShow           bool
 	SkipInitOnly               bool
 	SkipInitUpgrade            bool
+	KeepBackend                bool
+	PluginCacheDir             string
+	StateFilePath              string
 	PropertyMappings           []types.PropertyMapping
 	NameFormats                []types.NameFormat
+	DriftIgnoreRules           []types.DriftIgnoreRule
+	ResourceTypeMappings       []types.ResourceTypeMapping
+	Timeout                    time.Duration
 	JsonClient                 json.IJsonClient
 	Logger                     *logrus.Logger
+
+	priorStateResources []*types.StateResource
+	driftIgnoreMatchers []driftIgnoreMatcher
 }
 
-func NewPlanClient(terraformModulePath string, workingFolderPath string, subscriptionID string, ignoreResourceTypePatterns []string, skipInitPlanShow bool, skipInitOnly bool, skipInitUpgrade bool, propertyMappings []types.PropertyMapping, nameFormats []types.NameFormat, jsonClient json.IJsonClient, logger *logrus.Logger) *PlanClient {
-	return &PlanClient{
+func NewPlanClient(terraformModulePath string, workingFolderPath string, subscriptionID string, ignoreResourceTypePatterns []string, skipInitPlanShow bool, skipInitOnly bool, skipInitUpgrade bool, keepBackend bool, pluginCacheDir string, stateFilePath string, propertyMappings []types.PropertyMapping, nameFormats []types.NameFormat, driftIgnoreRules []types.DriftIgnoreRule, resourceTypeMappings []types.ResourceTypeMapping, timeout time.Duration, jsonClient json.IJsonClient, logger *logrus.Logger) (*PlanClient, error) {
+	planClient := &PlanClient{
 		TerraformModulePath:        terraformModulePath,
 		WorkingFolderPath:          workingFolderPath,
 		SubscriptionID:             subscriptionID,
@@ -43,57 +57,146 @@ func NewPlanClient(terraformModulePath string, workingFolderPath string, subscri
 		SkipInitPlanShow:           skipInitPlanShow,
 		SkipInitOnly:               skipInitOnly,
 		SkipInitUpgrade:            skipInitUpgrade,
+		KeepBackend:                keepBackend,
+		PluginCacheDir:             pluginCacheDir,
+		StateFilePath:              stateFilePath,
 		PropertyMappings:           propertyMappings,
 		NameFormats:                nameFormats,
+		DriftIgnoreRules:           getDriftIgnoreRules(driftIgnoreRules),
+		ResourceTypeMappings:       slices.Concat(resourceTypeMappings, defaultResourceTypeMappings),
+		Timeout:                    timeout,
 		JsonClient:                 jsonClient,
 		Logger:                     logger,
 	}
+
+	if err := validateNameFormats(nameFormats); err != nil {
+		return nil, err
+	}
+	driftIgnoreMatchers, err := newDriftIgnoreMatchers(planClient.DriftIgnoreRules)
+	if err != nil {
+		return nil, err
+	}
+	planClient.driftIgnoreMatchers = driftIgnoreMatchers
+	return planClient, nil
 }
 
-func (planClient *PlanClient) PlanAndGetResources() []*types.PlanResource {
+// validateNameFormats checks that Regex name formats are valid regular expressions, with each argument standing in for
+// a plain name. Resource names can still make an expression invalid, which is checked as each name is formatted.
+func validateNameFormats(nameFormats []types.NameFormat) error {
+	for _, nameFormat := range nameFormats {
+		if nameFormat.NameMatchType != types.NameMatchTypeRegex {
+			continue
+		}
+		nameFormatArguments := []any{}
+		for range nameFormat.NameFormatArguments {
+			nameFormatArguments = append(nameFormatArguments, "name")
+		}
+		if _, err := regexp.Compile(fmt.Sprintf(nameFormat.NameFormat, nameFormatArguments...)); err != nil {
+			return fmt.Errorf("invalid Regex name format %s for type %s: %w", nameFormat.NameFormat, nameFormat.Type, err)
+		}
+	}
+	return nil
+}
+
+const (
+	backendOverrideFileName     = "backend_override.tf"
+	backendOverrideFileContent  = "terraform {\n  backend \"local\" {}\n}\n"
+	backendOverrideBackupSuffix = ".terraform-state-importer.bak"
+)
+
+// terraformInterruptWaitDelay is how long terraform is given to stop after an interrupt before it is killed.
+const terraformInterruptWaitDelay = 30 * time.Second
+
+func (planClient *PlanClient) PlanAndGetResources(ctx context.Context) ([]*types.PlanResource, error) {
 	jsonFileName := "tfplan.json"
 
 	if !planClient.SkipInitPlanShow {
-		planFileName := "tfplan"
-		backendOverrideFilePath := planClient.createBackendOverrideFile()
-		chDir := fmt.Sprintf("-chdir=%s", planClient.TerraformModulePath)
-		planClient.Logger.Info("Running Terraform init, plan and show")
-
-		if !planClient.SkipInitOnly {
-			planClient.executeTerraformInit(chDir)
+		if err := planClient.runTerraform(ctx, jsonFileName, true, ""); err != nil {
+			return nil, err
 		}
-		planClient.executeTerraformPlan(chDir, planFileName)
-		planClient.executeTerraformShow(chDir, planFileName, jsonFileName, true)
-		planClient.removeBackendOverrideFile(backendOverrideFilePath)
 	}
 
-	plan := planClient.JsonClient.Import(jsonFileName)
+	plan, err := planClient.JsonClient.Import(jsonFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	if priorState, ok := plan["prior_state"].(map[string]any); ok {
+		planClient.priorStateResources = readStateResources(priorState)
+	}
 	return planClient.readResourcesFromPlan(plan)
 }
 
-func (planClient *PlanClient) PlanAsText() {
+func (planClient *PlanClient) PlanAsText(ctx context.Context) error {
 	textFileName := "tfplan.txt"
 
 	if !planClient.SkipInitPlanShow {
-		planFileName := "tfplan"
-		backendOverrideFilePath := planClient.createBackendOverrideFile()
-		chDir := fmt.Sprintf("-chdir=%s", planClient.TerraformModulePath)
-		planClient.Logger.Info("Running Terraform init, plan and show")
-
-		if !planClient.SkipInitOnly {
-			planClient.executeTerraformInit(chDir)
+		if err := planClient.runTerraform(ctx, textFileName, false, ""); err != nil {
+			return err
 		}
-		planClient.executeTerraformPlan(chDir, planFileName)
-		planClient.executeTerraformShow(chDir, planFileName, textFileName, false)
-		planClient.removeBackendOverrideFile(backendOverrideFilePath)
 	}
 
 	outputFileName := "tfplan_updates.txt"
-	planClient.ExtractUpdateResourcesFromPlan(textFileName, outputFileName)
+	return planClient.ExtractUpdateResourcesFromPlan(textFileName, outputFileName)
+}
+
+// PlanAndGetImportChanges plans the module with its import blocks and returns the changes for the resources being imported.
+// When the plan fails, for example because an imported resource does not exist, a PlanError with the diagnostics of the
+// plan is returned.
+func (planClient *PlanClient) PlanAndGetImportChanges(ctx context.Context) ([]*types.ImportChange, error) {
+	jsonFileName := "tfplan_verify.json"
+	planLogFileName := "tfplan_verify_log.json"
+
+	if err := planClient.runTerraform(ctx, jsonFileName, true, planLogFileName); err != nil {
+		return nil, err
+	}
+
+	plan, err := planClient.JsonClient.Import(jsonFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	return readImportChangesFromPlan(plan), nil
 }
 
-func (planClient *PlanClient) getCurrentSubscriptionID() string {
-	cmd := exec.Command("az", "account", "show", "--query", "id", "-o", "tsv")
+// runTerraform runs init, plan and show against a local backend and writes the shown plan to the working folder.
+// The backend override file is removed however terraform exits, including when the context is cancelled or times out.
+// With KeepBackend the module backend is used instead and the plan does not take the state lock. With a plan log file
+// name, the plan is run with -json and its output is written to the working folder.
+func (planClient *PlanClient) runTerraform(ctx context.Context, outputFileName string, jsonPlan bool, planLogFileName string) error {
+	planFileName := "tfplan"
+
+	if planClient.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, planClient.Timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !planClient.KeepBackend {
+		backendOverrideFilePath, backupFilePath, err := planClient.createBackendOverrideFile()
+		if err != nil {
+			return err
+		}
+		defer planClient.removeBackendOverrideFile(backendOverrideFilePath, backupFilePath)
+	}
+
+	chDir := fmt.Sprintf("-chdir=%s", planClient.TerraformModulePath)
+	planClient.Logger.Info("Running Terraform init, plan and show")
+
+	if !planClient.SkipInitOnly {
+		if err := planClient.executeTerraformInit(ctx, chDir); err != nil {
+			return err
+		}
+	}
+	if err := planClient.executeTerraformPlan(ctx, chDir, planFileName, planLogFileName); err != nil {
+		return err
+	}
+	return planClient.executeTerraformShow(ctx, chDir, planFileName, outputFileName, jsonPlan)
+}
+
+func (planClient *PlanClient) getCurrentSubscriptionID(ctx context.Context) (string, error) {
+	cmd := newCommand(ctx, "az", "account", "show", "--query", "id", "-o", "tsv")
 	env := cmd.Environ()
 
 	var stdout bytes.Buffer
@@ -103,8 +206,8 @@ func (planClient *PlanClient) getCurrentSubscriptionID() string {
 	cmd.Stderr = os.Stderr
 
 	planClient.Logger.Debugf("Running az cli: %s", cmd.String())
-	if err := cmd.Run(); err != nil {
-		planClient.Logger.Fatalf("Error: %s", err)
+	if err := runCommand(ctx, cmd); err != nil {
+		return "", fmt.Errorf("failed to get the current subscription ID from the az cli: %w", err)
 	}
 
 	output := stdout.String()
@@ -112,10 +215,10 @@ func (planClient *PlanClient) getCurrentSubscriptionID() string {
 	output = strings.ReplaceAll(output, "\n", "")
 	planClient.Logger.Debugf("Subscription ID: %s", output)
 
-	return output
+	return output, nil
 }
 
-func (planClient *PlanClient) readResourcesFromPlan(plan map[string]any) []*types.PlanResource {
+func (planClient *PlanClient) readResourcesFromPlan(plan map[string]any) ([]*types.PlanResource, error) {
 	resources := []*types.PlanResource{}
 
 	for _, resource := range plan["resource_changes"].([]any) {
@@ -147,10 +250,17 @@ func (planClient *PlanClient) readResourcesFromPlan(plan map[string]any) []*type
 			continue
 		}
 
+		// Resources in the state that are no longer in the configuration are planned for deletion with no after values
+		after, ok := resourceChange["change"].(map[string]any)["after"].(map[string]any)
+		if !ok {
+			planClient.Logger.Tracef("Skipping Resource %s as it is not in the configuration", resource.Address)
+			continue
+		}
+
 		resource.Type = resourceChange["type"].(string)
 		resource.Name = resourceChange["name"].(string)
 
-		resource.Properties = resourceChange["change"].(map[string]any)["after"].(map[string]any)
+		resource.Properties = after
 		resource.Properties["meta.type"] = resource.Type
 		resource.Properties["meta.name"] = resource.Name
 		resource.Properties["meta.address"] = resource.Address
@@ -166,6 +276,8 @@ func (planClient *PlanClient) readResourcesFromPlan(plan map[string]any) []*type
 			}
 		}
 
+		resource.AzureResourceType = planClient.getAzureResourceType(resource.Type, resource.SubType)
+
 		if val, ok := resource.Properties["location"]; ok {
 			if val != nil {
 				resource.Location = val.(string)
@@ -176,10 +288,52 @@ func (planClient *PlanClient) readResourcesFromPlan(plan map[string]any) []*type
 		resources = append(resources, &resource)
 		planClient.Logger.Tracef("Adding Resource: %s", resource.Address)
 	}
-	return planClient.mapPropertiesAndNames(resources)
+
+	parentReferences := getParentReferences(plan)
+	addresses := map[string][]string{}
+	for _, resource := range resources {
+		configurationAddress := GetConfigurationAddress(resource.Address)
+		addresses[configurationAddress] = append(addresses[configurationAddress], resource.Address)
+	}
+	for _, resource := range resources {
+		resource.ParentAddress = getParentAddress(resource.Address, parentReferences, addresses)
+	}
+
+	if err := planClient.mapPropertiesAndNames(resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
 }
 
-func (planClient *PlanClient) mapPropertiesAndNames(resources []*types.PlanResource) []*types.PlanResource {
+func readImportChangesFromPlan(plan map[string]any) []*types.ImportChange {
+	importChanges := []*types.ImportChange{}
+
+	resourceChanges, _ := plan["resource_changes"].([]any)
+	for _, resource := range resourceChanges {
+		resourceChange := resource.(map[string]any)
+		change, _ := resourceChange["change"].(map[string]any)
+		importing, ok := change["importing"].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		importChange := types.ImportChange{
+			Address: resourceChange["address"].(string),
+			Actions: []string{},
+		}
+		if importID, ok := importing["id"].(string); ok {
+			importChange.ImportID = importID
+		}
+		actions, _ := change["actions"].([]any)
+		for _, action := range actions {
+			importChange.Actions = append(importChange.Actions, action.(string))
+		}
+		importChanges = append(importChanges, &importChange)
+	}
+	return importChanges
+}
+
+func (planClient *PlanClient) mapPropertiesAndNames(resources []*types.PlanResource) error {
 	for _, resource := range resources {
 		for _, propertyMapping := range planClient.PropertyMappings {
 			if (propertyMapping.Type == resource.Type && propertyMapping.SubType == "") || (propertyMapping.Type == resource.Type && propertyMapping.SubType == resource.SubType) {
@@ -197,7 +351,7 @@ func (planClient *PlanClient) mapPropertiesAndNames(resources []*types.PlanResou
 
 							lookupProperties[sourceLookupProperty.Target] = lookupValue
 						} else {
-							planClient.Logger.Fatalf("Source lookup property %s not found in resource properties for %s", sourceLookupProperty.Name, resource.Address)
+							return fmt.Errorf("source lookup property %s not found in resource properties for %s", sourceLookupProperty.Name, resource.Address)
 						}
 					}
 
@@ -220,7 +374,7 @@ func (planClient *PlanClient) mapPropertiesAndNames(resources []*types.PlanResou
 								if targetValue, ok := lookupResource.Properties[targetProperty.From]; ok {
 									resource.Properties[targetProperty.Name] = targetValue
 								} else {
-									planClient.Logger.Fatalf("Mapping property %s not found in lookup resource properties for %s", targetProperty.From, lookupResource.Address)
+									return fmt.Errorf("mapping property %s not found in lookup resource properties for %s", targetProperty.From, lookupResource.Address)
 								}
 							}
 							break
@@ -239,12 +393,19 @@ func (planClient *PlanClient) mapPropertiesAndNames(resources []*types.PlanResou
 					if val, ok := resource.Properties[arg]; ok {
 						nameFormatArguments = append(nameFormatArguments, val.(string))
 					} else {
-						planClient.Logger.Fatalf("Name format argument %s not found in resource properties for %s", arg, resource.Address)
+						return fmt.Errorf("name format argument %s not found in resource properties for %s", arg, resource.Address)
 					}
 				}
 
 				resource.ResourceName = fmt.Sprintf(nameFormat.NameFormat, nameFormatArguments...)
 				resource.ResourceNameMatchType = nameFormat.NameMatchType
+				if nameFormat.NameMatchType == types.NameMatchTypeRegex {
+					pattern, err := regexp.Compile("(?i)" + resource.ResourceName)
+					if err != nil {
+						return fmt.Errorf("invalid regular expression %s from the Regex name format %s for %s: %w", resource.ResourceName, nameFormat.NameFormat, resource.Address, err)
+					}
+					resource.ResourceNamePattern = pattern
+				}
 				foundName = true
 				break
 			}
@@ -263,34 +424,53 @@ func (planClient *PlanClient) mapPropertiesAndNames(resources []*types.PlanResou
 		}
 	}
 
-	return resources
+	return nil
 }
 
-func (planClient *PlanClient) executeTerraformInit(chDir string) {
+func (planClient *PlanClient) executeTerraformInit(ctx context.Context, chDir string) error {
 	var cmd *exec.Cmd
 	if planClient.SkipInitUpgrade {
-		cmd = exec.Command("terraform", chDir, "init")
+		cmd = newCommand(ctx, "terraform", chDir, "init")
 	} else {
-		cmd = exec.Command("terraform", chDir, "init", "-upgrade")
+		cmd = newCommand(ctx, "terraform", chDir, "init", "-upgrade")
 	}
+	env, err := planClient.getPluginCacheEnv(cmd.Environ())
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	planClient.Logger.Infof("Running Terraform init: %s", cmd.String())
-	if err := cmd.Run(); err != nil {
-		planClient.Logger.Fatalf("Error: %s", err)
+	if err := runCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("terraform init failed: %w", err)
 	}
+	return nil
 }
 
-func (planClient *PlanClient) executeTerraformPlan(chDir string, planFileName string) {
+// executeTerraformPlan runs terraform plan. With a plan log file name, the plan is run with -json so the diagnostics of a
+// failed plan can be read back and returned in a PlanError.
+func (planClient *PlanClient) executeTerraformPlan(ctx context.Context, chDir string, planFileName string, planLogFileName string) error {
 	planFilePath := filepath.Join(planClient.WorkingFolderPath, planFileName)
 
-	cmd := exec.Command("terraform", chDir, "plan", fmt.Sprintf("-out=%s", planFilePath))
+	args := []string{chDir, "plan", fmt.Sprintf("-out=%s", planFilePath)}
+	if planClient.KeepBackend {
+		args = append(args, "-lock=false")
+	}
+	if planLogFileName != "" {
+		args = append(args, "-json")
+	}
+	cmd := newCommand(ctx, "terraform", args...)
 	env := cmd.Environ()
 
 	subscriptionID := planClient.SubscriptionID
 	if subscriptionID == "" {
-		subscriptionID = planClient.getCurrentSubscriptionID()
+		currentSubscriptionID, err := planClient.getCurrentSubscriptionID(ctx)
+		if err != nil {
+			return err
+		}
+		subscriptionID = currentSubscriptionID
 	}
 
 	env = append(env, fmt.Sprintf("ARM_SUBSCRIPTION_ID=%s", subscriptionID))
@@ -298,13 +478,45 @@ func (planClient *PlanClient) executeTerraformPlan(chDir string, planFileName st
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
+	planLogFilePath := filepath.Join(planClient.WorkingFolderPath, planLogFileName)
+	if planLogFileName != "" {
+		file, err := os.Create(planLogFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer file.Close()
+		cmd.Stdout = &planLogWriter{file: file, logger: planClient.Logger}
+	}
+
 	planClient.Logger.Infof("Running Terraform plan: %s", cmd.String())
-	if err := cmd.Run(); err != nil {
-		planClient.Logger.Fatalf("Error: %s", err)
+	if err := runCommand(ctx, cmd); err != nil {
+		err = fmt.Errorf("terraform plan failed: %w", err)
+		if planLogFileName == "" || ctx.Err() != nil {
+			return err
+		}
+		return planClient.getPlanError(planLogFilePath, err)
+	}
+	return nil
+}
+
+// getPlanError reads the error diagnostics of a failed plan from its log file.
+func (planClient *PlanClient) getPlanError(planLogFilePath string, err error) error {
+	file, openErr := os.Open(planLogFilePath)
+	if openErr != nil {
+		planClient.Logger.Warnf("Failed to read the diagnostics of the plan from %s: %v", planLogFilePath, openErr)
+		return err
+	}
+	defer file.Close()
+
+	diagnostics, readErr := readPlanDiagnostics(file)
+	if readErr != nil {
+		planClient.Logger.Warnf("Failed to read the diagnostics of the plan from %s: %v", planLogFilePath, readErr)
+		return err
 	}
+	return &PlanError{Diagnostics: diagnostics, Err: err}
 }
 
-func (planClient *PlanClient) executeTerraformShow(chDir string, planFileName string, outputFileName string, jsonPlan bool) {
+func (planClient *PlanClient) executeTerraformShow(ctx context.Context, chDir string, planFileName string, outputFileName string, jsonPlan bool) error {
 	planFilePath := filepath.Join(planClient.WorkingFolderPath, planFileName)
 	jsonFilePath := filepath.Join(planClient.WorkingFolderPath, outputFileName)
 
@@ -313,10 +525,10 @@ func (planClient *PlanClient) executeTerraformShow(chDir string, planFileName st
 		argument = "-no-color"
 	}
 
-	cmd := exec.Command("terraform", chDir, "show", argument, planFilePath)
+	cmd := newCommand(ctx, "terraform", chDir, "show", argument, planFilePath)
 	file, err := os.Create(jsonFilePath)
 	if err != nil {
-		planClient.Logger.Fatalf("Failed to create file: %v", err)
+		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
@@ -324,31 +536,89 @@ func (planClient *PlanClient) executeTerraformShow(chDir string, planFileName st
 	cmd.Stderr = os.Stderr
 
 	planClient.Logger.Infof("Running Terraform show: %s", cmd.String())
-	if err := cmd.Run(); err != nil {
-		planClient.Logger.Fatalf("Error: %s", err)
+	if err := runCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("terraform show failed: %w", err)
 	}
+	return nil
 }
 
-func (planClient *PlanClient) createBackendOverrideFile() string {
-	backendOverrideFilePath := filepath.Join(planClient.TerraformModulePath, "backend_override.tf")
+// createBackendOverrideFile writes an override file that switches the module to a local backend.
+// An existing backend_override.tf in the module is renamed to a backup file first, so it can be restored afterwards.
+func (planClient *PlanClient) createBackendOverrideFile() (string, string, error) {
+	backendOverrideFilePath := filepath.Join(planClient.TerraformModulePath, backendOverrideFileName)
+	backupFilePath := ""
+
+	existingContent, err := os.ReadFile(backendOverrideFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read existing backend override file: %w", err)
+	}
+	if err == nil && string(existingContent) != backendOverrideFileContent {
+		backupFilePath = backendOverrideFilePath + backendOverrideBackupSuffix
+		if _, err := os.Stat(backupFilePath); err == nil {
+			return "", "", fmt.Errorf("backend override backup file %s already exists from a previous run, restore it to %s or remove it", backupFilePath, backendOverrideFilePath)
+		}
+		planClient.Logger.Infof("Backing up existing backend override file %s to %s", backendOverrideFilePath, backupFilePath)
+		if err := os.Rename(backendOverrideFilePath, backupFilePath); err != nil {
+			return "", "", fmt.Errorf("failed to back up existing backend override file: %w", err)
+		}
+	}
 
 	planClient.Logger.Tracef("Creating backend override file: %s", backendOverrideFilePath)
 
-	backendOverrideFile, err := os.Create(backendOverrideFilePath)
-	if err != nil {
-		planClient.Logger.Fatalf("Failed to create file: %v", err)
-	}
-	defer backendOverrideFile.Close()
-	_, err = backendOverrideFile.WriteString(fmt.Sprintf("terraform {\n  backend \"local\" {}\n}\n"))
+	err = os.WriteFile(backendOverrideFilePath, []byte(backendOverrideFileContent), 0644)
 	if err != nil {
-		planClient.Logger.Fatalf("Failed to write to file: %v", err)
+		planClient.removeBackendOverrideFile(backendOverrideFilePath, backupFilePath)
+		return "", "", fmt.Errorf("failed to write to file: %w", err)
 	}
-	return backendOverrideFilePath
+	return backendOverrideFilePath, backupFilePath, nil
 }
 
-func (planClient *PlanClient) removeBackendOverrideFile(backendOverrideFilePath string) {
+// removeBackendOverrideFile removes the override file and restores the backup of the user's file if there is one.
+func (planClient *PlanClient) removeBackendOverrideFile(backendOverrideFilePath string, backupFilePath string) {
 	err := os.Remove(backendOverrideFilePath)
-	if err != nil {
-		planClient.Logger.Fatalf("Failed to remove file: %v", err)
+	if err != nil && !os.IsNotExist(err) {
+		planClient.Logger.Errorf("Failed to remove file %s, remove it before running terraform in the module: %v", backendOverrideFilePath, err)
+		return
+	}
+	if backupFilePath == "" {
+		return
+	}
+	planClient.Logger.Infof("Restoring backend override file %s from %s", backendOverrideFilePath, backupFilePath)
+	if err := os.Rename(backupFilePath, backendOverrideFilePath); err != nil {
+		planClient.Logger.Errorf("Failed to restore file %s, rename %s to %s before running terraform in the module: %v", backendOverrideFilePath, backupFilePath, backendOverrideFilePath, err)
+	}
+}
+
+// getPluginCacheEnv points terraform at the shared plugin cache, unless the environment already sets one.
+func (planClient *PlanClient) getPluginCacheEnv(env []string) ([]string, error) {
+	if planClient.PluginCacheDir == "" || os.Getenv("TF_PLUGIN_CACHE_DIR") != "" {
+		return env, nil
+	}
+	if err := os.MkdirAll(planClient.PluginCacheDir, 0755); err != nil {
+		return env, fmt.Errorf("failed to create plugin cache directory: %w", err)
+	}
+	return append(env, fmt.Sprintf("TF_PLUGIN_CACHE_DIR=%s", planClient.PluginCacheDir)), nil
+}
+
+// newCommand creates a command that is interrupted when the context is done, so terraform can release its lock and exit,
+// and is killed if it has not exited after terraformInterruptWaitDelay.
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = terraformInterruptWaitDelay
+	return cmd
+}
+
+// runCommand runs a command and reports the context error when the command was stopped because the context is done.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	err := cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
 	}
+	return err
 }
@@ -0,0 +1,164 @@
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+func Test_runCommand_ReturnsContextErrorWhenTimedOut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := runCommand(ctx, newCommand(ctx, "sleep", "5"))
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPlanClient_runTerraform_RemovesBackendOverrideFileWhenTerraformFails(t *testing.T) {
+	modulePath := t.TempDir()
+	t.Setenv("PATH", "")
+	planClient := &PlanClient{TerraformModulePath: modulePath, WorkingFolderPath: t.TempDir(), SkipInitOnly: true, SubscriptionID: "sub", Logger: logrus.New()}
+
+	err := planClient.runTerraform(context.Background(), "tfplan.json", true, "")
+
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(modulePath, "backend_override.tf"))
+}
+
+func TestPlanClient_runTerraform_WithCancelledContext(t *testing.T) {
+	modulePath := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	planClient := &PlanClient{TerraformModulePath: modulePath, WorkingFolderPath: t.TempDir(), Logger: logrus.New()}
+
+	err := planClient.runTerraform(ctx, "tfplan.json", true, "")
+
+	assert.ErrorIs(t, err, context.Canceled)
+	entries, _ := os.ReadDir(modulePath)
+	assert.Empty(t, entries)
+}
+
+func TestPlanClient_createBackendOverrideFile_BacksUpAndRestoresExistingFile(t *testing.T) {
+	modulePath := t.TempDir()
+	backendOverrideFilePath := filepath.Join(modulePath, "backend_override.tf")
+	userContent := "terraform {\n  backend \"azurerm\" {}\n}\n"
+	assert.NoError(t, os.WriteFile(backendOverrideFilePath, []byte(userContent), 0644))
+	planClient := &PlanClient{TerraformModulePath: modulePath, Logger: logrus.New()}
+
+	filePath, backupFilePath, err := planClient.createBackendOverrideFile()
+
+	assert.NoError(t, err)
+	assert.Equal(t, backendOverrideFilePath, filePath)
+	assert.FileExists(t, backupFilePath)
+	content, _ := os.ReadFile(backendOverrideFilePath)
+	assert.Equal(t, backendOverrideFileContent, string(content))
+
+	planClient.removeBackendOverrideFile(filePath, backupFilePath)
+
+	content, _ = os.ReadFile(backendOverrideFilePath)
+	assert.Equal(t, userContent, string(content))
+	assert.NoFileExists(t, backupFilePath)
+}
+
+func TestPlanClient_createBackendOverrideFile_WithExistingBackup(t *testing.T) {
+	modulePath := t.TempDir()
+	backendOverrideFilePath := filepath.Join(modulePath, "backend_override.tf")
+	assert.NoError(t, os.WriteFile(backendOverrideFilePath, []byte("user"), 0644))
+	assert.NoError(t, os.WriteFile(backendOverrideFilePath+backendOverrideBackupSuffix, []byte("backup"), 0644))
+	planClient := &PlanClient{TerraformModulePath: modulePath, Logger: logrus.New()}
+
+	_, _, err := planClient.createBackendOverrideFile()
+
+	assert.ErrorContains(t, err, "already exists")
+	content, _ := os.ReadFile(backendOverrideFilePath)
+	assert.Equal(t, "user", string(content))
+}
+
+func TestPlanClient_runTerraform_WithKeepBackend(t *testing.T) {
+	modulePath := t.TempDir()
+	t.Setenv("PATH", "")
+	planClient := &PlanClient{TerraformModulePath: modulePath, WorkingFolderPath: t.TempDir(), SkipInitOnly: true, SubscriptionID: "sub", KeepBackend: true, Logger: logrus.New()}
+
+	err := planClient.runTerraform(context.Background(), "tfplan.json", true, "")
+
+	assert.Error(t, err)
+	entries, _ := os.ReadDir(modulePath)
+	assert.Empty(t, entries)
+}
+
+func Test_readImportChangesFromPlan(t *testing.T) {
+	plan := map[string]any{
+		"resource_changes": []any{
+			map[string]any{
+				"address": "azurerm_resource_group.rg",
+				"change":  map[string]any{"actions": []any{"no-op"}, "importing": map[string]any{"id": "/subscriptions/sub/resourceGroups/rg"}},
+			},
+			map[string]any{
+				"address": "azurerm_virtual_network.vnet",
+				"change":  map[string]any{"actions": []any{"create"}},
+			},
+		},
+	}
+
+	importChanges := readImportChangesFromPlan(plan)
+
+	assert.Len(t, importChanges, 1)
+	assert.Equal(t, "azurerm_resource_group.rg", importChanges[0].Address)
+	assert.Equal(t, "/subscriptions/sub/resourceGroups/rg", importChanges[0].ImportID)
+	assert.Equal(t, []string{"no-op"}, importChanges[0].Actions)
+}
+
+func TestNewPlanClient_DoesNotShareDefaultRules(t *testing.T) {
+	defaultRuleCount := len(defaultDriftIgnoreRules)
+	// Spare capacity in the defaults would let each client overwrite the rules of the others
+	defaultDriftIgnoreRules = slices.Grow(defaultDriftIgnoreRules, 2)
+	defer func() { defaultDriftIgnoreRules = slices.Clip(defaultDriftIgnoreRules) }()
+
+	first, err := NewPlanClient("", "", "", nil, false, false, false, false, "", "", nil, nil, []types.DriftIgnoreRule{{AttributePath: "first"}}, nil, 0, nil, nil)
+	assert.NoError(t, err)
+	second, err := NewPlanClient("", "", "", nil, false, false, false, false, "", "", nil, nil, []types.DriftIgnoreRule{{AttributePath: "second"}}, nil, 0, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "first", first.DriftIgnoreRules[defaultRuleCount].AttributePath)
+	assert.Equal(t, "second", second.DriftIgnoreRules[defaultRuleCount].AttributePath)
+	assert.Len(t, defaultDriftIgnoreRules, defaultRuleCount)
+}
+
+func TestPlanClient_mapPropertiesAndNames_RegexNameFormat(t *testing.T) {
+	nameFormats := []types.NameFormat{{Type: "azurerm_virtual_network", NameFormat: `/resourceGroups/%s/providers/Microsoft.Network/virtualNetworks/%s$`, NameMatchType: types.NameMatchTypeRegex, NameFormatArguments: []string{"resource_group_name", "name"}}}
+	planClient, err := NewPlanClient("", "", "", nil, false, false, false, false, "", "", nil, nameFormats, nil, nil, 0, nil, logrus.New())
+	assert.NoError(t, err)
+	resources := []*types.PlanResource{
+		{Address: "azurerm_virtual_network.hub", Type: "azurerm_virtual_network", Properties: map[string]any{"resource_group_name": "rg-hub", "name": "vnet-hub"}},
+		{Address: "azurerm_virtual_network.broken", Type: "azurerm_virtual_network", Properties: map[string]any{"resource_group_name": "rg-(hub", "name": "vnet-hub"}},
+	}
+
+	err = planClient.mapPropertiesAndNames(resources[:1])
+
+	assert.NoError(t, err)
+	assert.True(t, resources[0].ResourceNamePattern.MatchString("/subscriptions/sub/resourcegroups/RG-HUB/providers/Microsoft.Network/virtualNetworks/vnet-hub"))
+
+	err = planClient.mapPropertiesAndNames(resources[1:])
+
+	assert.ErrorContains(t, err, "invalid regular expression /resourceGroups/rg-(hub/providers/Microsoft.Network/virtualNetworks/vnet-hub$ from the Regex name format /resourceGroups/%s/providers/Microsoft.Network/virtualNetworks/%s$ for azurerm_virtual_network.broken: error parsing regexp: missing closing )")
+}
+
+func TestNewPlanClient_InvalidRegexNameFormat(t *testing.T) {
+	nameFormats := []types.NameFormat{
+		{Type: "azurerm_resource_group", NameFormat: "%s", NameMatchType: types.NameMatchTypeExact, NameFormatArguments: []string{"name"}},
+		{Type: "azurerm_virtual_network", NameFormat: `/virtualNetworks/(%s$`, NameMatchType: types.NameMatchTypeRegex, NameFormatArguments: []string{"name"}},
+	}
+
+	_, err := NewPlanClient("", "", "", nil, false, false, false, false, "", "", nil, nameFormats, nil, nil, 0, nil, logrus.New())
+
+	assert.EqualError(t, err, "invalid Regex name format /virtualNetworks/(%s$ for type azurerm_virtual_network: error parsing regexp: missing closing ): `/virtualNetworks/(name$`")
+}
@@ -2,19 +2,20 @@ package terraform
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 )
 
-func (planClient *PlanClient) ExtractUpdateResourcesFromPlan(sourcePlanFileName string, outputPlanFileName string) {
+func (planClient *PlanClient) ExtractUpdateResourcesFromPlan(sourcePlanFileName string, outputPlanFileName string) error {
 	planFilePath := filepath.Join(planClient.WorkingFolderPath, sourcePlanFileName)
 	outputPlanFilePath := filepath.Join(planClient.WorkingFolderPath, outputPlanFileName)
 
 	file, err := os.Open(planFilePath)
 	if err != nil {
-		planClient.Logger.Fatal(err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
@@ -25,20 +26,8 @@ func (planClient *PlanClient) ExtractUpdateResourcesFromPlan(sourcePlanFileName
 	keepLines := false
 	resourceCommentLines := []string{}
 
-	updatePrefixes := []string{
-		"      ~",
-		"      -",
-		"      +",
-	}
-
-	skippablePrefixes := []string{
-		"      + replace_triggers_external_values",
-		"      + retry",
-		"      + timeouts",
-		"      ~ output",
-	}
-
 	resourceBuffer := []string{}
+	suppressedDrifts := []suppressedDrift{}
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -82,27 +71,44 @@ func (planClient *PlanClient) ExtractUpdateResourcesFromPlan(sourcePlanFileName
 		if keepLines && strings.HasPrefix(line, "    }") {
 			keepLines = false
 
-			skippable := true
+			resourceType := ""
+			if match := planResourceHeaderRegex.FindStringSubmatch(resourceBuffer[0]); match != nil {
+				resourceType = match[1]
+			}
+			subType := ""
 			for _, bufferLine := range resourceBuffer {
-				for _, updatePrefix := range updatePrefixes {
-					if strings.HasPrefix(bufferLine, updatePrefix) {
-						anySkippableMatches := false
-						for _, skippablePrefix := range skippablePrefixes {
-							if strings.HasPrefix(bufferLine, skippablePrefix) {
-								planClient.Logger.Tracef("Skippable Line Match: %s", line)
-								anySkippableMatches = true
-								break
-							} else {
-								planClient.Logger.Tracef("No Skippable Line: %s", line)
-							}
-						}
-						if !anySkippableMatches {
-							skippable = false
-							planClient.Logger.Tracef("Non Skippable Line: %s", line)
-						} else {
-							planClient.Logger.Tracef("Skippable Line: %s", line)
-						}
-					}
+				if match := planSubTypeRegex.FindStringSubmatch(bufferLine); match != nil {
+					subType = match[1]
+					break
+				}
+			}
+			address := resourceType
+			if len(resourceCommentLines) > 0 {
+				address = strings.Fields(resourceCommentLines[0])[1]
+			}
+
+			skippable := true
+			resourceSuppressedDrifts := []suppressedDrift{}
+			attributeLines := parsePlanAttributeLines(resourceBuffer)
+			for i, attributeLine := range attributeLines {
+				if attributeLine == nil || !attributeLine.IsChange {
+					continue
+				}
+				if attributeLine.Opens && hasNestedChanges(attributeLines, i) {
+					continue
+				}
+
+				if rule, matched := planClient.matchDriftIgnoreRule(resourceType, subType, attributeLine.Path); matched {
+					planClient.Logger.Tracef("Ignored Drift Line: %s, Reason: %s", resourceBuffer[i], rule.Reason)
+					resourceBuffer[i] = fmt.Sprintf("%s # drift ignored: %s", resourceBuffer[i], rule.Reason)
+					resourceSuppressedDrifts = append(resourceSuppressedDrifts, suppressedDrift{
+						Address:       address,
+						AttributePath: attributeLine.Path,
+						Reason:        rule.Reason,
+					})
+				} else {
+					planClient.Logger.Tracef("Non Skippable Line: %s", resourceBuffer[i])
+					skippable = false
 				}
 			}
 
@@ -110,6 +116,8 @@ func (planClient *PlanClient) ExtractUpdateResourcesFromPlan(sourcePlanFileName
 				updateLines = append(updateLines, resourceCommentLines...)
 				updateLines = append(updateLines, resourceBuffer...)
 				updateLines = append(updateLines, "")
+			} else {
+				suppressedDrifts = append(suppressedDrifts, resourceSuppressedDrifts...)
 			}
 		}
 
@@ -119,19 +127,30 @@ func (planClient *PlanClient) ExtractUpdateResourcesFromPlan(sourcePlanFileName
 	}
 
 	if err := scanner.Err(); err != nil {
-		planClient.Logger.Fatal(err)
+		return fmt.Errorf("failed to read %s: %w", planFilePath, err)
+	}
+
+	if len(suppressedDrifts) > 0 {
+		updateLines = append(updateLines, "# Resources with only ignored drift:")
+		for _, drift := range suppressedDrifts {
+			updateLines = append(updateLines, fmt.Sprintf("#   %s: %s (%s)", drift.Address, drift.AttributePath, drift.Reason))
+		}
+		updateLines = append(updateLines, "")
 	}
 
 	content := strings.Join(updateLines, "\n")
 
 	file, err = os.Create(outputPlanFilePath)
 	if err != nil {
-		planClient.Logger.Fatalf("Failed to create file: %v", err)
+		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 	writer := bufio.NewWriter(file)
-	_, err = writer.WriteString(content)
-	if err != nil {
-		planClient.Logger.Fatalf("Failed to write to file: %v", err)
+	if _, err = writer.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
 	}
+	return nil
 }
@@ -0,0 +1,157 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTextPlan = `Terraform will perform the following actions:
+
+  # azapi_resource.vnet will be updated in-place
+  ~ resource "azapi_resource" "vnet" {
+      ~ body                      = {
+          ~ properties = {
+              ~ addressSpace = {
+                  ~ addressPrefixes = [
+                      - "10.0.0.0/16",
+                      + "10.1.0.0/16",
+                    ]
+                }
+            }
+        }
+        id                        = "/subscriptions/123/resourceGroups/rg1/providers/Microsoft.Network/virtualNetworks/vnet"
+        name                      = "vnet"
+      ~ output                    = {
+          - properties = {} -> null
+        }
+        type                      = "Microsoft.Network/virtualNetworks@2024-01-01"
+    }
+
+  # azapi_resource.pip will be updated in-place
+  ~ resource "azapi_resource" "pip" {
+        id                        = "/subscriptions/123/resourceGroups/rg1/providers/Microsoft.Network/publicIPAddresses/pip"
+        name                      = "pip"
+      ~ output                    = {
+          - properties = {} -> null
+        }
+      + retry                     = null
+        type                      = "Microsoft.Network/publicIPAddresses@2024-01-01"
+    }
+
+  # azurerm_resource_group.rg will be updated in-place
+  ~ resource "azurerm_resource_group" "rg" {
+        id       = "/subscriptions/123/resourceGroups/rg1"
+        name     = "rg1"
+      ~ tags     = {
+          - "policy-managed" = "true" -> null
+        }
+    }
+
+Plan: 0 to add, 3 to change, 0 to destroy.
+`
+
+func runExtractUpdateResourcesFromPlan(t *testing.T, driftIgnoreRules []types.DriftIgnoreRule) string {
+	workingFolderPath := t.TempDir()
+	err := os.WriteFile(filepath.Join(workingFolderPath, "tfplan.txt"), []byte(testTextPlan), 0644)
+	assert.NoError(t, err)
+
+	planClient, err := NewPlanClient("", workingFolderPath, "", nil, false, false, false, false, "", "", nil, nil, driftIgnoreRules, nil, 0, nil, logrus.New())
+	assert.NoError(t, err)
+	assert.NoError(t, planClient.ExtractUpdateResourcesFromPlan("tfplan.txt", "tfplan_updates.txt"))
+
+	content, err := os.ReadFile(filepath.Join(workingFolderPath, "tfplan_updates.txt"))
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestExtractUpdateResourcesFromPlan_DefaultRules(t *testing.T) {
+	content := runExtractUpdateResourcesFromPlan(t, nil)
+
+	assert.Contains(t, content, `~ resource "azapi_resource" "vnet"`)
+	assert.Contains(t, content, `~ resource "azurerm_resource_group" "rg"`)
+	assert.NotContains(t, content, `~ resource "azapi_resource" "pip"`)
+	assert.Contains(t, content, "#   azapi_resource.pip: output.properties (azapi computed output)")
+	assert.Contains(t, content, "#   azapi_resource.pip: retry (retry settings, not an Azure property)")
+}
+
+func TestExtractUpdateResourcesFromPlan_GlobRule(t *testing.T) {
+	content := runExtractUpdateResourcesFromPlan(t, []types.DriftIgnoreRule{
+		{Type: "azurerm_*", AttributePath: "tags", MatchType: types.DriftIgnoreMatchTypeGlob, Reason: "tags managed by policy"},
+	})
+
+	assert.NotContains(t, content, `~ resource "azurerm_resource_group" "rg"`)
+	assert.Contains(t, content, "#   azurerm_resource_group.rg: tags.policy-managed (tags managed by policy)")
+}
+
+func TestExtractUpdateResourcesFromPlan_RegexRuleWithSubType(t *testing.T) {
+	content := runExtractUpdateResourcesFromPlan(t, []types.DriftIgnoreRule{
+		{Type: "azapi_resource", SubType: "^Microsoft.Network/virtualNetworks$", AttributePath: `^body\.properties\.addressSpace$`, MatchType: types.DriftIgnoreMatchTypeRegex, Reason: "address space managed by IPAM"},
+	})
+
+	assert.NotContains(t, content, `~ resource "azapi_resource" "vnet"`)
+	assert.Contains(t, content, "#   azapi_resource.vnet: body.properties.addressSpace.addressPrefixes (address space managed by IPAM)")
+}
+
+func TestExtractUpdateResourcesFromPlan_AnnotatesIgnoredLinesInKeptResources(t *testing.T) {
+	content := runExtractUpdateResourcesFromPlan(t, nil)
+
+	assert.Contains(t, content, "          - properties = {} -> null # drift ignored: azapi computed output\n")
+	assert.Contains(t, content, "                      + \"10.1.0.0/16\",\n")
+}
+
+func TestExtractUpdateResourcesFromPlan_DefaultRulesApplyToEveryType(t *testing.T) {
+	plan := `  # azurerm_resource_group.rg will be updated in-place
+  ~ resource "azurerm_resource_group" "rg" {
+        id       = "/subscriptions/123/resourceGroups/rg1"
+        name     = "rg1"
+      + timeouts {
+          + create = "30m"
+        }
+    }
+`
+	workingFolderPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(workingFolderPath, "tfplan.txt"), []byte(plan), 0644))
+	planClient, err := NewPlanClient("", workingFolderPath, "", nil, false, false, false, false, "", "", nil, nil, nil, nil, 0, nil, logrus.New())
+	assert.NoError(t, err)
+
+	assert.NoError(t, planClient.ExtractUpdateResourcesFromPlan("tfplan.txt", "tfplan_updates.txt"))
+
+	content, err := os.ReadFile(filepath.Join(workingFolderPath, "tfplan_updates.txt"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), `~ resource "azurerm_resource_group" "rg"`)
+	assert.Contains(t, string(content), "#   azurerm_resource_group.rg: timeouts.create (timeouts, not an Azure property)")
+}
+
+func Test_getDriftIgnoreRules_ConfiguredRuleReplacesDefault(t *testing.T) {
+	driftIgnoreRules := getDriftIgnoreRules([]types.DriftIgnoreRule{
+		{Type: "azapi_*", AttributePath: "timeouts", MatchType: types.DriftIgnoreMatchTypeGlob, Reason: "azapi timeouts"},
+	})
+
+	attributePaths := []string{}
+	for _, driftIgnoreRule := range driftIgnoreRules {
+		attributePaths = append(attributePaths, driftIgnoreRule.Type+":"+driftIgnoreRule.AttributePath)
+	}
+	assert.Equal(t, []string{":replace_triggers_external_values", ":retry", ":output", "azapi_*:timeouts"}, attributePaths)
+}
+
+func TestNewPlanClient_InvalidDriftIgnoreRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     types.DriftIgnoreRule
+		expected string
+	}{
+		{"regex", types.DriftIgnoreRule{SubType: "^Microsoft.Network/(virtualNetworks$", AttributePath: "body", MatchType: types.DriftIgnoreMatchTypeRegex}, `invalid subType pattern "^Microsoft.Network/(virtualNetworks$" in the drift ignore rule for attribute path body: error parsing regexp: missing closing ): ` + "`^Microsoft.Network/(virtualNetworks$`"},
+		{"glob", types.DriftIgnoreRule{Type: "azurerm_[", AttributePath: "tags", MatchType: types.DriftIgnoreMatchTypeGlob}, `invalid type pattern "azurerm_[" in the drift ignore rule for attribute path tags: syntax error in pattern`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewPlanClient("", "", "", nil, false, false, false, false, "", "", nil, nil, []types.DriftIgnoreRule{test.rule}, nil, 0, nil, logrus.New())
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
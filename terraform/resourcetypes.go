@@ -0,0 +1,90 @@
+package terraform
+
+import (
+	"strings"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// defaultResourceTypeMappings maps Terraform resource types to the Azure resource types returned by Resource Graph.
+// An azapi_resource uses its sub type unless it is listed here, since a few Resource Graph types differ from the ARM type.
+var defaultResourceTypeMappings = []types.ResourceTypeMapping{
+	{Type: "azapi_resource", SubType: "Microsoft.Resources/resourceGroups", AzureResourceType: "Microsoft.Resources/subscriptions/resourceGroups"},
+	{Type: "azapi_resource", SubType: "Microsoft.Management/managementGroups/subscriptions", AzureResourceType: "Microsoft.Resources/subscriptions"},
+
+	{Type: "azurerm_resource_group", AzureResourceType: "Microsoft.Resources/subscriptions/resourceGroups"},
+	{Type: "azurerm_management_group", AzureResourceType: "Microsoft.Management/managementGroups"},
+	{Type: "azurerm_management_group_subscription_association", AzureResourceType: "Microsoft.Resources/subscriptions"},
+	{Type: "azurerm_policy_definition", AzureResourceType: "Microsoft.Authorization/policyDefinitions"},
+	{Type: "azurerm_policy_set_definition", AzureResourceType: "Microsoft.Authorization/policySetDefinitions"},
+	{Type: "azurerm_management_group_policy_assignment", AzureResourceType: "Microsoft.Authorization/policyAssignments"},
+	{Type: "azurerm_subscription_policy_assignment", AzureResourceType: "Microsoft.Authorization/policyAssignments"},
+	{Type: "azurerm_resource_group_policy_assignment", AzureResourceType: "Microsoft.Authorization/policyAssignments"},
+	{Type: "azurerm_resource_policy_assignment", AzureResourceType: "Microsoft.Authorization/policyAssignments"},
+	{Type: "azurerm_role_definition", AzureResourceType: "Microsoft.Authorization/roleDefinitions"},
+	{Type: "azurerm_role_assignment", AzureResourceType: "Microsoft.Authorization/roleAssignments"},
+
+	{Type: "azurerm_virtual_network", AzureResourceType: "Microsoft.Network/virtualNetworks"},
+	{Type: "azurerm_subnet", AzureResourceType: "Microsoft.Network/virtualNetworks/subnets"},
+	{Type: "azurerm_virtual_network_peering", AzureResourceType: "Microsoft.Network/virtualNetworks/virtualNetworkPeerings"},
+	{Type: "azurerm_network_security_group", AzureResourceType: "Microsoft.Network/networkSecurityGroups"},
+	{Type: "azurerm_network_security_rule", AzureResourceType: "Microsoft.Network/networkSecurityGroups/securityRules"},
+	{Type: "azurerm_route_table", AzureResourceType: "Microsoft.Network/routeTables"},
+	{Type: "azurerm_route", AzureResourceType: "Microsoft.Network/routeTables/routes"},
+	{Type: "azurerm_public_ip", AzureResourceType: "Microsoft.Network/publicIPAddresses"},
+	{Type: "azurerm_public_ip_prefix", AzureResourceType: "Microsoft.Network/publicIPPrefixes"},
+	{Type: "azurerm_network_interface", AzureResourceType: "Microsoft.Network/networkInterfaces"},
+	{Type: "azurerm_nat_gateway", AzureResourceType: "Microsoft.Network/natGateways"},
+	{Type: "azurerm_network_ddos_protection_plan", AzureResourceType: "Microsoft.Network/ddosProtectionPlans"},
+	{Type: "azurerm_network_watcher", AzureResourceType: "Microsoft.Network/networkWatchers"},
+	{Type: "azurerm_firewall", AzureResourceType: "Microsoft.Network/azureFirewalls"},
+	{Type: "azurerm_firewall_policy", AzureResourceType: "Microsoft.Network/firewallPolicies"},
+	{Type: "azurerm_firewall_policy_rule_collection_group", AzureResourceType: "Microsoft.Network/firewallPolicies/ruleCollectionGroups"},
+	{Type: "azurerm_bastion_host", AzureResourceType: "Microsoft.Network/bastionHosts"},
+	{Type: "azurerm_virtual_network_gateway", AzureResourceType: "Microsoft.Network/virtualNetworkGateways"},
+	{Type: "azurerm_virtual_network_gateway_connection", AzureResourceType: "Microsoft.Network/connections"},
+	{Type: "azurerm_local_network_gateway", AzureResourceType: "Microsoft.Network/localNetworkGateways"},
+	{Type: "azurerm_express_route_circuit", AzureResourceType: "Microsoft.Network/expressRouteCircuits"},
+	{Type: "azurerm_virtual_wan", AzureResourceType: "Microsoft.Network/virtualWans"},
+	{Type: "azurerm_virtual_hub", AzureResourceType: "Microsoft.Network/virtualHubs"},
+	{Type: "azurerm_virtual_hub_connection", AzureResourceType: "Microsoft.Network/virtualHubs/hubVirtualNetworkConnections"},
+	{Type: "azurerm_virtual_hub_routing_intent", AzureResourceType: "Microsoft.Network/virtualHubs/routingIntent"},
+	{Type: "azurerm_vpn_gateway", AzureResourceType: "Microsoft.Network/vpnGateways"},
+	{Type: "azurerm_vpn_site", AzureResourceType: "Microsoft.Network/vpnSites"},
+	{Type: "azurerm_point_to_site_vpn_gateway", AzureResourceType: "Microsoft.Network/p2sVpnGateways"},
+	{Type: "azurerm_express_route_gateway", AzureResourceType: "Microsoft.Network/expressRouteGateways"},
+	{Type: "azurerm_private_dns_zone", AzureResourceType: "Microsoft.Network/privateDnsZones"},
+	{Type: "azurerm_private_dns_zone_virtual_network_link", AzureResourceType: "Microsoft.Network/privateDnsZones/virtualNetworkLinks"},
+	{Type: "azurerm_private_dns_resolver", AzureResourceType: "Microsoft.Network/dnsResolvers"},
+	{Type: "azurerm_private_dns_resolver_inbound_endpoint", AzureResourceType: "Microsoft.Network/dnsResolvers/inboundEndpoints"},
+	{Type: "azurerm_private_dns_resolver_outbound_endpoint", AzureResourceType: "Microsoft.Network/dnsResolvers/outboundEndpoints"},
+	{Type: "azurerm_private_dns_resolver_dns_forwarding_ruleset", AzureResourceType: "Microsoft.Network/dnsForwardingRulesets"},
+	{Type: "azurerm_dns_zone", AzureResourceType: "Microsoft.Network/dnsZones"},
+	{Type: "azurerm_private_endpoint", AzureResourceType: "Microsoft.Network/privateEndpoints"},
+	{Type: "azurerm_application_gateway", AzureResourceType: "Microsoft.Network/applicationGateways"},
+	{Type: "azurerm_lb", AzureResourceType: "Microsoft.Network/loadBalancers"},
+
+	{Type: "azurerm_log_analytics_workspace", AzureResourceType: "Microsoft.OperationalInsights/workspaces"},
+	{Type: "azurerm_log_analytics_solution", AzureResourceType: "Microsoft.OperationsManagement/solutions"},
+	{Type: "azurerm_automation_account", AzureResourceType: "Microsoft.Automation/automationAccounts"},
+	{Type: "azurerm_monitor_action_group", AzureResourceType: "Microsoft.Insights/actionGroups"},
+	{Type: "azurerm_monitor_data_collection_rule", AzureResourceType: "Microsoft.Insights/dataCollectionRules"},
+	{Type: "azurerm_monitor_diagnostic_setting", AzureResourceType: "Microsoft.Insights/diagnosticSettings"},
+	{Type: "azurerm_user_assigned_identity", AzureResourceType: "Microsoft.ManagedIdentity/userAssignedIdentities"},
+	{Type: "azurerm_key_vault", AzureResourceType: "Microsoft.KeyVault/vaults"},
+	{Type: "azurerm_storage_account", AzureResourceType: "Microsoft.Storage/storageAccounts"},
+	{Type: "azurerm_recovery_services_vault", AzureResourceType: "Microsoft.RecoveryServices/vaults"},
+	{Type: "azurerm_linux_virtual_machine", AzureResourceType: "Microsoft.Compute/virtualMachines"},
+	{Type: "azurerm_windows_virtual_machine", AzureResourceType: "Microsoft.Compute/virtualMachines"},
+	{Type: "azurerm_managed_disk", AzureResourceType: "Microsoft.Compute/disks"},
+}
+
+// getAzureResourceType returns the Azure resource type for a Terraform resource type, or an empty string if it is not known.
+func (planClient *PlanClient) getAzureResourceType(resourceType string, subType string) string {
+	for _, resourceTypeMapping := range planClient.ResourceTypeMappings {
+		if resourceTypeMapping.Type == resourceType && (resourceTypeMapping.SubType == "" || strings.EqualFold(resourceTypeMapping.SubType, subType)) {
+			return resourceTypeMapping.AzureResourceType
+		}
+	}
+	return subType
+}
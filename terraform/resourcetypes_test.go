@@ -0,0 +1,59 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/azure/terraform-state-importer/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_defaultResourceTypeMappings_CoverSampleConfigs checks the built in table knows every Terraform and
+// Resource Graph type referenced by the sample configs, so new samples fail here rather than at match time.
+func Test_defaultResourceTypeMappings_CoverSampleConfigs(t *testing.T) {
+	configFiles, err := filepath.Glob(filepath.Join("..", ".config", "*.yaml"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, configFiles)
+
+	terraformTypes := map[string]bool{}
+	azureResourceTypes := map[string]bool{}
+	for _, resourceTypeMapping := range defaultResourceTypeMappings {
+		terraformTypes[resourceTypeMapping.Type] = true
+		azureResourceTypes[strings.ToLower(resourceTypeMapping.AzureResourceType)] = true
+	}
+
+	terraformTypeRegex := regexp.MustCompile(`\bazurerm_[a-z_]+\b`)
+	graphTypeRegex := regexp.MustCompile(`\|\s*where type == "([^"]+)"`)
+
+	for _, configFile := range configFiles {
+		content, err := os.ReadFile(configFile)
+		assert.NoError(t, err)
+
+		for _, terraformType := range terraformTypeRegex.FindAllString(string(content), -1) {
+			assert.True(t, terraformTypes[terraformType], "%s: no resource type mapping for %s", configFile, terraformType)
+		}
+		for _, match := range graphTypeRegex.FindAllStringSubmatch(string(content), -1) {
+			assert.True(t, azureResourceTypes[strings.ToLower(match[1])], "%s: no resource type mapping produces %s", configFile, match[1])
+		}
+	}
+}
+
+func Test_getAzureResourceType(t *testing.T) {
+	planClient := &PlanClient{ResourceTypeMappings: defaultResourceTypeMappings}
+
+	assert.Equal(t, "Microsoft.Network/virtualNetworks", planClient.getAzureResourceType("azurerm_virtual_network", ""))
+	assert.Equal(t, "Microsoft.Resources/subscriptions/resourceGroups", planClient.getAzureResourceType("azapi_resource", "Microsoft.Resources/resourceGroups"))
+	assert.Equal(t, "Microsoft.Network/virtualNetworks", planClient.getAzureResourceType("azapi_resource", "Microsoft.Network/virtualNetworks"))
+	assert.Equal(t, "", planClient.getAzureResourceType("azurerm_unknown", ""))
+}
+
+func Test_getAzureResourceType_OverrideWins(t *testing.T) {
+	planClient, err := NewPlanClient("", "", "", nil, false, false, false, false, "", "", nil, nil, nil,
+		[]types.ResourceTypeMapping{{Type: "azurerm_virtual_network", AzureResourceType: "Custom/type"}}, 0, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Custom/type", planClient.getAzureResourceType("azurerm_virtual_network", ""))
+}
@@ -0,0 +1,124 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+// GetStateResources returns the resources in the state file, or in the prior state of the last plan when no state
+// file is set. The prior state is only populated when the module is planned with its own backend or a local state.
+func (planClient *PlanClient) GetStateResources() ([]*types.StateResource, error) {
+	if planClient.StateFilePath == "" {
+		return planClient.priorStateResources, nil
+	}
+
+	content, err := os.ReadFile(planClient.StateFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	var state map[string]any
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", planClient.StateFilePath, err)
+	}
+
+	stateResources := readStateResources(state)
+	planClient.Logger.Infof("Read %d resources from state file %s", len(stateResources), planClient.StateFilePath)
+	return stateResources, nil
+}
+
+// readStateResources reads the managed resources from a state file, or from the output of terraform show -json.
+func readStateResources(state map[string]any) []*types.StateResource {
+	if values, ok := state["values"].(map[string]any); ok {
+		return readStateValues(values)
+	}
+
+	stateResources := []*types.StateResource{}
+	resources, _ := state["resources"].([]any)
+	for _, rawResource := range resources {
+		resource, _ := rawResource.(map[string]any)
+		if mode, _ := resource["mode"].(string); mode != "managed" {
+			continue
+		}
+		resourceType, _ := resource["type"].(string)
+		name, _ := resource["name"].(string)
+		address := fmt.Sprintf("%s.%s", resourceType, name)
+		if module, _ := resource["module"].(string); module != "" {
+			address = fmt.Sprintf("%s.%s", module, address)
+		}
+
+		instances, _ := resource["instances"].([]any)
+		for _, rawInstance := range instances {
+			instance, _ := rawInstance.(map[string]any)
+			attributes, _ := instance["attributes"].(map[string]any)
+			resourceID, _ := attributes["id"].(string)
+			if resourceID == "" {
+				continue
+			}
+			stateResources = append(stateResources, &types.StateResource{
+				Address:    address + formatIndexKey(instance["index_key"]),
+				Type:       resourceType,
+				ResourceID: resourceID,
+			})
+		}
+	}
+	return stateResources
+}
+
+// readStateValues reads the managed resources from the values of a shown state or the prior state of a plan.
+func readStateValues(values map[string]any) []*types.StateResource {
+	stateResources := []*types.StateResource{}
+	rootModule, ok := values["root_module"].(map[string]any)
+	if !ok {
+		return stateResources
+	}
+
+	modules := []map[string]any{rootModule}
+	for len(modules) > 0 {
+		module := modules[0]
+		modules = modules[1:]
+
+		resources, _ := module["resources"].([]any)
+		for _, rawResource := range resources {
+			resource, _ := rawResource.(map[string]any)
+			if mode, _ := resource["mode"].(string); mode != "managed" {
+				continue
+			}
+			resourceValues, _ := resource["values"].(map[string]any)
+			resourceID, _ := resourceValues["id"].(string)
+			if resourceID == "" {
+				continue
+			}
+			address, _ := resource["address"].(string)
+			resourceType, _ := resource["type"].(string)
+			stateResources = append(stateResources, &types.StateResource{
+				Address:    address,
+				Type:       resourceType,
+				ResourceID: resourceID,
+			})
+		}
+
+		childModules, _ := module["child_modules"].([]any)
+		for _, childModule := range childModules {
+			if childModule, ok := childModule.(map[string]any); ok {
+				modules = append(modules, childModule)
+			}
+		}
+	}
+	return stateResources
+}
+
+// formatIndexKey formats the count index or for_each key of a resource instance as it appears in its address.
+func formatIndexKey(indexKey any) string {
+	switch key := indexKey.(type) {
+	case float64:
+		return fmt.Sprintf("[%d]", int(key))
+	case string:
+		return fmt.Sprintf("[%s]", strconv.Quote(key))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,84 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/azure/terraform-state-importer/types"
+)
+
+func Test_readStateResources_StateFile(t *testing.T) {
+	state := map[string]any{
+		"version": float64(4),
+		"resources": []any{
+			map[string]any{
+				"module": "module.network", "mode": "managed", "type": "azurerm_virtual_network", "name": "vnet",
+				"instances": []any{
+					map[string]any{"index_key": float64(0), "attributes": map[string]any{"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/hub"}},
+					map[string]any{"index_key": float64(1), "attributes": map[string]any{"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/spoke"}},
+				},
+			},
+			map[string]any{
+				"mode": "managed", "type": "azurerm_resource_group", "name": "rg",
+				"instances": []any{map[string]any{"index_key": "primary", "attributes": map[string]any{"id": "/subscriptions/sub/resourceGroups/rg"}}},
+			},
+			map[string]any{
+				"mode": "data", "type": "azurerm_client_config", "name": "current",
+				"instances": []any{map[string]any{"attributes": map[string]any{"id": "data"}}},
+			},
+		},
+	}
+
+	stateResources := readStateResources(state)
+
+	assert.Equal(t, []*types.StateResource{
+		{Address: "module.network.azurerm_virtual_network.vnet[0]", Type: "azurerm_virtual_network", ResourceID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/hub"},
+		{Address: "module.network.azurerm_virtual_network.vnet[1]", Type: "azurerm_virtual_network", ResourceID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/spoke"},
+		{Address: "azurerm_resource_group.rg[\"primary\"]", Type: "azurerm_resource_group", ResourceID: "/subscriptions/sub/resourceGroups/rg"},
+	}, stateResources)
+}
+
+func Test_readStateResources_PriorState(t *testing.T) {
+	priorState := map[string]any{
+		"values": map[string]any{
+			"root_module": map[string]any{
+				"resources": []any{
+					map[string]any{"address": "azurerm_resource_group.rg", "mode": "managed", "type": "azurerm_resource_group", "values": map[string]any{"id": "/subscriptions/sub/resourceGroups/rg"}},
+				},
+				"child_modules": []any{
+					map[string]any{
+						"address": "module.network",
+						"resources": []any{
+							map[string]any{"address": "module.network.azurerm_virtual_network.vnet[0]", "mode": "managed", "type": "azurerm_virtual_network", "values": map[string]any{"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/hub"}},
+							map[string]any{"address": "module.network.data.azurerm_subnet.subnet", "mode": "data", "type": "azurerm_subnet", "values": map[string]any{"id": "subnet"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	stateResources := readStateResources(priorState)
+
+	assert.Equal(t, []*types.StateResource{
+		{Address: "azurerm_resource_group.rg", Type: "azurerm_resource_group", ResourceID: "/subscriptions/sub/resourceGroups/rg"},
+		{Address: "module.network.azurerm_virtual_network.vnet[0]", Type: "azurerm_virtual_network", ResourceID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/hub"},
+	}, stateResources)
+}
+
+func TestPlanClient_GetStateResources_FromStateFile(t *testing.T) {
+	stateFilePath := filepath.Join(t.TempDir(), "terraform.tfstate")
+	content := `{"version": 4, "resources": [{"mode": "managed", "type": "azurerm_resource_group", "name": "rg", "instances": [{"attributes": {"id": "/subscriptions/sub/resourceGroups/rg"}}]}]}`
+	assert.NoError(t, os.WriteFile(stateFilePath, []byte(content), 0644))
+	planClient := &PlanClient{StateFilePath: stateFilePath, Logger: logrus.New()}
+
+	stateResources, err := planClient.GetStateResources()
+
+	assert.NoError(t, err)
+	assert.Len(t, stateResources, 1)
+	assert.Equal(t, "azurerm_resource_group.rg", stateResources[0].Address)
+}
@@ -0,0 +1,11 @@
+package types
+
+type AttributeDifference struct {
+	ResourceAddress string
+	ResourceID      string
+	ResourceType    string
+	AttributePath   string
+	PlannedValue    any
+	LiveValue       any
+	SuggestedValue  string
+}
@@ -0,0 +1,15 @@
+package types
+
+// CandidateScore is the confidence, out of 100, that a Resource Graph resource is the one a plan resource refers to.
+type CandidateScore struct {
+	ResourceID string
+	Score      int
+	Reasons    []string
+}
+
+// CandidateScoring controls when the highest scoring candidate is picked without raising an issue.
+// The top candidate must reach the threshold and beat the runner up by at least the margin. A zero threshold disables it.
+type CandidateScoring struct {
+	Threshold int
+	Margin    int
+}
@@ -0,0 +1,26 @@
+package types
+
+type DriftIgnoreRule struct {
+	Type          string
+	SubType       string
+	AttributePath string
+	MatchType     DriftIgnoreMatchType
+	Reason        string
+}
+
+type DriftIgnoreMatchType string
+
+const (
+	DriftIgnoreMatchTypeGlob  DriftIgnoreMatchType = "Glob"
+	DriftIgnoreMatchTypeRegex DriftIgnoreMatchType = "Regex"
+)
+
+func (matchType DriftIgnoreMatchType) IsValidDriftIgnoreMatchType() bool {
+	switch matchType {
+	case DriftIgnoreMatchTypeGlob,
+		DriftIgnoreMatchTypeRegex:
+		return true
+	default:
+		return false
+	}
+}
@@ -7,10 +7,14 @@ type ResourceGraphQuery struct {
 }
 
 type GraphResource struct {
-	ID       string
-	Type     string
-	Name     string
-	Location string
+	ID             string
+	Type           string
+	Name           string
+	Location       string
+	SubscriptionID string
+	ResourceGroup  string
+	Tags           map[string]string
+	Properties     map[string]any `json:"-"`
 }
 
 type ResourceGraphQueryScope string
@@ -9,3 +9,50 @@ type DestroyBlock struct {
 	ID   string
 	Type string
 }
+
+// SplitImportsBy is how the import blocks are split across files, so they can be applied in waves.
+type SplitImportsBy string
+
+const (
+	SplitImportsByNone          SplitImportsBy = ""
+	SplitImportsByModule        SplitImportsBy = "module"
+	SplitImportsBySubscription  SplitImportsBy = "subscription"
+	SplitImportsByResourceGroup SplitImportsBy = "resourceGroup"
+	SplitImportsByResourceType  SplitImportsBy = "resourceType"
+)
+
+func (splitImportsBy SplitImportsBy) IsValidSplitImportsBy() bool {
+	switch splitImportsBy {
+	case SplitImportsByNone,
+		SplitImportsByModule,
+		SplitImportsBySubscription,
+		SplitImportsByResourceGroup,
+		SplitImportsByResourceType:
+		return true
+	default:
+		return false
+	}
+}
+
+// ImportManifest lists the import files written when the import blocks are split, and the imports in each file.
+// GeneratedFiles lists every file written to the module, so the next run removes exactly those files.
+type ImportManifest struct {
+	SplitImportsBy SplitImportsBy
+	Files          []ImportManifestFile
+	GeneratedFiles []string
+}
+
+type ImportManifestFile struct {
+	Group        string
+	FileName     string
+	ImportBlocks []ImportBlock
+}
+
+type RemovedBlock struct {
+	From string
+}
+
+type MovedBlock struct {
+	From string
+	To   string
+}
@@ -0,0 +1,35 @@
+package types
+
+type ImportStatus string
+
+const (
+	ImportStatusClean    ImportStatus = "Clean"
+	ImportStatusUpdated  ImportStatus = "Updated"
+	ImportStatusReplaced ImportStatus = "Replaced"
+	ImportStatusFailed   ImportStatus = "Failed"
+	// ImportStatusUnverified is an import that was not checked, as the plan failed on other imports
+	ImportStatusUnverified ImportStatus = "Unverified"
+)
+
+// ImportChange is a resource change from a plan that imports an existing resource.
+type ImportChange struct {
+	Address  string
+	ImportID string
+	Actions  []string
+}
+
+type ImportVerification struct {
+	ResourceAddress string
+	ResourceID      string
+	Status          ImportStatus
+	Actions         []string `json:",omitempty"`
+	Blocker         bool
+	Reason          string `json:",omitempty"`
+}
+
+// PlanDiagnostic is an error reported by terraform plan, with the address of the resource it is for when there is one.
+type PlanDiagnostic struct {
+	Summary string
+	Detail  string
+	Address string `json:",omitempty"`
+}
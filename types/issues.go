@@ -1,5 +1,23 @@
 package types
 
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+const (
+	// IssueIDLength keeps 16 hex digits of the hash, so IDs are unique in even the largest estates
+	IssueIDLength = 18
+	// LegacyIssueIDLength is the length of the IDs written by earlier versions, which are a prefix of the current IDs
+	LegacyIssueIDLength = 7
+)
+
+// GetIssueID returns the ID of the issue for a Terraform address or Azure resource ID.
+func GetIssueID(resourceAddress string) string {
+	sha256ID := sha256.Sum256([]byte(resourceAddress))
+	return fmt.Sprintf("i-%x", sha256ID)[0:IssueIDLength]
+}
+
 type Issue struct {
 	IssueID           string
 	IssueType         IssueType
@@ -9,7 +27,13 @@ type Issue struct {
 	ResourceSubType   string
 	ResourceLocation  string
 	MappedResourceIDs []string
+	CandidateScores   []CandidateScore
 	Resolution        IssueResolution
+	ResolvedBy        string
+	// StaleReason is set on a resolution carried over from a previous CSV file that no longer applies
+	StaleReason string `json:",omitempty"`
+	// ExtraColumns holds the values of columns added to the CSV file by reviewers, such as Owner or Notes
+	ExtraColumns map[string]string `json:",omitempty"`
 }
 
 type IssueType string
@@ -33,6 +57,20 @@ func (issueType IssueType) IsValidIssueType() bool {
 	}
 }
 
+// ValidActionTypes returns the actions that can resolve an issue of this type.
+func (issueType IssueType) ValidActionTypes() []ActionType {
+	switch issueType {
+	case IssueTypeMultipleResourceIDs:
+		return []ActionType{ActionTypeUse, ActionTypeIgnore}
+	case IssueTypeNoResourceID:
+		return []ActionType{ActionTypeIgnore, ActionTypeReplace}
+	case IssueTypeUnusedResourceID:
+		return []ActionType{ActionTypeIgnore, ActionTypeReplace, ActionTypeDestroy, ActionTypeForget}
+	default:
+		return []ActionType{}
+	}
+}
+
 type IssueResolution struct {
 	ActionType ActionType
 	ActionID   string
@@ -46,6 +84,8 @@ const (
 	ActionTypeIgnore  ActionType = "Ignore"
 	ActionTypeReplace ActionType = "Replace"
 	ActionTypeDestroy ActionType = "Destroy"
+	// ActionTypeForget removes a resource from state without destroying it
+	ActionTypeForget ActionType = "Forget"
 )
 
 func (actionType ActionType) IsValidActionType() bool {
@@ -54,7 +94,8 @@ func (actionType ActionType) IsValidActionType() bool {
 		ActionTypeUse,
 		ActionTypeIgnore,
 		ActionTypeReplace,
-		ActionTypeDestroy:
+		ActionTypeDestroy,
+		ActionTypeForget:
 		return true
 	default:
 		return false
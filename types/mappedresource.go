@@ -8,6 +8,21 @@ type MappedResource struct {
 	ResourceType       string
 	IssueType          IssueType
 	ActionType         ActionType
+	ResolvedBy         string `json:",omitempty"`
+	// Rename records the pair of a Replace action on both the Terraform address and the resource that replaces it
+	Rename *ResourceRename `json:",omitempty"`
+	// MovedFrom is the address the resource has in state when it is moved rather than imported
+	MovedFrom string `json:",omitempty"`
+	// PickedBy is the score and reasons of a candidate picked by candidate scoring instead of raising an issue
+	PickedBy *CandidateScore `json:",omitempty"`
+}
+
+// ResourceRename pairs a Terraform address that has no resource with the unused resource that is imported in its place.
+type ResourceRename struct {
+	FromIssueID       string
+	FromResourceID    string
+	ToIssueID         string
+	ToResourceAddress string
 }
 
 type MappedResourceType string
@@ -0,0 +1,27 @@
+package types
+
+// ResolutionRule resolves matching issues automatically, so the same decisions do not have to be made by hand in every environment.
+type ResolutionRule struct {
+	Name               string
+	Type               string
+	IssueType          IssueType
+	NamePattern        string
+	AddressPattern     string
+	IDPattern          string
+	ActionType         ActionType
+	CandidateIDPattern string
+}
+
+// IsValidAction checks the action can be applied to the issue type without pairing it with another issue.
+func (resolutionRule ResolutionRule) IsValidAction() bool {
+	switch resolutionRule.ActionType {
+	case ActionTypeIgnore:
+		return resolutionRule.IssueType != IssueTypeMultipleResourceIDs
+	case ActionTypeUse:
+		return resolutionRule.IssueType == IssueTypeMultipleResourceIDs && resolutionRule.CandidateIDPattern != ""
+	case ActionTypeDestroy, ActionTypeForget:
+		return resolutionRule.IssueType == IssueTypeUnusedResourceID
+	default:
+		return false
+	}
+}
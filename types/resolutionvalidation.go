@@ -0,0 +1,23 @@
+package types
+
+type ResolutionProblemType string
+
+const (
+	ResolutionProblemTypeMalformed            ResolutionProblemType = "Malformed"
+	ResolutionProblemTypeUnresolved           ResolutionProblemType = "Unresolved"
+	ResolutionProblemTypeUnknownIssue         ResolutionProblemType = "UnknownIssue"
+	ResolutionProblemTypeIssueTypeChanged     ResolutionProblemType = "IssueTypeChanged"
+	ResolutionProblemTypeDuplicateUse         ResolutionProblemType = "DuplicateUse"
+	ResolutionProblemTypeMissingCandidate     ResolutionProblemType = "MissingCandidate"
+	ResolutionProblemTypeInvalidReplaceTarget ResolutionProblemType = "InvalidReplaceTarget"
+)
+
+// ResolutionProblem is a resolution that cannot be applied to the current run, or an issue without a resolution.
+type ResolutionProblem struct {
+	Type            ResolutionProblemType
+	IssueID         string `json:",omitempty"`
+	ResourceAddress string `json:",omitempty"`
+	// Position is the row of the problem in the resolved issues file, when it is known
+	Position string `json:",omitempty"`
+	Message  string
+}
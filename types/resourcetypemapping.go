@@ -0,0 +1,7 @@
+package types
+
+type ResourceTypeMapping struct {
+	Type              string
+	SubType           string
+	AzureResourceType string
+}
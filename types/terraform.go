@@ -1,23 +1,41 @@
 package types
 
+import "regexp"
+
 type PlanResource struct {
 	Address               string
 	Type                  string
 	SubType               string
+	AzureResourceType     string
 	APIVersion            string
 	Name                  string
 	Location              string
 	ResourceName          string
 	ResourceNameMatchType NameMatchType
-	MappedResources       []*GraphResource
-	Properties            map[string]any
-	PropertiesCalculated  map[string]any
+	// ResourceNamePattern is the compiled resource name of a Regex name format, so it is compiled once per resource
+	ResourceNamePattern  *regexp.Regexp `json:"-"`
+	ParentAddress        string
+	MappedResources      []*GraphResource
+	Properties           map[string]any
+	PropertiesCalculated map[string]any
 }
 
 type NameMatchType string
 
 const (
-	NameMatchTypeExact      NameMatchType = "Exact"
-	NameMatchTypeIDContains NameMatchType = "IDContains"
-	NameMatchTypeIDEndsWith NameMatchType = "IDEndsWith"
+	NameMatchTypeExact                NameMatchType = "Exact"
+	NameMatchTypeIDContains           NameMatchType = "IDContains"
+	NameMatchTypeIDEndsWith           NameMatchType = "IDEndsWith"
+	NameMatchTypeIDEquals             NameMatchType = "IDEquals"
+	NameMatchTypeRegex                NameMatchType = "Regex"
+	NameMatchTypeNameAndType          NameMatchType = "NameAndType"
+	NameMatchTypeNameAndResourceGroup NameMatchType = "NameAndResourceGroup"
+	NameMatchTypeTagEquals            NameMatchType = "TagEquals"
 )
+
+// StateResource is a managed resource in the Terraform state, with the Azure resource ID it is bound to.
+type StateResource struct {
+	Address    string
+	Type       string
+	ResourceID string
+}
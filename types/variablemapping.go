@@ -0,0 +1,22 @@
+package types
+
+type VariableMapping struct {
+	Variable       string
+	Type           string
+	SubType        string
+	AddressPattern string
+	AttributePath  string
+}
+
+type VariableValue struct {
+	Variable    string
+	Value       any
+	Conflicting bool
+	Sources     []VariableValueSource
+}
+
+type VariableValueSource struct {
+	ResourceAddress string
+	ResourceID      string
+	Value           any
+}